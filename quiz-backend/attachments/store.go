@@ -0,0 +1,169 @@
+// Package attachments stores the images and diagrams that questions embed.
+// Files are content-addressed: each is kept once under its SHA-256 hash, so
+// uploading the same image twice yields the same attachment.
+package attachments
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"aws-rds-quiz-backend/config"
+	"aws-rds-quiz-backend/formats"
+	"aws-rds-quiz-backend/models"
+
+	"github.com/gabriel-vasile/mimetype"
+	"gorm.io/gorm"
+)
+
+// AllowedTypes lists the MIME types accepted as attachments
+var AllowedTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp", "image/svg+xml"}
+
+// Errors returned for rejected attachments
+var (
+	ErrTooLarge        = errors.New("attachment is too large")
+	ErrUnsupportedType = errors.New("attachment must be one of: " + strings.Join(AllowedTypes, ", "))
+	ErrInvalidHash     = errors.New("invalid attachment hash")
+)
+
+var validHash = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// Store keeps attachment files in a directory, two levels deep by hash
+type Store struct {
+	dir     string
+	maxSize int64
+}
+
+// NewStore creates a Store for the configured directory
+func NewStore(cfg config.AttachmentsConfig) *Store {
+	return &Store{dir: cfg.Dir, maxSize: int64(cfg.MaxSize)}
+}
+
+// MaxSize returns the largest accepted attachment in bytes
+func (s *Store) MaxSize() int64 {
+	return s.maxSize
+}
+
+// Check sniffs the content type of data, rejecting files that are too large
+// or not an accepted image type
+func (s *Store) Check(data []byte) (string, error) {
+	if int64(len(data)) > s.maxSize {
+		return "", fmt.Errorf("%w (limit %d bytes)", ErrTooLarge, s.maxSize)
+	}
+	detected := mimetype.Detect(data)
+	for _, allowed := range AllowedTypes {
+		if detected.Is(allowed) {
+			return allowed, nil
+		}
+	}
+	return "", fmt.Errorf("%w, got %s", ErrUnsupportedType, detected.String())
+}
+
+// Save stores the content read from r and records it. Content that was
+// stored before returns the existing record, keeping its first filename.
+func (s *Store) Save(db *gorm.DB, r io.Reader, filename string) (*models.Attachment, error) {
+	data, err := io.ReadAll(io.LimitReader(r, s.maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attachment: %v", err)
+	}
+	mimeType, err := s.Check(data)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+	sha := hex.EncodeToString(sum[:])
+	if err := s.write(sha, data); err != nil {
+		return nil, err
+	}
+
+	attachment := models.Attachment{SHA256: sha}
+	err = db.Where(&attachment).
+		Attrs(models.Attachment{MIMEType: mimeType, Size: int64(len(data)), Filename: cleanFilename(filename)}).
+		FirstOrCreate(&attachment).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to record attachment: %v", err)
+	}
+	return &attachment, nil
+}
+
+// Open opens the stored file for a hash
+func (s *Store) Open(sha string) (*os.File, error) {
+	if !validHash.MatchString(sha) {
+		return nil, ErrInvalidHash
+	}
+	return os.Open(s.path(sha))
+}
+
+// Loader returns a loader for exporting the attachments recorded in db
+func (s *Store) Loader(db *gorm.DB) formats.Loader {
+	return func(sha string) (formats.File, error) {
+		var attachment models.Attachment
+		if err := db.Where("sha256 = ?", sha).First(&attachment).Error; err != nil {
+			return formats.File{}, err
+		}
+		f, err := s.Open(sha)
+		if err != nil {
+			return formats.File{}, err
+		}
+		defer f.Close()
+
+		var buf bytes.Buffer
+		if _, err := buf.ReadFrom(f); err != nil {
+			return formats.File{}, err
+		}
+		return formats.File{SHA256: sha, Filename: attachment.Filename, MIMEType: attachment.MIMEType, Data: buf.Bytes()}, nil
+	}
+}
+
+// write stores data under its hash unless it is already there. The file is
+// written to a temporary name and renamed so readers never see partial files.
+func (s *Store) write(sha string, data []byte) error {
+	path := s.path(sha)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create attachment directory: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), sha+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to store attachment: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to store attachment: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to store attachment: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to store attachment: %v", err)
+	}
+	return nil
+}
+
+func (s *Store) path(sha string) string {
+	return filepath.Join(s.dir, sha[:2], sha)
+}
+
+// cleanFilename keeps the base name of an uploaded file
+func cleanFilename(name string) string {
+	name = strings.TrimSpace(filepath.Base(strings.ReplaceAll(name, `\`, "/")))
+	if name == "." || name == "/" {
+		return ""
+	}
+	if len(name) > 255 {
+		name = name[:255]
+	}
+	return name
+}
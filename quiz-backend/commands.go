@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"aws-rds-quiz-backend/attachments"
+	"aws-rds-quiz-backend/config"
+	"aws-rds-quiz-backend/database"
+	"aws-rds-quiz-backend/formats"
+	"aws-rds-quiz-backend/importer"
+	"aws-rds-quiz-backend/lint"
+	"aws-rds-quiz-backend/models"
+)
+
+const usage = `Usage: quiz-backend [command] [flags]
+
+Commands:
+  serve     Run the API server (default)
+  import    Bulk import questions from a JSON, CSV, YAML, GIFT, Moodle XML or QTI file
+  lint      Check the question bank for structural problems
+  seed      Sync the built-in questions into the default bank
+`
+
+// runCommand dispatches a CLI subcommand and returns the process exit code
+func runCommand(cfg *config.Config, name string, args []string) int {
+	switch name {
+	case "serve":
+		serve(cfg)
+		return 0
+	case "import":
+		quietDatabaseLog(cfg)
+		return runImport(cfg, args)
+	case "lint":
+		quietDatabaseLog(cfg)
+		return runLint(cfg, args)
+	case "seed":
+		quietDatabaseLog(cfg)
+		return runSeed(cfg, args)
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", name, usage)
+		return 2
+	}
+}
+
+// runImport implements `quiz-backend import [-bank slug] [-format f] [-dry-run] [-partial] [-publish] [-author name] FILE`
+func runImport(cfg *config.Config, args []string) int {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	bankSlug := fs.String("bank", "", "bank to import into (default: the default bank)")
+	format := fs.String("format", "", "file format: json, csv, yaml, gift, moodlexml or qti (default: from file extension)")
+	dryRun := fs.Bool("dry-run", false, "validate the file without writing to the database")
+	partial := fs.Bool("partial", false, "import valid rows even if some rows are rejected")
+	publish := fs.Bool("publish", false, "publish the imported questions instead of creating drafts")
+	author := fs.String("author", "", "author recorded on the imported questions")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: quiz-backend import [-bank slug] [-format f] [-dry-run] [-partial] [-publish] [-author name] FILE")
+		return 2
+	}
+	path := fs.Arg(0)
+
+	if *format == "" {
+		*format = formats.FormatFromFilename(path)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer file.Close()
+
+	rows, err := formats.Decode(*format, file)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	db, err := database.InitDB(cfg.Database)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to connect to database:", err)
+		return 1
+	}
+	bank, err := models.FindBank(db, *bankSlug)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load bank %q: %v\n", *bankSlug, err)
+		return 1
+	}
+
+	report, err := importer.Import(db, rows, importer.Options{
+		DryRun:  *dryRun,
+		Partial: *partial,
+		Publish: *publish,
+		Author:  *author,
+		Bank:    &bank,
+		Store:   attachments.NewStore(cfg.Attachments),
+	})
+	if report != nil {
+		printJSON(report)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Import failed:", err)
+		return 1
+	}
+	if report.Rejected > 0 && !report.Committed && !*dryRun {
+		fmt.Fprintf(os.Stderr, "Import aborted: %d rows rejected\n", report.Rejected)
+		return 1
+	}
+	return 0
+}
+
+// runLint implements `quiz-backend lint [-bank slug] [-rule id] [-disable id] [-json]`.
+// It exits with status 1 when any error-level finding is reported.
+func runLint(cfg *config.Config, args []string) int {
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	var only, disabled listFlag
+	fs.Var(&only, "rule", "run only this rule (repeatable)")
+	fs.Var(&disabled, "disable", "skip this rule (repeatable)")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	bankSlug := fs.String("bank", "", "bank to check (default: the default bank)")
+	fs.Parse(args)
+
+	db, err := database.InitDB(cfg.Database)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to connect to database:", err)
+		return 1
+	}
+	bank, err := models.FindBank(db, *bankSlug)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load bank %q: %v\n", *bankSlug, err)
+		return 1
+	}
+
+	var questions []models.Question
+	if err := db.Scopes(models.InBank(bank.ID)).Order("id").Find(&questions).Error; err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to load questions:", err)
+		return 1
+	}
+
+	cfg.Lint.Disabled = append(cfg.Lint.Disabled, disabled...)
+	report, err := lint.Run(questions, cfg.Lint, only)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	if *asJSON {
+		printJSON(report)
+	} else {
+		for _, finding := range report.Findings {
+			where := "bank"
+			if finding.QuestionID != 0 {
+				where = fmt.Sprintf("question %d", finding.QuestionID)
+			}
+			fmt.Printf("%s: %s [%s] %s\n", where, finding.Severity, finding.Rule, finding.Message)
+		}
+		fmt.Printf("%d questions checked: %d errors, %d warnings, %d suppressed\n",
+			report.Questions, report.Errors, report.Warnings, report.Suppressed)
+	}
+
+	if report.Errors > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runSeed implements `quiz-backend seed [-dry-run] [-json]`, printing the
+// changes made to the seeded questions, or only planned with -dry-run
+func runSeed(cfg *config.Config, args []string) int {
+	fs := flag.NewFlagSet("seed", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "print the planned changes without writing to the database")
+	asJSON := fs.Bool("json", false, "print the plan as JSON")
+	fs.Parse(args)
+
+	var plan *database.SeedPlan
+	if *dryRun {
+		var err error
+		if plan, err = database.PlanSeed(cfg.Database); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	} else {
+		cfg.Database.SkipSeed = true
+		db, err := database.InitDB(cfg.Database)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to connect to database:", err)
+			return 1
+		}
+		if plan, err = database.SyncSeed(db, false); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	if *asJSON {
+		printJSON(plan)
+	} else {
+		fmt.Print(plan)
+	}
+	return 0
+}
+
+// listFlag collects a repeatable string flag
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// quietDatabaseLog keeps SQL logging out of command output unless
+// DB_LOG_LEVEL was set explicitly
+func quietDatabaseLog(cfg *config.Config) {
+	if os.Getenv("DB_LOG_LEVEL") == "" {
+		cfg.Database.LogLevel = "warn"
+	}
+}
+
+func printJSON(v interface{}) {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
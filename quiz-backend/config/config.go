@@ -3,12 +3,20 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	CORS     CORSConfig
+	Server        ServerConfig
+	Database      DatabaseConfig
+	CORS          CORSConfig
+	QuestionFiles QuestionFilesConfig
+	Lint          LintConfig
+	Attachments   AttachmentsConfig
+	Translations  TranslationsConfig
+	Trash         TrashConfig
+	Exam          ExamConfig
+	Templates     TemplatesConfig
 }
 
 type ServerConfig struct {
@@ -24,6 +32,8 @@ type DatabaseConfig struct {
 	Password string
 	DBName   string
 	SSLMode  string
+	LogLevel string
+	SkipSeed bool // leave the seed questions alone on startup
 }
 
 type CORSConfig struct {
@@ -32,6 +42,57 @@ type CORSConfig struct {
 	AllowedHeaders []string
 }
 
+// QuestionFilesConfig configures the Markdown question directory. An empty
+// Dir disables it.
+type QuestionFilesConfig struct {
+	Dir          string
+	PollInterval int // seconds between checks for changed files
+}
+
+// LintConfig configures the question linter
+type LintConfig struct {
+	Disabled               []string // rule IDs that never run
+	LongOptionRatio        float64  // how many times longer than the others an option may be
+	MaxAnswerPositionShare float64  // largest share of questions with the answer at one position
+	MinAnswerPositionCount int      // fewest questions for which the position share is checked
+}
+
+// AttachmentsConfig configures the attachment file store
+type AttachmentsConfig struct {
+	Dir     string
+	MaxSize int // largest accepted upload in bytes
+}
+
+// TranslationsConfig configures question translations. An empty Locales
+// accepts translations into any locale.
+type TranslationsConfig struct {
+	SourceLocale string   // locale the questions themselves are written in
+	Locales      []string // locales translations may be added for
+}
+
+// TrashConfig configures how long soft-deleted questions and submissions are
+// kept before they may be purged
+type TrashConfig struct {
+	RetentionDays int
+}
+
+// ExamConfig configures exam mode. With WithholdAnswers set the learner
+// question routes serve questions without their answers, and exam sessions
+// reveal them according to DefaultReview and TimeLimit; otherwise those are
+// the defaults of exam sessions that name no review policy or time limit.
+type ExamConfig struct {
+	WithholdAnswers bool
+	DefaultReview   string // immediate, after_close or never
+	TimeLimit       int    // seconds; 0 for no time limit
+	EditorToken     string // bearer token of the authoring routes in exam mode
+}
+
+// TemplatesConfig configures template questions. VariantSecret signs the
+// servedVariant tokens of the variants served outside quiz sessions.
+type TemplatesConfig struct {
+	VariantSecret string
+}
+
 func LoadConfig() *Config {
 	return &Config{
 		Server: ServerConfig{
@@ -46,11 +107,42 @@ func LoadConfig() *Config {
 			Password: getEnv("DB_PASSWORD", ""),
 			DBName:   getEnv("DB_NAME", "quiz.db"),
 			SSLMode:  getEnv("DB_SSLMODE", "disable"),
+			LogLevel: getEnv("DB_LOG_LEVEL", "info"),
 		},
 		CORS: CORSConfig{
 			AllowedOrigins: []string{"http://localhost:3000", "http://localhost:3001"},
-			AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-			AllowedHeaders: []string{"Origin", "Content-Type", "Accept", "Authorization"},
+			AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+			AllowedHeaders: []string{"Origin", "Content-Type", "Accept", "Authorization", "X-User-ID"},
+		},
+		QuestionFiles: QuestionFilesConfig{
+			Dir:          getEnv("QUESTIONS_DIR", ""),
+			PollInterval: getEnvAsInt("QUESTIONS_POLL_INTERVAL", 2),
+		},
+		Lint: LintConfig{
+			Disabled:               getEnvAsList("LINT_DISABLE"),
+			LongOptionRatio:        getEnvAsFloat("LINT_LONG_OPTION_RATIO", 2.5),
+			MaxAnswerPositionShare: getEnvAsFloat("LINT_MAX_ANSWER_POSITION_SHARE", 0.4),
+			MinAnswerPositionCount: getEnvAsInt("LINT_MIN_ANSWER_POSITION_COUNT", 10),
+		},
+		Attachments: AttachmentsConfig{
+			Dir:     getEnv("ATTACHMENTS_DIR", "uploads"),
+			MaxSize: getEnvAsInt("ATTACHMENTS_MAX_SIZE", 5<<20),
+		},
+		Translations: TranslationsConfig{
+			SourceLocale: getEnv("SOURCE_LOCALE", "en"),
+			Locales:      getEnvAsList("TRANSLATION_LOCALES"),
+		},
+		Trash: TrashConfig{
+			RetentionDays: getEnvAsInt("TRASH_RETENTION_DAYS", 30),
+		},
+		Exam: ExamConfig{
+			WithholdAnswers: getEnvAsBool("EXAM_WITHHOLD_ANSWERS", false),
+			DefaultReview:   getEnv("EXAM_REVIEW_POLICY", "immediate"),
+			TimeLimit:       getEnvAsInt("EXAM_TIME_LIMIT", 0),
+			EditorToken:     getEnv("EXAM_EDITOR_TOKEN", ""),
+		},
+		Templates: TemplatesConfig{
+			VariantSecret: getEnv("TEMPLATE_VARIANT_SECRET", ""),
 		},
 	}
 }
@@ -70,3 +162,32 @@ func getEnvAsInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
+func getEnvAsBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
+// getEnvAsList reads a comma-separated list
+func getEnvAsList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
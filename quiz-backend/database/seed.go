@@ -0,0 +1,426 @@
+package database
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"aws-rds-quiz-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// seedIDPrefix namespaces the external IDs of seeded questions
+const seedIDPrefix = "seed:"
+
+// seedActor is recorded as the actor of editorial transitions made by SyncSeed
+const seedActor = "seed"
+
+// seedQuestion is one built-in question. ID is its stable key: the content
+// may be fixed freely, but an ID must never be renamed or reused.
+type seedQuestion struct {
+	ID            string
+	Question      string
+	Options       []string
+	CorrectAnswer int
+	Explanation   string
+	Category      string
+	Difficulty    string
+}
+
+// SeedChange describes what syncing does to one seeded question
+type SeedChange struct {
+	ExternalID string               `json:"externalId"`
+	ID         uint                 `json:"id,omitempty"` // unset for questions still to be created
+	Question   string               `json:"question"`
+	Changes    []models.FieldChange `json:"changes,omitempty"`
+}
+
+// SeedPlan lists what syncing the seed data does, or did
+type SeedPlan struct {
+	Created   []SeedChange `json:"created"`
+	Updated   []SeedChange `json:"updated"`
+	Retired   []SeedChange `json:"retired"`
+	Adopted   []SeedChange `json:"adopted"` // seeded before seed keys existed, matched by text
+	Unchanged int          `json:"unchanged"`
+}
+
+// Empty reports whether syncing changes nothing
+func (p *SeedPlan) Empty() bool {
+	return len(p.Created) == 0 && len(p.Updated) == 0 && len(p.Retired) == 0 && len(p.Adopted) == 0
+}
+
+// SyncSeed brings the seeded questions of the default bank in line with the
+// seed data. Entries are matched by their stable key: new ones are created
+// and published, and ones whose seed content changed since it was last
+// applied are updated, recording a revision, so edits made through the API
+// stand until the seed entry itself changes. Published questions whose entry
+// was removed are retired, and questions moved to the trash are left there.
+// With dryRun set nothing is written and the plan is only returned.
+func SyncSeed(db *gorm.DB, dryRun bool) (*SeedPlan, error) {
+	bank, err := models.DefaultBank(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load default bank: %v", err)
+	}
+
+	// Questions seeded before sources were recorded have none
+	var existing []models.Question
+	legacyRows := "bank_id = ? AND (source IS NULL OR source = '') AND external_id IS NULL"
+	if err := db.Unscoped().Scopes(models.WithTaxonomy).Where("source = ? OR ("+legacyRows+")", models.SourceSeed, bank.ID).Order("id").Find(&existing).Error; err != nil {
+		return nil, fmt.Errorf("failed to load seeded questions: %v", err)
+	}
+	byKey := make(map[string]*models.Question, len(existing))
+	legacy := make(map[string]*models.Question)
+	for i := range existing {
+		q := &existing[i]
+		if q.ExternalID != nil {
+			byKey[*q.ExternalID] = q
+		} else if !q.DeletedAt.Valid {
+			legacy[q.Question] = q
+		}
+	}
+
+	plan := &SeedPlan{Created: []SeedChange{}, Updated: []SeedChange{}, Retired: []SeedChange{}, Adopted: []SeedChange{}}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		seen := make(map[string]bool)
+		for _, entry := range seedData() {
+			key := seedIDPrefix + entry.ID
+			if seen[key] {
+				return fmt.Errorf("duplicate seed key %s", key)
+			}
+			seen[key] = true
+			if err := syncSeedEntry(tx, &bank, entry, key, byKey, legacy, plan, dryRun); err != nil {
+				return fmt.Errorf("%s: %v", key, err)
+			}
+		}
+
+		for i := range existing {
+			q := &existing[i]
+			if q.ExternalID == nil || seen[*q.ExternalID] || q.DeletedAt.Valid || !q.IsPublished() {
+				continue
+			}
+			entry, err := q.Transition(seedActor, models.TransitionRequest{Action: models.ActionRetire, Comment: "Removed from the seed data"})
+			if err != nil {
+				return err
+			}
+			plan.Retired = append(plan.Retired, SeedChange{ExternalID: *q.ExternalID, ID: q.ID, Question: q.Question})
+			if dryRun {
+				continue
+			}
+			if err := tx.Model(q).Select("status").Updates(q).Error; err != nil {
+				return err
+			}
+			if err := tx.Create(&entry).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sync seed questions: %v", err)
+	}
+	return plan, nil
+}
+
+// syncSeedEntry creates, adopts or updates the question for one seed entry
+func syncSeedEntry(tx *gorm.DB, bank *models.Bank, entry seedQuestion, key string, byKey, legacy map[string]*models.Question, plan *SeedPlan, dryRun bool) error {
+	hash := entry.hash()
+	q, ok := byKey[key]
+	adopted := false
+	if !ok {
+		if q, ok = legacy[entry.Question]; ok {
+			delete(legacy, entry.Question)
+			adopted = true
+			plan.Adopted = append(plan.Adopted, SeedChange{ExternalID: key, ID: q.ID, Question: q.Question})
+		}
+	}
+
+	if !ok {
+		req := entry.apply(models.QuestionRequest{})
+		if err := bank.Check(&req); err != nil {
+			return err
+		}
+		plan.Created = append(plan.Created, SeedChange{ExternalID: key, Question: req.Question})
+		if dryRun {
+			return nil
+		}
+		externalID := key
+		question := models.Question{BankID: bank.ID, Source: models.SourceSeed, Status: models.StatusPublished, ExternalID: &externalID, SeedHash: hash}
+		if err := req.ApplyTo(&question); err != nil {
+			return err
+		}
+		return tx.Create(&question).Error
+	}
+
+	if q.SeedHash == hash || q.DeletedAt.Valid {
+		plan.Unchanged++
+		return nil
+	}
+
+	current, err := q.ToRequest()
+	if err != nil {
+		return err
+	}
+	req := entry.apply(current)
+	if err := bank.Check(&req); err != nil {
+		return err
+	}
+	changes, err := models.DiffRequests(current, req)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		if !adopted {
+			plan.Unchanged++
+		}
+		if dryRun {
+			return nil
+		}
+		return tx.Model(q).UpdateColumns(map[string]interface{}{"source": models.SourceSeed, "external_id": key, "seed_hash": hash}).Error
+	}
+
+	plan.Updated = append(plan.Updated, SeedChange{ExternalID: key, ID: q.ID, Question: req.Question, Changes: changes})
+	if dryRun {
+		return nil
+	}
+	if err := req.ApplyTo(q); err != nil {
+		return err
+	}
+	externalID := key
+	q.Source = models.SourceSeed
+	q.ExternalID = &externalID
+	q.SeedHash = hash
+	return tx.Save(q).Error
+}
+
+// apply overlays the seed content on a question's authoring representation,
+// keeping what the seed does not set, such as tags and topic
+func (s seedQuestion) apply(req models.QuestionRequest) models.QuestionRequest {
+	req.Question = s.Question
+	req.Options = append([]string{}, s.Options...)
+	req.CorrectAnswer = s.CorrectAnswer
+	req.Explanation = s.Explanation
+	req.Category = s.Category
+	req.Difficulty = s.Difficulty
+	req.Normalize()
+	return req
+}
+
+// hash fingerprints the seed content of an entry
+func (s seedQuestion) hash() string {
+	data, _ := json.Marshal(s)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// String summarizes a plan, one line per change
+func (p *SeedPlan) String() string {
+	var b strings.Builder
+	for _, c := range p.Created {
+		fmt.Fprintf(&b, "create %s: %s\n", c.ExternalID, c.Question)
+	}
+	for _, c := range p.Adopted {
+		fmt.Fprintf(&b, "adopt %s: question %d\n", c.ExternalID, c.ID)
+	}
+	for _, c := range p.Updated {
+		fields := make([]string, len(c.Changes))
+		for i, change := range c.Changes {
+			fields[i] = change.Field
+		}
+		fmt.Fprintf(&b, "update %s: question %d (%s)\n", c.ExternalID, c.ID, strings.Join(fields, ", "))
+	}
+	for _, c := range p.Retired {
+		fmt.Fprintf(&b, "retire %s: question %d\n", c.ExternalID, c.ID)
+	}
+	fmt.Fprintf(&b, "%d created, %d adopted, %d updated, %d retired, %d unchanged\n",
+		len(p.Created), len(p.Adopted), len(p.Updated), len(p.Retired), p.Unchanged)
+	return b.String()
+}
+
+// seedData returns the built-in questions
+func seedData() []seedQuestion {
+	return []seedQuestion{
+		{
+			ID:            "rds-no-ssh-access",
+			Question:      "Which of the following is NOT supported by Amazon RDS?",
+			Options:       []string{"Automatic backups", "SSH access to the database instance", "Read replicas", "Multi-AZ deployments"},
+			CorrectAnswer: 1,
+			Explanation:   "Amazon RDS does not provide SSH access to the database instance. It's a managed service where AWS handles the underlying infrastructure.",
+			Category:      "RDS",
+			Difficulty:    "medium",
+		},
+		{
+			ID:            "rds-vs-aurora-throughput",
+			Question:      "What is the primary difference between Amazon RDS and Amazon Aurora?",
+			Options:       []string{"Aurora supports only NoSQL databases", "Aurora is fully serverless", "Aurora provides up to 5 times the throughput of MySQL on the same hardware", "Aurora does not support backups"},
+			CorrectAnswer: 2,
+			Explanation:   "Aurora is designed to provide up to 5 times the throughput of MySQL on the same hardware, making it significantly more performant than standard RDS.",
+			Category:      "Aurora",
+			Difficulty:    "medium",
+		},
+		{
+			ID:            "rds-multi-az-failover",
+			Question:      "Which Amazon RDS feature provides high availability and failover support?",
+			Options:       []string{"Read Replica", "Multi-AZ deployment", "Cross-region replication", "DB parameter group"},
+			CorrectAnswer: 1,
+			Explanation:   "Multi-AZ deployment provides high availability and automatic failover support by maintaining a standby instance in a different Availability Zone.",
+			Category:      "RDS",
+			Difficulty:    "easy",
+		},
+		{
+			ID:            "rds-read-scaling",
+			Question:      "You need to horizontally scale read traffic from your RDS database. Which feature should you use?",
+			Options:       []string{"Multi-AZ deployment", "Read Replica", "Database snapshots", "IAM authentication"},
+			CorrectAnswer: 1,
+			Explanation:   "Read Replicas allow you to horizontally scale read traffic by creating copies of your database that can handle read requests.",
+			Category:      "RDS",
+			Difficulty:    "medium",
+		},
+		{
+			ID:            "aurora-storage-replication",
+			Question:      "Which statement about Amazon Aurora is TRUE?",
+			Options:       []string{"Aurora stores data in a single Availability Zone", "Aurora automatically replicates six copies of your data across three Availability Zones", "Aurora does not support cross-region replication", "Aurora does not support PostgreSQL"},
+			CorrectAnswer: 1,
+			Explanation:   "Aurora automatically replicates six copies of your data across three Availability Zones, providing high durability and availability.",
+			Category:      "Aurora",
+			Difficulty:    "medium",
+		},
+		{
+			ID:            "rds-oracle-migration",
+			Question:      "You want to migrate an on-premises Oracle DB to AWS with minimal code change. Which RDS engine should you choose?",
+			Options:       []string{"Amazon Aurora MySQL", "Amazon RDS PostgreSQL", "Amazon RDS Oracle", "Amazon DynamoDB"},
+			CorrectAnswer: 2,
+			Explanation:   "Amazon RDS Oracle would require minimal code changes since you're migrating from Oracle to Oracle.",
+			Category:      "RDS",
+			Difficulty:    "easy",
+		},
+		{
+			ID:            "aurora-serverless-v2-failover",
+			Question:      "Which Aurora feature provides automatic failover and automatic scaling of compute capacity?",
+			Options:       []string{"Aurora Multi-Master", "Aurora Serverless v2", "Aurora Global Database", "Aurora Read Replica"},
+			CorrectAnswer: 1,
+			Explanation:   "Aurora Serverless v2 provides automatic failover and automatic scaling of compute capacity based on demand.",
+			Category:      "Aurora",
+			Difficulty:    "hard",
+		},
+		{
+			ID:            "aurora-global-database-use-case",
+			Question:      "Which of the following is a valid use case for Amazon Aurora Global Databases?",
+			Options:       []string{"Single region analytics", "Multi-region write operations", "Disaster recovery with cross-region read replicas", "In-memory caching"},
+			CorrectAnswer: 2,
+			Explanation:   "Aurora Global Databases are designed for disaster recovery with cross-region read replicas, providing global read scaling and disaster recovery.",
+			Category:      "Aurora",
+			Difficulty:    "hard",
+		},
+		{
+			ID:            "rds-backups-on-delete",
+			Question:      "What happens to backups when you delete an RDS instance?",
+			Options:       []string{"All backups are retained", "You are given an option to create a final snapshot", "Backups are automatically stored in S3", "Backups are transferred to Glacier"},
+			CorrectAnswer: 1,
+			Explanation:   "When you delete an RDS instance, you are given an option to create a final snapshot before deletion.",
+			Category:      "RDS",
+			Difficulty:    "medium",
+		},
+		{
+			ID:            "rds-kms-encryption",
+			Question:      "Which of the following can be encrypted using AWS KMS in Amazon RDS?",
+			Options:       []string{"Only backups", "Only the database instance", "Data at rest including backups, snapshots, and replicas", "Only log files"},
+			CorrectAnswer: 2,
+			Explanation:   "AWS KMS can encrypt data at rest including backups, snapshots, and replicas in Amazon RDS.",
+			Category:      "RDS",
+			Difficulty:    "medium",
+		},
+		{
+			ID:            "aurora-supported-engines",
+			Question:      "Which of the following databases are supported by Amazon Aurora?",
+			Options:       []string{"MySQL and MongoDB", "MySQL and PostgreSQL", "Oracle and SQL Server", "DynamoDB and MySQL"},
+			CorrectAnswer: 1,
+			Explanation:   "Amazon Aurora supports MySQL and PostgreSQL database engines.",
+			Category:      "Aurora",
+			Difficulty:    "easy",
+		},
+		{
+			ID:            "rds-mysql-read-replica-limit",
+			Question:      "What is the maximum number of Read Replicas you can create for an RDS MySQL DB instance?",
+			Options:       []string{"2", "5", "15", "Unlimited"},
+			CorrectAnswer: 2,
+			Explanation:   "You can create up to 15 Read Replicas for an RDS MySQL DB instance.",
+			Category:      "RDS",
+			Difficulty:    "medium",
+		},
+		{
+			ID:            "aurora-postgresql-ha",
+			Question:      "Which AWS service is best suited for running a highly available, PostgreSQL-compatible relational database with minimal maintenance?",
+			Options:       []string{"Amazon RDS for PostgreSQL", "Amazon EC2 with PostgreSQL", "Amazon Aurora PostgreSQL", "Amazon Redshift"},
+			CorrectAnswer: 2,
+			Explanation:   "Amazon Aurora PostgreSQL is best suited for running a highly available, PostgreSQL-compatible relational database with minimal maintenance.",
+			Category:      "Aurora",
+			Difficulty:    "medium",
+		},
+		{
+			ID:            "rds-performance-monitoring",
+			Question:      "Which of the following can be used to monitor Amazon RDS performance metrics?",
+			Options:       []string{"AWS Config", "CloudTrail", "CloudWatch", "AWS Budgets"},
+			CorrectAnswer: 2,
+			Explanation:   "CloudWatch can be used to monitor Amazon RDS performance metrics and set up alarms.",
+			Category:      "RDS",
+			Difficulty:    "easy",
+		},
+		{
+			ID:            "rds-enable-automatic-failover",
+			Question:      "How can you enable automatic failover in Amazon RDS?",
+			Options:       []string{"Enable read replicas", "Use DB parameter groups", "Create a Multi-AZ deployment", "Enable backup retention"},
+			CorrectAnswer: 2,
+			Explanation:   "Creating a Multi-AZ deployment enables automatic failover in Amazon RDS.",
+			Category:      "RDS",
+			Difficulty:    "medium",
+		},
+		{
+			ID:            "aurora-global-database-description",
+			Question:      "Which of the following best describes Aurora Global Databases?",
+			Options:       []string{"Supports multi-region read and write operations", "Allows writes in one AWS Region and reads in others", "Only available in single-region setups", "Supports DynamoDB-compatible storage"},
+			CorrectAnswer: 1,
+			Explanation:   "Aurora Global Databases allow writes in one AWS Region and reads in others, providing global read scaling.",
+			Category:      "Aurora",
+			Difficulty:    "hard",
+		},
+		{
+			ID:            "rds-default-backup-retention",
+			Question:      "What is the default backup retention period for an RDS instance?",
+			Options:       []string{"0 days", "1 day", "7 days", "30 days"},
+			CorrectAnswer: 2,
+			Explanation:   "The default backup retention period for an RDS instance is 7 days.",
+			Category:      "RDS",
+			Difficulty:    "easy",
+		},
+		{
+			ID:            "rds-sql-server-engine",
+			Question:      "Which Amazon RDS engine supports Microsoft SQL Server?",
+			Options:       []string{"Aurora", "PostgreSQL", "Oracle", "SQL Server"},
+			CorrectAnswer: 3,
+			Explanation:   "Amazon RDS supports Microsoft SQL Server as one of its database engines.",
+			Category:      "RDS",
+			Difficulty:    "easy",
+		},
+		{
+			ID:            "rds-read-replica-replication",
+			Question:      "What kind of replication is used in Amazon RDS Read Replicas?",
+			Options:       []string{"Synchronous", "Asynchronous", "Bidirectional", "Real-time mirroring"},
+			CorrectAnswer: 1,
+			Explanation:   "Amazon RDS Read Replicas use asynchronous replication.",
+			Category:      "RDS",
+			Difficulty:    "medium",
+		},
+		{
+			ID:            "aurora-serverless-v2-scaling",
+			Question:      "Which of the following statements about Amazon Aurora Serverless v2 is TRUE?",
+			Options:       []string{"It only supports MySQL 5.6", "It requires manual scaling of compute capacity", "It supports fine-grained compute scaling with high availability", "It cannot be paused"},
+			CorrectAnswer: 2,
+			Explanation:   "Aurora Serverless v2 supports fine-grained compute scaling with high availability, automatically scaling based on demand.",
+			Category:      "Aurora",
+			Difficulty:    "hard",
+		},
+	}
+}
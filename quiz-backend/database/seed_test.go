@@ -0,0 +1,166 @@
+package database
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"aws-rds-quiz-backend/config"
+	"aws-rds-quiz-backend/models"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// baselineSchema is the schema of the first release, before banks, sources
+// and external IDs
+const baselineSchema = "CREATE TABLE `questions` (`id` integer PRIMARY KEY AUTOINCREMENT,`question` text NOT NULL,`options` text NOT NULL," +
+	"`correct_answer` integer NOT NULL,`explanation` text,`category` text DEFAULT \"RDS\",`difficulty` text DEFAULT \"medium\"," +
+	"`created_at` datetime,`updated_at` datetime,`deleted_at` datetime);" +
+	"CREATE INDEX `idx_questions_deleted_at` ON `questions`(`deleted_at`);" +
+	"CREATE TABLE `quiz_submissions` (`id` integer PRIMARY KEY AUTOINCREMENT,`user_id` text,`answers` text NOT NULL,`time_spent` integer NOT NULL," +
+	"`score` integer NOT NULL,`total` integer NOT NULL,`percentage` real NOT NULL,`created_at` datetime,`updated_at` datetime,`deleted_at` datetime);"
+
+// baselineRow is a question as the first release stored it
+type baselineRow struct {
+	question  string
+	options   []string
+	correct   int
+	deleted   bool
+	seedIndex int // the seed entry the row was seeded from, -1 for none
+}
+
+// seededRow returns the baseline row of a seed entry
+func seededRow(index int) baselineRow {
+	entry := seedData()[index]
+	return baselineRow{question: entry.Question, options: entry.Options, correct: entry.CorrectAnswer, seedIndex: index}
+}
+
+// baselineDB writes a first-release database holding rows and returns its path
+func baselineDB(t *testing.T, rows []baselineRow) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "quiz.db")
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	if err := db.Exec(baselineSchema).Error; err != nil {
+		t.Fatalf("failed to create baseline schema: %v", err)
+	}
+	for _, row := range rows {
+		options, _ := json.Marshal(row.options)
+		deletedAt := interface{}(nil)
+		if row.deleted {
+			deletedAt = "2024-01-01 00:00:00"
+		}
+		if err := db.Exec("INSERT INTO questions (question, options, correct_answer, explanation, category, difficulty, created_at, updated_at, deleted_at) VALUES (?, ?, ?, '', 'RDS', 'medium', datetime('now'), datetime('now'), ?)",
+			row.question, string(options), row.correct, deletedAt).Error; err != nil {
+			t.Fatalf("failed to insert baseline row: %v", err)
+		}
+	}
+	closeDB(t, db)
+	return path
+}
+
+func closeDB(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("failed to get database handle: %v", err)
+	}
+	sqlDB.Close()
+}
+
+func TestSyncSeedAdoptsBaselineRows(t *testing.T) {
+	seeded := len(seedData())
+	custom := baselineRow{question: "A question an operator added by hand?", options: []string{"a", "b"}, seedIndex: -1}
+	trashed := seededRow(2)
+	trashed.deleted = true
+
+	tests := []struct {
+		name    string
+		rows    []baselineRow
+		adopted int
+		created int
+		total   int // questions afterwards, including trashed ones
+	}{
+		{"empty database", nil, 0, seeded, seeded},
+		{"every seed row", func() []baselineRow {
+			rows := make([]baselineRow, seeded)
+			for i := range rows {
+				rows[i] = seededRow(i)
+			}
+			return rows
+		}(), seeded, 0, seeded},
+		{"some seed rows and a custom one", []baselineRow{seededRow(0), custom, seededRow(1)}, 2, seeded - 2, seeded + 1},
+		{"trashed seed row", []baselineRow{seededRow(0), trashed}, 1, seeded - 1, seeded + 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.DatabaseConfig{Driver: "sqlite", DBName: baselineDB(t, tt.rows), LogLevel: "silent", SkipSeed: true}
+
+			before, err := os.ReadFile(cfg.DBName)
+			if err != nil {
+				t.Fatalf("failed to read database: %v", err)
+			}
+			plan, err := PlanSeed(cfg)
+			if err != nil {
+				t.Fatalf("PlanSeed() error = %v", err)
+			}
+			if len(plan.Adopted) != tt.adopted || len(plan.Created) != tt.created {
+				t.Errorf("PlanSeed() adopts %d and creates %d, want %d and %d", len(plan.Adopted), len(plan.Created), tt.adopted, tt.created)
+			}
+			after, err := os.ReadFile(cfg.DBName)
+			if err != nil {
+				t.Fatalf("failed to read database: %v", err)
+			}
+			if !bytes.Equal(before, after) {
+				t.Error("PlanSeed() changed the database file")
+			}
+
+			db, err := InitDB(cfg)
+			if err != nil {
+				t.Fatalf("InitDB() error = %v", err)
+			}
+			defer closeDB(t, db)
+			plan, err = SyncSeed(db, false)
+			if err != nil {
+				t.Fatalf("SyncSeed() error = %v", err)
+			}
+			if len(plan.Adopted) != tt.adopted || len(plan.Created) != tt.created {
+				t.Errorf("SyncSeed() adopted %d and created %d, want %d and %d", len(plan.Adopted), len(plan.Created), tt.adopted, tt.created)
+			}
+
+			var total int64
+			db.Unscoped().Model(&models.Question{}).Count(&total)
+			if int(total) != tt.total {
+				t.Errorf("%d questions after syncing, want %d", total, tt.total)
+			}
+			for i, row := range tt.rows {
+				var q models.Question
+				if err := db.Unscoped().First(&q, i+1).Error; err != nil {
+					t.Fatalf("baseline row %d: %v", i+1, err)
+				}
+				adopted := row.seedIndex >= 0 && !row.deleted
+				if got := q.ExternalID != nil && q.Source == models.SourceSeed; got != adopted {
+					t.Errorf("baseline row %d adopted = %v (source %q), want %v", i+1, got, q.Source, adopted)
+				}
+				if adopted && *q.ExternalID != seedIDPrefix+seedData()[row.seedIndex].ID {
+					t.Errorf("baseline row %d adopted as %s", i+1, *q.ExternalID)
+				}
+			}
+
+			again, err := SyncSeed(db, false)
+			if err != nil {
+				t.Fatalf("SyncSeed() error = %v", err)
+			}
+			if !again.Empty() {
+				t.Errorf("second SyncSeed() = %s, want no changes", again)
+			}
+		})
+	}
+}
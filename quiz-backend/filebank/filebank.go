@@ -0,0 +1,271 @@
+package filebank
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"aws-rds-quiz-backend/formats"
+	"aws-rds-quiz-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// externalIDPrefix namespaces the external IDs of file-backed questions
+const externalIDPrefix = "md:"
+
+// FileError reports a Markdown file that could not be synced
+type FileError struct {
+	File  string `json:"file"`
+	Error string `json:"error"`
+}
+
+// SyncReport summarizes a sync of the directory into the database
+type SyncReport struct {
+	Created   int         `json:"created"`
+	Updated   int         `json:"updated"`
+	Deleted   int         `json:"deleted"`
+	Unchanged int         `json:"unchanged"`
+	Errors    []FileError `json:"errors,omitempty"`
+}
+
+// Bank keeps the questions table in sync with a directory of Markdown files,
+// one question per file
+type Bank struct {
+	db  *gorm.DB
+	dir string
+}
+
+// New creates a Bank for the given directory
+func New(db *gorm.DB, dir string) *Bank {
+	return &Bank{db: db, dir: dir}
+}
+
+// Sync loads every *.md file in the directory tree and creates, updates or
+// soft-deletes questions so the table matches the files. Questions from files
+// that fail to parse are left untouched. New questions join the default bank.
+func (b *Bank) Sync() (*SyncReport, error) {
+	report := &SyncReport{}
+
+	bank, err := models.DefaultBank(b.db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load default bank: %v", err)
+	}
+
+	files, err := b.files()
+	if err != nil {
+		return nil, err
+	}
+
+	parsed := make(map[string]models.QuestionRequest, len(files))
+	keep := make(map[string]bool, len(files))
+	for _, path := range files {
+		id, req, err := b.parse(path, &bank)
+		if id != "" {
+			keep[id] = true
+		}
+		if err != nil {
+			report.Errors = append(report.Errors, FileError{File: b.rel(path), Error: err.Error()})
+			continue
+		}
+		if _, dup := parsed[id]; dup {
+			report.Errors = append(report.Errors, FileError{File: b.rel(path), Error: "duplicate id " + id})
+			continue
+		}
+		missing, err := models.MissingAttachments(b.db, req.AttachmentHashes())
+		if err != nil {
+			return nil, err
+		}
+		if len(missing) > 0 {
+			report.Errors = append(report.Errors, FileError{File: b.rel(path), Error: "unknown attachment " + missing[0]})
+			continue
+		}
+		parsed[id] = req
+	}
+
+	var existing []models.Question
+	if err := b.db.Unscoped().Scopes(models.WithTaxonomy).Where("source = ?", models.SourceMarkdown).Find(&existing).Error; err != nil {
+		return nil, fmt.Errorf("failed to load file-backed questions: %v", err)
+	}
+
+	err = b.db.Transaction(func(tx *gorm.DB) error {
+		for i := range existing {
+			q := &existing[i]
+			id := ""
+			if q.ExternalID != nil {
+				id = *q.ExternalID
+			}
+
+			req, ok := parsed[id]
+			if !ok {
+				if !keep[id] && !q.DeletedAt.Valid {
+					if err := tx.Delete(q).Error; err != nil {
+						return err
+					}
+					report.Deleted++
+				}
+				continue
+			}
+			delete(parsed, id)
+
+			current, err := q.ToRequest()
+			if err == nil && !q.DeletedAt.Valid && reflect.DeepEqual(current, req) {
+				report.Unchanged++
+				continue
+			}
+			if err := req.ApplyTo(q); err != nil {
+				return err
+			}
+			q.DeletedAt = gorm.DeletedAt{}
+			if err := tx.Unscoped().Save(q).Error; err != nil {
+				return err
+			}
+			report.Updated++
+		}
+
+		ids := make([]string, 0, len(parsed))
+		for id := range parsed {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			req := parsed[id]
+			externalID := id
+			q := models.Question{BankID: bank.ID, Source: models.SourceMarkdown, ExternalID: &externalID}
+			if err := req.ApplyTo(&q); err != nil {
+				return err
+			}
+			if err := tx.Create(&q).Error; err != nil {
+				return fmt.Errorf("%s: %v", id, err)
+			}
+			report.Created++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sync question files: %v", err)
+	}
+
+	return report, nil
+}
+
+// Watch polls the directory and re-syncs whenever a file is added, removed or
+// modified. It never returns.
+func (b *Bank) Watch(interval time.Duration) {
+	last, _ := b.fingerprint()
+	for range time.Tick(interval) {
+		current, err := b.fingerprint()
+		if err != nil {
+			log.Printf("Warning: Failed to scan question files: %v", err)
+			continue
+		}
+		if current == last {
+			continue
+		}
+		last = current
+
+		report, err := b.Sync()
+		if err != nil {
+			log.Printf("Warning: %v", err)
+			continue
+		}
+		b.log(report)
+	}
+}
+
+// SyncAndWatch performs an initial sync and then watches for changes in the
+// background
+func (b *Bank) SyncAndWatch(interval time.Duration) error {
+	report, err := b.Sync()
+	if err != nil {
+		return err
+	}
+	b.log(report)
+
+	go b.Watch(interval)
+	return nil
+}
+
+func (b *Bank) log(report *SyncReport) {
+	log.Printf("Synced question files from %s: %d created, %d updated, %d deleted, %d unchanged",
+		b.dir, report.Created, report.Updated, report.Deleted, report.Unchanged)
+	for _, fe := range report.Errors {
+		log.Printf("Warning: %s: %s", fe.File, fe.Error)
+	}
+}
+
+// parse reads one file and returns its external ID and normalized request
+func (b *Bank) parse(path string, bank *models.Bank) (string, models.QuestionRequest, error) {
+	id := externalIDPrefix + strings.TrimSuffix(filepath.ToSlash(b.rel(path)), filepath.Ext(path))
+
+	f, err := os.Open(path)
+	if err != nil {
+		return id, models.QuestionRequest{}, err
+	}
+	defer f.Close()
+
+	mq, err := formats.DecodeMarkdown(f)
+	if mq.ID != "" {
+		id = externalIDPrefix + mq.ID
+	}
+	if err != nil {
+		return id, mq.Question, err
+	}
+
+	mq.Question.Normalize()
+	if err := bank.Check(&mq.Question); err != nil {
+		return id, mq.Question, err
+	}
+	return id, mq.Question, nil
+}
+
+// files lists the Markdown files under the directory in a stable order
+func (b *Bank) files() ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(b.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.EqualFold(filepath.Ext(path), ".md") {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read question directory: %v", err)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// fingerprint summarizes the names, sizes and modification times of the
+// Markdown files so changes can be detected cheaply
+func (b *Bank) fingerprint() (string, error) {
+	files, err := b.files()
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	for _, path := range files {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(&sb, "%s|%d|%d\n", path, info.Size(), info.ModTime().UnixNano())
+	}
+	return sb.String(), nil
+}
+
+func (b *Bank) rel(path string) string {
+	if rel, err := filepath.Rel(b.dir, path); err == nil {
+		return rel
+	}
+	return path
+}
@@ -0,0 +1,190 @@
+package formats
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strings"
+
+	"aws-rds-quiz-backend/models"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+// File is an attachment carried inside an import or export file
+type File struct {
+	SHA256   string
+	Filename string
+	MIMEType string
+	Data     []byte
+}
+
+// Loader returns the stored attachment with the given hash
+type Loader func(sha256 string) (File, error)
+
+var (
+	// dataRef matches an image embedded as a data URI, which is how the
+	// text formats carry attachments
+	dataRef = regexp.MustCompile(`!\[([^\]\n]*)\]\(data:([\w.+-]+/[\w.+-]+);base64,([A-Za-z0-9+/=]*)\)`)
+
+	htmlImage = regexp.MustCompile(`(?i)<img\b[^>]*>`)
+	htmlAttr  = regexp.MustCompile(`(?i)\b(src|alt)\s*=\s*("[^"]*"|'[^']*')`)
+)
+
+// newFile wraps data read from an import file
+func newFile(filename, mimeType string, data []byte) File {
+	sum := sha256.Sum256(data)
+	return File{SHA256: hex.EncodeToString(sum[:]), Filename: filename, MIMEType: mimeType, Data: data}
+}
+
+// embeddedName names an attachment inside an archive or Moodle question
+func embeddedName(f File) string {
+	mimeType := f.MIMEType
+	if mimeType == "" {
+		mimeType = mimetype.Detect(f.Data).String()
+	}
+	return f.SHA256[:16] + mimetype.Lookup(mimeType).Extension()
+}
+
+// attachmentSet loads each attachment referenced by an export once
+type attachmentSet struct {
+	load  Loader
+	files map[string]*File
+}
+
+func newAttachmentSet(load Loader) *attachmentSet {
+	return &attachmentSet{load: load, files: make(map[string]*File)}
+}
+
+// get returns the attachment with the given hash, or nil when there is no
+// loader or the attachment cannot be loaded
+func (s *attachmentSet) get(sha string) (*File, error) {
+	if s.load == nil {
+		return nil, nil
+	}
+	if f, ok := s.files[sha]; ok {
+		return f, nil
+	}
+	f, err := s.load(sha)
+	if err != nil {
+		s.files[sha] = nil
+		return nil, err
+	}
+	s.files[sha] = &f
+	return &f, nil
+}
+
+// withDataURIs wraps an encoder for a text format so that attachment
+// references are written as data URIs, carrying the binaries inline
+func withDataURIs(encode func(io.Writer, []models.QuestionRequest) ([]string, error)) encodeFunc {
+	return func(w io.Writer, questions []models.QuestionRequest, load Loader) ([]string, error) {
+		set := newAttachmentSet(load)
+		var warnings []string
+		inlined := make([]models.QuestionRequest, len(questions))
+		for i, q := range questions {
+			inline := func(text string) string {
+				return models.ReplaceAttachmentRefs(text, identity, func(ref models.AttachmentRef) string {
+					f, err := set.get(ref.SHA256)
+					if f == nil {
+						warnings = append(warnings, missingAttachment(i, ref, err)...)
+						return models.AttachmentReference(ref.Alt, ref.SHA256)
+					}
+					return fmt.Sprintf("![%s](data:%s;base64,%s)", ref.Alt, f.MIMEType, base64.StdEncoding.EncodeToString(f.Data))
+				})
+			}
+			q.Question = inline(q.Question)
+			q.Options = append([]string(nil), q.Options...)
+			for j := range q.Options {
+				q.Options[j] = inline(q.Options[j])
+			}
+			inlined[i] = q
+		}
+		encodeWarnings, err := encode(w, inlined)
+		return append(warnings, encodeWarnings...), err
+	}
+}
+
+// missingAttachment warns about a reference whose binary was not exported
+func missingAttachment(i int, ref models.AttachmentRef, err error) []string {
+	if err == nil {
+		return nil
+	}
+	return []string{fmt.Sprintf("question %d: attachment %s was not exported: %v", i+1, ref.SHA256, err)}
+}
+
+// extractDataURIs turns the data URIs in a decoded row's question text and
+// options back into attachment references, collecting the binaries
+func extractDataURIs(row *Row) {
+	extract := func(field, text string) string {
+		return dataRef.ReplaceAllStringFunc(text, func(match string) string {
+			m := dataRef.FindStringSubmatch(match)
+			data, err := base64.StdEncoding.DecodeString(m[3])
+			if err != nil {
+				if row.Err == nil {
+					row.Err = fmt.Errorf("invalid image data in %s: %v", field, err)
+				}
+				return match
+			}
+			f := newFile("", m[2], data)
+			row.Files = appendFile(row.Files, f)
+			return models.AttachmentReference(m[1], f.SHA256)
+		})
+	}
+	row.Question.Question = extract("question text", row.Question.Question)
+	for i := range row.Question.Options {
+		row.Question.Options[i] = extract(fmt.Sprintf("option %d", i+1), row.Question.Options[i])
+	}
+}
+
+// htmlWithImages renders text as an HTML fragment with its attachment
+// references as <img> elements. src names the image in the target format.
+// Each image is added to files; references that cannot be loaded stay as
+// text and are reported in warnings.
+func htmlWithImages(i int, text string, set *attachmentSet, escape func(string) string, src func(File) string, files *[]File, warnings *[]string) string {
+	return models.ReplaceAttachmentRefs(text, escape, func(ref models.AttachmentRef) string {
+		f, err := set.get(ref.SHA256)
+		if f == nil {
+			*warnings = append(*warnings, missingAttachment(i, ref, err)...)
+			return escape(models.AttachmentReference(ref.Alt, ref.SHA256))
+		}
+		*files = appendFile(*files, *f)
+		return fmt.Sprintf(`<img src="%s" alt="%s" />`, html.EscapeString(src(*f)), html.EscapeString(ref.Alt))
+	})
+}
+
+// imagesToRefs replaces the <img> elements of an HTML fragment whose source
+// resolves to a file with attachment references, so that htmlToText keeps
+// them. Images that do not resolve are left for htmlToText to drop.
+func imagesToRefs(fragment string, resolve func(src string) (File, bool), files *[]File) string {
+	return htmlImage.ReplaceAllStringFunc(fragment, func(tag string) string {
+		attrs := map[string]string{}
+		for _, m := range htmlAttr.FindAllStringSubmatch(tag, -1) {
+			attrs[strings.ToLower(m[1])] = html.UnescapeString(m[2][1 : len(m[2])-1])
+		}
+		f, ok := resolve(attrs["src"])
+		if !ok {
+			return tag
+		}
+		*files = appendFile(*files, f)
+		alt := strings.NewReplacer("[", "", "]", "", "\n", " ").Replace(attrs["alt"])
+		return html.EscapeString(models.AttachmentReference(alt, f.SHA256))
+	})
+}
+
+// appendFile adds f unless a file with the same content is already listed
+func appendFile(files []File, f File) []File {
+	for _, existing := range files {
+		if existing.SHA256 == f.SHA256 {
+			return files
+		}
+	}
+	return append(files, f)
+}
+
+func identity(s string) string {
+	return s
+}
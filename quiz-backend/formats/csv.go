@@ -0,0 +1,239 @@
+package formats
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"aws-rds-quiz-backend/models"
+)
+
+// decodeCSV reads a spreadsheet export with a header row. Recognised columns
+// are question, option1..option6 (or a single "options" column separated by
+// "|"), correctAnswer (0-based index or letter A-F; several separated by "|"
+// for multiple choice), explanation, category, difficulty, type, scoring,
+// answerKey (a JSON object, for the types that need one), tags (separated by
+// "|"), topic (a path such as "RDS > High Availability"),
+// rationale1..rationale6 (why each option is right or wrong), references (a
+// JSON array of {"title", "url"} objects) and variants (a template's value
+// table, a JSON array of objects). Header names are matched case-insensitively
+// and ignore spaces, dashes and underscores.
+func decodeCSV(r io.Reader) ([]Row, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %v", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[normalizeHeader(name)] = i
+	}
+	if _, ok := columns["question"]; !ok {
+		return nil, fmt.Errorf("invalid CSV: missing \"question\" column")
+	}
+
+	var rows []Row
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %v", err)
+		}
+		line, _ := reader.FieldPos(0)
+		if isBlank(record) {
+			continue
+		}
+
+		req, err := csvQuestion(columns, record)
+		rows = append(rows, Row{Line: line, Question: req, Err: err})
+	}
+	return rows, nil
+}
+
+func csvQuestion(columns map[string]int, record []string) (models.QuestionRequest, error) {
+	field := func(name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	req := models.QuestionRequest{
+		Question:    field("question"),
+		Explanation: field("explanation"),
+		Category:    field("category"),
+		Difficulty:  field("difficulty"),
+		Type:        field("type"),
+		Scoring:     field("scoring"),
+		Topic:       field("topic"),
+	}
+	if tags := field("tags"); tags != "" {
+		req.Tags = strings.Split(tags, "|")
+	}
+	if key := field("answerkey"); key != "" {
+		req.AnswerKey = json.RawMessage(key)
+	}
+	if references := field("references"); references != "" {
+		if err := json.Unmarshal([]byte(references), &req.References); err != nil {
+			return req, fmt.Errorf("invalid references: %v", err)
+		}
+	}
+	if variants := field("variants"); variants != "" {
+		if err := json.Unmarshal([]byte(variants), &req.Variants); err != nil {
+			return req, fmt.Errorf("invalid variants: %v", err)
+		}
+	}
+
+	if options := field("options"); options != "" {
+		req.Options = strings.Split(options, "|")
+	} else {
+		for i := 1; i <= 6; i++ {
+			if option := field("option" + strconv.Itoa(i)); option != "" {
+				req.Options = append(req.Options, option)
+			}
+		}
+	}
+	for i := 1; i <= len(req.Options); i++ {
+		req.Rationales = append(req.Rationales, field("rationale"+strconv.Itoa(i)))
+	}
+
+	answer := field("correctanswer")
+	if answer == "" {
+		if req.AnswerKey != nil {
+			return req, nil
+		}
+		return req, fmt.Errorf("correctAnswer is required")
+	}
+	for _, value := range strings.FieldsFunc(answer, isAnswerSeparator) {
+		index, err := parseAnswerIndex(strings.TrimSpace(value))
+		if err != nil {
+			return req, err
+		}
+		req.CorrectAnswers = append(req.CorrectAnswers, index)
+	}
+	if len(req.CorrectAnswers) > 1 && req.Type == "" {
+		req.Type = models.QuestionTypeMultiple
+	}
+	if req.Type == "" || req.Type == models.QuestionTypeSingle {
+		req.CorrectAnswer = req.CorrectAnswers[0]
+		req.CorrectAnswers = nil
+	}
+
+	return req, nil
+}
+
+// parseAnswerIndex accepts a 0-based index or a single option letter
+func parseAnswerIndex(value string) (int, error) {
+	if index, err := strconv.Atoi(value); err == nil {
+		return index, nil
+	}
+	if len(value) == 1 {
+		letter := strings.ToUpper(value)[0]
+		if letter >= 'A' && letter <= 'F' {
+			return int(letter - 'A'), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid correctAnswer %q: use a 0-based index or a letter A-F", value)
+}
+
+func isAnswerSeparator(r rune) bool {
+	return r == '|' || r == ','
+}
+
+func normalizeHeader(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	return strings.NewReplacer(" ", "", "_", "", "-", "").Replace(name)
+}
+
+func isBlank(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// encodeCSV writes one row per question using option1..option6 columns
+func encodeCSV(w io.Writer, questions []models.QuestionRequest) ([]string, error) {
+	writer := csv.NewWriter(w)
+
+	header := []string{"question"}
+	for i := 1; i <= 6; i++ {
+		header = append(header, "option"+strconv.Itoa(i))
+	}
+	header = append(header, "correctAnswer", "explanation", "category", "difficulty", "type", "scoring", "answerKey", "tags", "topic")
+	for i := 1; i <= 6; i++ {
+		header = append(header, "rationale"+strconv.Itoa(i))
+	}
+	header = append(header, "references", "variants")
+	if err := writer.Write(header); err != nil {
+		return nil, err
+	}
+
+	var warnings []string
+	for n, q := range questions {
+		if len(q.Options) > 6 {
+			warnings = append(warnings, fmt.Sprintf("question %d: skipped, only 6 option columns are available", n+1))
+			continue
+		}
+		record := []string{q.Question}
+		for i := 0; i < 6; i++ {
+			option := ""
+			if i < len(q.Options) {
+				option = q.Options[i]
+			}
+			record = append(record, option)
+		}
+		answer := ""
+		switch {
+		case len(q.CorrectAnswers) > 0:
+			indices := make([]string, len(q.CorrectAnswers))
+			for i, index := range q.CorrectAnswers {
+				indices[i] = strconv.Itoa(index)
+			}
+			answer = strings.Join(indices, "|")
+		case len(q.AnswerKey) == 0:
+			answer = strconv.Itoa(q.CorrectAnswer)
+		}
+		record = append(record, answer, q.Explanation, q.Category, q.Difficulty, q.Type, q.Scoring, string(q.AnswerKey), strings.Join(q.Tags, "|"), q.Topic)
+		for i := 0; i < 6; i++ {
+			record = append(record, models.RationaleAt(q.Rationales, i))
+		}
+		references := ""
+		if len(q.References) > 0 {
+			data, err := json.Marshal(q.References)
+			if err != nil {
+				return nil, err
+			}
+			references = string(data)
+		}
+		variants := ""
+		if len(q.Variants) > 0 {
+			data, err := json.Marshal(q.Variants)
+			if err != nil {
+				return nil, err
+			}
+			variants = string(data)
+		}
+		record = append(record, references, variants)
+		if err := writer.Write(record); err != nil {
+			return nil, err
+		}
+	}
+
+	writer.Flush()
+	return warnings, writer.Error()
+}
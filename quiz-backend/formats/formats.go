@@ -0,0 +1,177 @@
+package formats
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"aws-rds-quiz-backend/models"
+)
+
+// Supported interchange formats
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+	FormatYAML = "yaml"
+)
+
+// Row is a single question read from an import file. Line is the 1-based
+// line in the source file where the question starts; for archive formats it
+// is the position of the item and Source names the file it came from. Err is
+// set when the row could not be decoded into a QuestionRequest, and Warnings
+// lists anything that was dropped or approximated while decoding it. Files
+// holds the attachments the file carried for the question's references.
+type Row struct {
+	Line     int                    `json:"line"`
+	Source   string                 `json:"source,omitempty"`
+	Question models.QuestionRequest `json:"question"`
+	Warnings []string               `json:"warnings,omitempty"`
+	Files    []File                 `json:"-"`
+	Err      error                  `json:"-"`
+}
+
+// encodeFunc writes questions, loading referenced attachments with load, and
+// returns a warning for each conversion that could not be represented exactly
+// in the target format
+type encodeFunc func(io.Writer, []models.QuestionRequest, Loader) ([]string, error)
+
+// decoders maps a format name to its decoder
+var decoders = map[string]func(io.Reader) ([]Row, error){
+	FormatJSON:      decodeJSON,
+	FormatCSV:       decodeCSV,
+	FormatYAML:      decodeYAML,
+	FormatGIFT:      decodeGIFT,
+	FormatMoodleXML: decodeMoodleXML,
+	FormatQTI:       decodeQTI,
+}
+
+// encoders maps a format name to its encoder
+var encoders = map[string]encodeFunc{
+	FormatJSON:      withDataURIs(encodeJSON),
+	FormatCSV:       withDataURIs(encodeCSV),
+	FormatYAML:      withDataURIs(encodeYAML),
+	FormatGIFT:      withDataURIs(encodeGIFT),
+	FormatMoodleXML: encodeMoodleXML,
+	FormatQTI:       encodeQTI,
+}
+
+// contentTypes maps a format name to the MIME type used when exporting
+var contentTypes = map[string]string{
+	FormatJSON:      "application/json",
+	FormatCSV:       "text/csv; charset=utf-8",
+	FormatYAML:      "application/yaml",
+	FormatGIFT:      "text/plain; charset=utf-8",
+	FormatMoodleXML: "application/xml",
+	FormatQTI:       "application/zip",
+}
+
+// Decode reads all questions from r in the given format. Images embedded as
+// data URIs become attachment references with their binaries in Row.Files.
+func Decode(format string, r io.Reader) ([]Row, error) {
+	decode, ok := decoders[format]
+	if !ok {
+		return nil, fmt.Errorf("unsupported import format: %s", format)
+	}
+	rows, err := decode(r)
+	if err != nil {
+		return nil, err
+	}
+	for i := range rows {
+		extractDataURIs(&rows[i])
+	}
+	return rows, nil
+}
+
+// Encode writes questions to w in the given format. Attachments referenced
+// by the questions are loaded with load and embedded in the file: as data
+// URIs in the text formats, as files in Moodle XML and QTI. A nil load leaves
+// the references as they are. The returned warnings describe lossy
+// conversions, one per affected question.
+func Encode(format string, w io.Writer, questions []models.QuestionRequest, load Loader) ([]string, error) {
+	encode, ok := encoders[format]
+	if !ok {
+		return nil, fmt.Errorf("unsupported export format: %s", format)
+	}
+	return encode(w, questions, load)
+}
+
+// ContentType returns the MIME type for an export format
+func ContentType(format string) string {
+	return contentTypes[format]
+}
+
+// Extension returns the file extension used for an export format
+func Extension(format string) string {
+	switch format {
+	case FormatMoodleXML:
+		return ".xml"
+	case FormatQTI:
+		return ".zip"
+	}
+	return "." + format
+}
+
+// FormatFromFilename guesses the format from a file extension
+func FormatFromFilename(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+		return FormatJSON
+	case ".csv":
+		return FormatCSV
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".gift", ".txt":
+		return FormatGIFT
+	case ".xml":
+		return FormatMoodleXML
+	case ".zip":
+		return FormatQTI
+	}
+	return ""
+}
+
+// decodeQuestion strictly decodes a JSON document into a QuestionRequest so
+// that misspelled field names are reported instead of silently ignored
+func decodeQuestion(data []byte) (models.QuestionRequest, error) {
+	var req models.QuestionRequest
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		return req, err
+	}
+	return req, nil
+}
+
+// answerRationales returns per-answer feedback as option rationales. When
+// there is no explanation and only the first correct answer has feedback,
+// that feedback becomes the explanation instead.
+func answerRationales(req *models.QuestionRequest, feedbacks []string, first int) []string {
+	var given []int
+	for i, feedback := range feedbacks {
+		if feedback != "" {
+			given = append(given, i)
+		}
+	}
+	switch {
+	case len(given) == 0:
+		return nil
+	case len(given) == 1 && given[0] == first && req.Explanation == "":
+		req.Explanation = feedbacks[first]
+		return nil
+	}
+	return feedbacks
+}
+
+// mustMarshal encodes an answer key built by a decoder. The key types only
+// hold strings, numbers and booleans, so encoding cannot fail.
+func mustMarshal(v interface{}) json.RawMessage {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
@@ -0,0 +1,190 @@
+package formats
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"aws-rds-quiz-backend/models"
+)
+
+// sample questions of every type, as requests the authoring API accepts
+var (
+	singleChoice = `{"question": "What is the default MySQL port?", "options": ["3306", "5432", "1521", "1433"], "correctAnswer": 0,
+		"explanation": "MySQL listens on 3306.", "category": "RDS", "difficulty": "easy"}`
+	multipleChoice = `{"question": "Which engines does Aurora support?", "type": "multiple_choice", "options": ["MySQL", "PostgreSQL", "Oracle", "SQL Server"],
+		"correctAnswers": [0, 1], "category": "Aurora", "difficulty": "medium"}`
+	trueFalse = `{"question": "Multi-AZ standbys serve read traffic.", "type": "true_false", "answerKey": {"answer": false},
+		"category": "RDS", "difficulty": "medium"}`
+	matching = `{"question": "Match each engine to its default port.", "type": "matching", "options": ["MySQL", "PostgreSQL"],
+		"answerKey": {"targets": ["3306", "5432"], "pairs": [0, 1]}, "category": "RDS", "difficulty": "hard"}`
+	fillBlank = `{"question": "Which port does PostgreSQL listen on by default?", "type": "fill_blank", "answerKey": {"accepted": ["5432"]},
+		"category": "RDS", "difficulty": "easy"}`
+	numeric = `{"question": "How many days of automated backups can RDS keep at most?", "type": "numeric", "answerKey": {"value": 35, "tolerance": 1},
+		"category": "RDS", "difficulty": "medium"}`
+	ordering = `{"question": "Order the steps of a point-in-time restore.", "type": "ordering", "options": ["Pick a time", "Restore", "Repoint the app"],
+		"answerKey": {"order": [0, 1, 2]}, "category": "RDS", "difficulty": "hard"}`
+	sqlExercise = `{"question": "List the MySQL instances.", "type": "sql", "answerKey": {"fixture": "CREATE TABLE db (name TEXT, engine TEXT);",
+		"reference": "SELECT name FROM db WHERE engine = 'mysql'"}, "category": "RDS", "difficulty": "hard"}`
+)
+
+// request builds a normalized, valid question request
+func request(t *testing.T, body string) models.QuestionRequest {
+	t.Helper()
+	var req models.QuestionRequest
+	if err := json.Unmarshal([]byte(body), &req); err != nil {
+		t.Fatalf("invalid request: %v", err)
+	}
+	req.Normalize()
+	if err := req.Validate(); err != nil {
+		t.Fatalf("invalid question: %v", err)
+	}
+	return req
+}
+
+// gradedFields is the part of a question that decides how it is shown and
+// graded, which a round trip must preserve
+type gradedFields struct {
+	Question       string
+	Options        []string
+	CorrectAnswer  int
+	Type           string
+	CorrectAnswers []int
+	Scoring        string
+	AnswerKey      interface{}
+	Category       string
+	Difficulty     string
+}
+
+func graded(t *testing.T, req models.QuestionRequest) gradedFields {
+	t.Helper()
+	// Round trip through a question so equivalent keys compare equal
+	var q models.Question
+	if err := req.ApplyTo(&q); err != nil {
+		t.Fatalf("failed to apply request: %v", err)
+	}
+	normalized, err := q.ToRequest()
+	if err != nil {
+		t.Fatalf("failed to rebuild request: %v", err)
+	}
+	var key interface{}
+	if len(normalized.AnswerKey) > 0 {
+		if err := json.Unmarshal(normalized.AnswerKey, &key); err != nil {
+			t.Fatalf("invalid answer key: %v", err)
+		}
+	}
+	return gradedFields{
+		Question:       normalized.Question,
+		Options:        normalized.Options,
+		CorrectAnswer:  normalized.CorrectAnswer,
+		Type:           normalized.Type,
+		CorrectAnswers: normalized.CorrectAnswers,
+		Scoring:        normalized.Scoring,
+		AnswerKey:      key,
+		Category:       normalized.Category,
+		Difficulty:     normalized.Difficulty,
+	}
+}
+
+// roundTrip encodes a question in format, decodes it back and checks that
+// the graded fields survived
+func roundTrip(t *testing.T, format, body string) {
+	t.Helper()
+	want := request(t, body)
+
+	var buf bytes.Buffer
+	warnings, err := Encode(format, &buf, []models.QuestionRequest{want}, nil)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if len(warnings) > 0 {
+		t.Fatalf("Encode() warnings = %v", warnings)
+	}
+	rows, err := Decode(format, &buf)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("Decode() returned %d rows, want 1", len(rows))
+	}
+	if rows[0].Err != nil {
+		t.Fatalf("Decode() row error = %v", rows[0].Err)
+	}
+	got := rows[0].Question
+	got.Normalize()
+	if err := got.Validate(); err != nil {
+		t.Fatalf("decoded question is invalid: %v", err)
+	}
+
+	wantFields, gotFields := graded(t, want), graded(t, got)
+	if !reflect.DeepEqual(wantFields, gotFields) {
+		t.Errorf("round trip changed the question\n got: %+v\nwant: %+v", gotFields, wantFields)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	questions := []struct {
+		name string
+		body string
+	}{
+		{"single choice", singleChoice},
+		{"multiple choice", multipleChoice},
+		{"true false", trueFalse},
+		{"matching", matching},
+		{"fill blank", fillBlank},
+		{"numeric", numeric},
+		{"ordering", ordering},
+		{"sql", sqlExercise},
+	}
+
+	for _, format := range []string{FormatJSON, FormatCSV, FormatYAML} {
+		for _, q := range questions {
+			t.Run(format+"/"+q.name, func(t *testing.T) {
+				roundTrip(t, format, q.body)
+			})
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		input  string
+		rows   int
+		bad    []int // lines of the rows that fail to decode
+	}{
+		{"json not an array", FormatJSON, `{"question": "x"}`, 0, nil},
+		{"json bad row", FormatJSON, "[\n{\"question\": \"ok\", \"options\": [\"a\", \"b\"]},\n{\"question\": 5}\n]", 2, []int{3}},
+		{"csv bad answer", FormatCSV, "question,options,correct_answer\nok,a|b,0\nbad,a|b,x\n", 2, []int{3}},
+		{"yaml bad row", FormatYAML, "- question: ok\n  options: [a, b]\n- question: [1]\n", 2, []int{3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := Decode(tt.format, bytes.NewBufferString(tt.input))
+			if tt.rows == 0 {
+				if err == nil {
+					t.Fatalf("Decode() returned %d rows, want an error", len(rows))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if len(rows) != tt.rows {
+				t.Fatalf("Decode() returned %d rows, want %d", len(rows), tt.rows)
+			}
+			var bad []int
+			for _, row := range rows {
+				if row.Err != nil {
+					bad = append(bad, row.Line)
+				}
+			}
+			if !reflect.DeepEqual(bad, tt.bad) {
+				t.Errorf("rows failing on lines %v, want %v", bad, tt.bad)
+			}
+		})
+	}
+}
@@ -0,0 +1,613 @@
+package formats
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"aws-rds-quiz-backend/models"
+)
+
+// FormatGIFT is the Moodle GIFT text format
+const FormatGIFT = "gift"
+
+// Comment conventions used to carry a question's difficulty, tags, topic and
+// documentation references, which GIFT has no syntax for. Moodle ignores
+// comments. A reference is written as "// reference: <url> <title>".
+const (
+	giftDifficulty = "// difficulty:"
+	giftTags       = "// tags:"
+	giftTopic      = "// topic:"
+	giftReference  = "// reference:"
+)
+
+// giftBlock is one question's worth of GIFT source
+type giftBlock struct {
+	line       int
+	text       string
+	category   string
+	difficulty string
+	tags       []string
+	topic      string
+	references []models.Reference
+}
+
+// decodeGIFT reads questions in Moodle GIFT format. Only the constructs that
+// map onto a models.Question are accepted; anything else (essay, missing
+// word, uneven partial credit weights) rejects that question with an error
+// naming the construct. Moodle multiple-answer questions, where each
+// correct answer carries an equal positive weight, become multiple_choice
+// questions with partial scoring. True/false, short answer and matching
+// questions become true_false, fill_blank and matching questions; Moodle
+// grades matching pair by pair, so those use partial scoring. Numeric answers
+// become numeric questions with an absolute tolerance. Per-answer feedback on
+// choice questions becomes the option rationales, except that feedback on
+// the correct answer alone is taken as the explanation when there is no
+// "####" general feedback.
+func decodeGIFT(r io.Reader) ([]Row, error) {
+	blocks, err := splitGIFT(r)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]Row, 0, len(blocks))
+	for _, block := range blocks {
+		req, err := parseGIFTQuestion(block)
+		if err != nil {
+			err = fmt.Errorf("line %d: %v", block.line, err)
+		}
+		rows = append(rows, Row{Line: block.line, Question: req, Err: err})
+	}
+	return rows, nil
+}
+
+// splitGIFT breaks the input into blank-line separated question blocks,
+// tracking $CATEGORY: directives and difficulty, tags, topic and reference
+// comments as it goes
+func splitGIFT(r io.Reader) ([]giftBlock, error) {
+	var (
+		blocks     []giftBlock
+		current    *giftBlock
+		lines      []string
+		category   string
+		difficulty string
+		tags       []string
+		topic      string
+		references []models.Reference
+		depth      int
+	)
+
+	flush := func() {
+		if current != nil {
+			current.text = strings.Join(lines, "\n")
+			blocks = append(blocks, *current)
+		}
+		current, lines, difficulty, tags, topic, references = nil, nil, "", nil, "", nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+		if lineNo == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		trimmed := strings.TrimSpace(line)
+
+		if depth == 0 {
+			switch {
+			case trimmed == "":
+				flush()
+				continue
+			case strings.HasPrefix(trimmed, giftDifficulty):
+				difficulty = strings.TrimSpace(strings.TrimPrefix(trimmed, giftDifficulty))
+				continue
+			case strings.HasPrefix(trimmed, giftTags):
+				tags = strings.Split(strings.TrimPrefix(trimmed, giftTags), ",")
+				continue
+			case strings.HasPrefix(trimmed, giftTopic):
+				topic = strings.TrimSpace(strings.TrimPrefix(trimmed, giftTopic))
+				continue
+			case strings.HasPrefix(trimmed, giftReference):
+				link, title, _ := strings.Cut(strings.TrimSpace(strings.TrimPrefix(trimmed, giftReference)), " ")
+				references = append(references, models.Reference{Title: strings.TrimSpace(title), URL: link})
+				continue
+			case strings.HasPrefix(trimmed, "//"):
+				continue
+			case strings.HasPrefix(trimmed, "$CATEGORY:"):
+				flush()
+				category = giftCategory(strings.TrimPrefix(trimmed, "$CATEGORY:"))
+				continue
+			}
+		}
+
+		if current == nil {
+			current = &giftBlock{line: lineNo, category: category, difficulty: difficulty, tags: tags, topic: topic, references: references}
+		}
+		lines = append(lines, line)
+		depth += braceDelta(line)
+		if depth < 0 {
+			return nil, fmt.Errorf("line %d: unexpected \"}\"", lineNo)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if depth > 0 {
+		return nil, fmt.Errorf("line %d: unclosed \"{\" in question", current.line)
+	}
+	flush()
+
+	return blocks, nil
+}
+
+// giftCategory keeps the last segment of a Moodle category path such as
+// "$course$/top/RDS"
+func giftCategory(path string) string {
+	path = strings.TrimSpace(path)
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		path = path[i+1:]
+	}
+	return path
+}
+
+// braceDelta counts unescaped braces on a line
+func braceDelta(line string) int {
+	delta := 0
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '{':
+			delta++
+		case '}':
+			delta--
+		}
+	}
+	return delta
+}
+
+// parseGIFTQuestion converts a single block into a QuestionRequest
+func parseGIFTQuestion(block giftBlock) (models.QuestionRequest, error) {
+	req := models.QuestionRequest{Category: block.category, Difficulty: block.difficulty, Tags: block.tags, Topic: block.topic, References: block.references}
+	text := strings.TrimSpace(block.text)
+
+	// Optional ::title::, which has no counterpart in the model
+	if strings.HasPrefix(text, "::") {
+		end := indexUnescaped(text[2:], "::")
+		if end < 0 {
+			return req, fmt.Errorf("unterminated \"::\" question title")
+		}
+		text = strings.TrimSpace(text[end+4:])
+	}
+
+	open := indexUnescaped(text, "{")
+	if open < 0 {
+		return req, fmt.Errorf("description items without an answer block are not supported")
+	}
+	close := open + 1 + indexUnescaped(text[open+1:], "}")
+	if close <= open {
+		return req, fmt.Errorf("unclosed \"{\" in question")
+	}
+
+	req.Question = giftUnescape(stripGIFTMarkup(text[:open]))
+	if rest := strings.TrimSpace(text[close+1:]); rest != "" {
+		return req, fmt.Errorf("missing word questions (text after the answer block) are not supported")
+	}
+
+	answers := strings.TrimSpace(text[open+1 : close])
+	switch {
+	case answers == "":
+		return req, fmt.Errorf("essay questions are not supported")
+	case strings.HasPrefix(answers, "#"):
+		return parseGIFTNumeric(req, answers[1:])
+	}
+
+	// General feedback follows "####" and becomes the explanation
+	if i := indexUnescaped(answers, "####"); i >= 0 {
+		req.Explanation = giftUnescape(strings.TrimSpace(answers[i+4:]))
+		answers = strings.TrimSpace(answers[:i])
+	}
+
+	switch strings.ToUpper(answers) {
+	case "T", "TRUE":
+		return giftTrueFalse(req, true)
+	case "F", "FALSE":
+		return giftTrueFalse(req, false)
+	}
+	if first := strings.ToUpper(answers)[0]; first == 'T' || first == 'F' {
+		return req, fmt.Errorf("feedback on true/false answers is not supported")
+	}
+	if indexUnescaped(answers, "->") >= 0 {
+		return parseGIFTMatching(req, answers)
+	}
+
+	var (
+		correct   []int
+		weights   []float64
+		feedbacks []string
+		weighted  bool
+		anyWrong  bool
+	)
+	for _, answer := range splitGIFTAnswers(answers) {
+		marker, body := answer[0], strings.TrimSpace(answer[1:])
+
+		weight := 0.0
+		if marker == '=' {
+			weight = 100
+		}
+		if strings.HasPrefix(body, "%") {
+			end := strings.Index(body[1:], "%")
+			if end < 0 {
+				return req, fmt.Errorf("unterminated answer weight in %q", body)
+			}
+			value, err := strconv.ParseFloat(body[1:end+1], 64)
+			if err != nil {
+				return req, fmt.Errorf("invalid answer weight %q", body[:end+2])
+			}
+			weight, weighted = value, true
+			body = strings.TrimSpace(body[end+2:])
+		}
+		if marker == '~' {
+			anyWrong = true
+		}
+
+		feedback := ""
+		if i := indexUnescaped(body, "#"); i >= 0 {
+			feedback = giftUnescape(strings.TrimSpace(body[i+1:]))
+			body = body[:i]
+		}
+		if weight > 0 {
+			correct = append(correct, len(req.Options))
+			weights = append(weights, weight)
+		}
+		req.Options = append(req.Options, giftUnescape(body))
+		feedbacks = append(feedbacks, feedback)
+	}
+
+	if len(correct) == 0 {
+		return req, fmt.Errorf("question has no correct (\"=\") answer")
+	}
+	req.Rationales = answerRationales(&req, feedbacks, correct[0])
+	if !anyWrong {
+		if req.Rationales != nil {
+			return req, fmt.Errorf("feedback on short answers is not supported")
+		}
+		return giftShortAnswer(req, weights)
+	}
+
+	if len(correct) == 1 {
+		if weights[0] != 100 {
+			return req, fmt.Errorf("partial credit weight %g%% is not supported", weights[0])
+		}
+		req.CorrectAnswer = correct[0]
+		return req, nil
+	}
+
+	// Several correct answers: a Moodle multiple-answer question, which must
+	// split the credit evenly to map onto partial scoring
+	if !weighted {
+		return req, fmt.Errorf("multiple \"=\" answers are not supported")
+	}
+	total := 0.0
+	for _, weight := range weights {
+		if math.Abs(weight-weights[0]) > 0.01 {
+			return req, fmt.Errorf("uneven answer weights are not supported")
+		}
+		total += weight
+	}
+	if math.Abs(total-100) > 0.1 {
+		return req, fmt.Errorf("answer weights must add up to 100%%, got %g%%", total)
+	}
+	req.Type = models.QuestionTypeMultiple
+	req.CorrectAnswers = correct
+	req.Scoring = models.ScoringPartial
+
+	return req, nil
+}
+
+// parseGIFTNumeric reads a numeric answer block, either "value:tolerance" or
+// "min..max", followed by optional "####" general feedback. Several weighted
+// numeric answers are not supported.
+func parseGIFTNumeric(req models.QuestionRequest, answers string) (models.QuestionRequest, error) {
+	if i := indexUnescaped(answers, "####"); i >= 0 {
+		req.Explanation = giftUnescape(strings.TrimSpace(answers[i+4:]))
+		answers = answers[:i]
+	}
+	answers = strings.TrimSpace(answers)
+	if strings.HasPrefix(answers, "=") {
+		answers = strings.TrimSpace(answers[1:])
+		if strings.ContainsAny(answers, "=~%") {
+			return req, fmt.Errorf("numeric questions with several answers are not supported")
+		}
+	}
+	if i := indexUnescaped(answers, "#"); i >= 0 {
+		return req, fmt.Errorf("feedback on numeric answers is not supported")
+	}
+
+	key := models.NumericKey{ToleranceMode: models.ToleranceAbsolute}
+	var err error
+	if low, high, ok := strings.Cut(answers, ".."); ok {
+		var min, max float64
+		if min, err = strconv.ParseFloat(strings.TrimSpace(low), 64); err == nil {
+			max, err = strconv.ParseFloat(strings.TrimSpace(high), 64)
+		}
+		key.Value, key.Tolerance = (min+max)/2, math.Abs(max-min)/2
+	} else {
+		value, tolerance, hasTolerance := strings.Cut(answers, ":")
+		key.Value, err = strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err == nil && hasTolerance {
+			key.Tolerance, err = strconv.ParseFloat(strings.TrimSpace(tolerance), 64)
+		}
+	}
+	if err != nil {
+		return req, fmt.Errorf("invalid numeric answer %q", answers)
+	}
+
+	req.Type = models.QuestionTypeNumeric
+	req.AnswerKey = mustMarshal(key)
+	return req, nil
+}
+
+// giftTrueFalse builds a true_false question from a {T} or {F} answer block
+func giftTrueFalse(req models.QuestionRequest, answer bool) (models.QuestionRequest, error) {
+	req.Type = models.QuestionTypeTrueFalse
+	req.AnswerKey = mustMarshal(models.TrueFalseKey{Answer: answer})
+	return req, nil
+}
+
+// giftShortAnswer turns a block of "=" answers into a fill_blank question
+// whose accepted answers are the options parsed so far
+func giftShortAnswer(req models.QuestionRequest, weights []float64) (models.QuestionRequest, error) {
+	for _, weight := range weights {
+		if weight != 100 {
+			return req, fmt.Errorf("partial credit weight %g%% is not supported", weight)
+		}
+	}
+	req.Type = models.QuestionTypeFillBlank
+	req.AnswerKey = mustMarshal(models.FillBlankKey{Accepted: req.Options, Match: models.MatchNormalized})
+	req.Options = nil
+	return req, nil
+}
+
+// parseGIFTMatching reads "=prompt -> target" pairs. Prompts become the
+// options and identical targets are shared between prompts.
+func parseGIFTMatching(req models.QuestionRequest, answers string) (models.QuestionRequest, error) {
+	var key models.MatchingKey
+	targets := make(map[string]int)
+	for _, answer := range splitGIFTAnswers(answers) {
+		if answer[0] != '=' {
+			return req, fmt.Errorf("matching pairs must start with \"=\"")
+		}
+		body := answer[1:]
+		arrow := indexUnescaped(body, "->")
+		if arrow < 0 {
+			return req, fmt.Errorf("matching pair %q has no \"->\"", strings.TrimSpace(body))
+		}
+		prompt, target := giftUnescape(body[:arrow]), giftUnescape(body[arrow+2:])
+		if prompt == "" {
+			return req, fmt.Errorf("matching distractors without a prompt are not supported")
+		}
+		index, ok := targets[target]
+		if !ok {
+			index = len(key.Targets)
+			targets[target] = index
+			key.Targets = append(key.Targets, target)
+		}
+		req.Options = append(req.Options, prompt)
+		key.Pairs = append(key.Pairs, index)
+	}
+
+	req.Type = models.QuestionTypeMatching
+	req.Scoring = models.ScoringPartial
+	req.AnswerKey = mustMarshal(key)
+	return req, nil
+}
+
+// splitGIFTAnswers splits an answer block on unescaped "=" and "~" markers,
+// keeping the marker as the first byte of each answer
+func splitGIFTAnswers(block string) []string {
+	var (
+		answers []string
+		start   = -1
+	)
+	for i := 0; i < len(block); i++ {
+		switch block[i] {
+		case '\\':
+			i++
+		case '=', '~':
+			// "->" belongs to matching syntax and is rejected later
+			if block[i] == '=' && i > 0 && block[i-1] == '-' {
+				continue
+			}
+			if start >= 0 {
+				answers = append(answers, block[start:i])
+			}
+			start = i
+		}
+	}
+	if start >= 0 {
+		answers = append(answers, block[start:])
+	}
+	return answers
+}
+
+// stripGIFTMarkup removes a leading [html], [markdown] or [plain] marker
+func stripGIFTMarkup(text string) string {
+	text = strings.TrimSpace(text)
+	for _, marker := range []string{"[html]", "[markdown]", "[plain]", "[moodle]"} {
+		if strings.HasPrefix(text, marker) {
+			return strings.TrimSpace(text[len(marker):])
+		}
+	}
+	return text
+}
+
+// indexUnescaped finds sep in s, skipping backslash-escaped characters
+func indexUnescaped(s, sep string) int {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.HasPrefix(s[i:], sep) {
+			return i
+		}
+	}
+	return -1
+}
+
+var (
+	giftEscaper   = strings.NewReplacer(`\`, `\\`, `~`, `\~`, `=`, `\=`, `#`, `\#`, `{`, `\{`, `}`, `\}`, `:`, `\:`, "\n", `\n`)
+	giftUnescaper = strings.NewReplacer(`\\`, `\`, `\~`, `~`, `\=`, `=`, `\#`, `#`, `\{`, `{`, `\}`, `}`, `\:`, `:`, `\n`, "\n")
+)
+
+func giftUnescape(s string) string {
+	return strings.TrimSpace(giftUnescaper.Replace(strings.TrimSpace(s)))
+}
+
+// encodeGIFT writes questions as GIFT multiple choice items, grouped under
+// $CATEGORY: directives
+func encodeGIFT(w io.Writer, questions []models.QuestionRequest) ([]string, error) {
+	bw := bufio.NewWriter(w)
+	category := ""
+	var warnings []string
+
+	for i, q := range questions {
+		if i == 0 || q.Category != category {
+			category = q.Category
+			fmt.Fprintf(bw, "$CATEGORY: %s\n\n", category)
+		}
+		if len(q.Variants) > 0 {
+			warnings = append(warnings, fmt.Sprintf("question %d: skipped, GIFT has no question templates", i+1))
+			continue
+		}
+
+		var body strings.Builder
+		switch q.Type {
+		case models.QuestionTypeMultiple:
+			if q.Scoring != models.ScoringPartial {
+				warnings = append(warnings, fmt.Sprintf("question %d: exact scoring is exported as Moodle partial credit", i+1))
+			}
+			writeGIFTMultiple(&body, q)
+		case models.QuestionTypeTrueFalse:
+			var key models.TrueFalseKey
+			json.Unmarshal(q.AnswerKey, &key)
+			fmt.Fprintf(&body, "\t%s\n", strings.ToUpper(strconv.FormatBool(key.Answer)))
+		case models.QuestionTypeFillBlank:
+			var key models.FillBlankKey
+			json.Unmarshal(q.AnswerKey, &key)
+			if key.Match == models.MatchRegex {
+				warnings = append(warnings, fmt.Sprintf("question %d: skipped, GIFT has no regular expression answers", i+1))
+				continue
+			}
+			if key.Match == models.MatchExact || key.CaseSensitive {
+				warnings = append(warnings, fmt.Sprintf("question %d: %s matching is exported as Moodle's case-insensitive short answer", i+1, key.Match))
+			}
+			for _, accepted := range key.Accepted {
+				fmt.Fprintf(&body, "\t=%s\n", giftEscaper.Replace(accepted))
+			}
+		case models.QuestionTypeMatching:
+			if q.Scoring != models.ScoringPartial {
+				warnings = append(warnings, fmt.Sprintf("question %d: exact scoring is exported as Moodle partial credit", i+1))
+			}
+			if !writeGIFTMatching(&body, q) {
+				warnings = append(warnings, fmt.Sprintf("question %d: unmatched targets are not exported", i+1))
+			}
+		case models.QuestionTypeNumeric:
+			var key models.NumericKey
+			json.Unmarshal(q.AnswerKey, &key)
+			if key.Unit != "" {
+				warnings = append(warnings, fmt.Sprintf("question %d: unit %q is not exported, GIFT has no units", i+1, key.Unit))
+			}
+			fmt.Fprintf(&body, "\t#%s:%s\n", formatNumber(key.Value), formatNumber(key.Allowance()))
+		case models.QuestionTypeOrdering, models.QuestionTypeSQL:
+			warnings = append(warnings, fmt.Sprintf("question %d: skipped, GIFT has no %s questions", i+1, q.Type))
+			continue
+		default:
+			for j, option := range q.Options {
+				marker := "~"
+				if j == q.CorrectAnswer {
+					marker = "="
+				}
+				fmt.Fprintf(&body, "\t%s%s%s\n", marker, giftEscaper.Replace(option), giftFeedback(q.Rationales, j))
+			}
+		}
+
+		if q.Difficulty != "" {
+			fmt.Fprintf(bw, "%s %s\n", giftDifficulty, q.Difficulty)
+		}
+		if len(q.Tags) > 0 {
+			fmt.Fprintf(bw, "%s %s\n", giftTags, strings.Join(q.Tags, ", "))
+		}
+		if q.Topic != "" {
+			fmt.Fprintf(bw, "%s %s\n", giftTopic, q.Topic)
+		}
+		for _, ref := range q.References {
+			fmt.Fprintf(bw, "%s %s %s\n", giftReference, ref.URL, ref.Title)
+		}
+		fmt.Fprintf(bw, "%s {\n%s", giftEscaper.Replace(q.Question), body.String())
+		if q.Explanation != "" {
+			fmt.Fprintf(bw, "\t####%s\n", giftEscaper.Replace(q.Explanation))
+		}
+		fmt.Fprint(bw, "}\n\n")
+	}
+
+	return warnings, bw.Flush()
+}
+
+// writeGIFTMultiple writes a multiple-answer question: the correct answers
+// share 100% of the credit and each wrong answer cancels it
+func writeGIFTMultiple(w io.Writer, q models.QuestionRequest) {
+	correct := make(map[int]bool, len(q.CorrectAnswers))
+	for _, index := range q.CorrectAnswers {
+		correct[index] = true
+	}
+	weight := formatWeight(100 / float64(len(q.CorrectAnswers)))
+
+	for j, option := range q.Options {
+		if correct[j] {
+			fmt.Fprintf(w, "\t~%%%s%%%s%s\n", weight, giftEscaper.Replace(option), giftFeedback(q.Rationales, j))
+		} else {
+			fmt.Fprintf(w, "\t~%%-100%%%s%s\n", giftEscaper.Replace(option), giftFeedback(q.Rationales, j))
+		}
+	}
+}
+
+// giftFeedback returns the "#feedback" suffix carrying an option's rationale
+func giftFeedback(rationales []string, index int) string {
+	if rationale := models.RationaleAt(rationales, index); rationale != "" {
+		return " #" + giftEscaper.Replace(rationale)
+	}
+	return ""
+}
+
+// writeGIFTMatching writes "=prompt -> target" pairs and reports whether
+// every target was used, since GIFT cannot carry distractor targets
+func writeGIFTMatching(w io.Writer, q models.QuestionRequest) bool {
+	var key models.MatchingKey
+	json.Unmarshal(q.AnswerKey, &key)
+	used := make(map[int]bool, len(key.Pairs))
+	for j, prompt := range q.Options {
+		target := key.Pairs[j]
+		used[target] = true
+		fmt.Fprintf(w, "\t=%s -> %s\n", giftEscaper.Replace(prompt), giftEscaper.Replace(key.Targets[target]))
+	}
+	return len(used) == len(key.Targets)
+}
+
+// formatNumber formats a numeric answer or tolerance as briefly as possible
+func formatNumber(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// formatWeight formats a percentage with up to five decimals, as Moodle does
+func formatWeight(weight float64) string {
+	return strconv.FormatFloat(math.Round(weight*1e5)/1e5, 'f', -1, 64)
+}
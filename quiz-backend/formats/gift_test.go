@@ -0,0 +1,106 @@
+package formats
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"aws-rds-quiz-backend/models"
+)
+
+func TestGIFTRoundTrip(t *testing.T) {
+	// Moodle grades multiple answers and matching pairs with partial credit
+	partial := func(body string) string {
+		return strings.Replace(body, `"category"`, `"scoring": "partial", "category"`, 1)
+	}
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"single choice", singleChoice},
+		{"multiple choice", partial(multipleChoice)},
+		{"true false", trueFalse},
+		{"matching", partial(matching)},
+		{"fill blank", fillBlank},
+		{"numeric", numeric},
+		{"escaped text", `{"question": "Which of {a} = {b} is valid?", "options": ["a ~ b", "a = b", "a # b", "a: b"], "correctAnswer": 1}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roundTrip(t, FormatGIFT, tt.body)
+		})
+	}
+}
+
+func TestGIFTExportWarnings(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		warning string
+	}{
+		{"ordering", ordering, "skipped, GIFT has no ordering questions"},
+		{"sql", sqlExercise, "skipped, GIFT has no sql questions"},
+		{"exact multiple choice", multipleChoice, "exact scoring is exported as Moodle partial credit"},
+		{"numeric unit", `{"question": "Max retention?", "type": "numeric", "answerKey": {"value": 35, "unit": "days"}}`, `unit "days" is not exported`},
+		{"regex", `{"question": "Engine?", "type": "fill_blank", "answerKey": {"accepted": ["mysql|postgres"], "match": "regex"}}`, "no regular expression answers"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			warnings, err := Encode(FormatGIFT, &buf, []models.QuestionRequest{request(t, tt.body)}, nil)
+			if err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			if len(warnings) != 1 || !strings.Contains(warnings[0], tt.warning) {
+				t.Errorf("Encode() warnings = %q, want one containing %q", warnings, tt.warning)
+			}
+		})
+	}
+}
+
+func TestGIFTDecode(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		check func(t *testing.T, row Row)
+	}{
+		{"category and comments", "$CATEGORY: $course$/Aurora\n\n// difficulty: hard\n// tags: ha, failover\nFailover is automatic. {TRUE}\n", func(t *testing.T, row Row) {
+			q := row.Question
+			q.Normalize()
+			if q.Category != "Aurora" || q.Difficulty != "hard" || strings.Join(q.Tags, ",") != "failover,ha" {
+				t.Errorf("question = %+v, want the Aurora category, hard difficulty and two tags", q)
+			}
+		}},
+		{"essay rejected", "Describe Multi-AZ. {}\n", func(t *testing.T, row Row) {
+			if row.Err == nil {
+				t.Error("row error = nil, want an error for an essay question")
+			}
+		}},
+		{"uneven weights rejected", "Pick {~%70%a ~%30%b ~%-100%c}\n", func(t *testing.T, row Row) {
+			if row.Err == nil {
+				t.Error("row error = nil, want an error for uneven weights")
+			}
+		}},
+		{"numeric range", "Max replicas? {#15:0}\n", func(t *testing.T, row Row) {
+			if row.Err != nil || row.Question.Type != models.QuestionTypeNumeric {
+				t.Errorf("row = %+v, want a numeric question", row)
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := Decode(FormatGIFT, strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if len(rows) != 1 {
+				t.Fatalf("Decode() returned %d rows, want 1", len(rows))
+			}
+			tt.check(t, rows[0])
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package formats
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"aws-rds-quiz-backend/models"
+)
+
+// decodeJSON reads a JSON array of question objects
+func decodeJSON(r io.Reader) ([]Row, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return nil, fmt.Errorf("invalid JSON: expected an array of questions")
+	}
+
+	var rows []Row
+	for decoder.More() {
+		line := lineAt(data, skipSpace(data, decoder.InputOffset()))
+
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return nil, fmt.Errorf("invalid JSON at line %d: %v", line, err)
+		}
+
+		req, err := decodeQuestion(raw)
+		rows = append(rows, Row{Line: line, Question: req, Err: err})
+	}
+
+	if _, err := decoder.Token(); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+	return rows, nil
+}
+
+// skipSpace advances offset past whitespace and the separating comma
+func skipSpace(data []byte, offset int64) int64 {
+	for offset < int64(len(data)) {
+		switch data[offset] {
+		case ' ', '\t', '\r', '\n', ',':
+			offset++
+		default:
+			return offset
+		}
+	}
+	return offset
+}
+
+// lineAt returns the 1-based line number of the byte at offset
+func lineAt(data []byte, offset int64) int {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
+
+// encodeJSON writes questions as an indented JSON array
+func encodeJSON(w io.Writer, questions []models.QuestionRequest) ([]string, error) {
+	if questions == nil {
+		questions = []models.QuestionRequest{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return nil, encoder.Encode(questions)
+}
@@ -0,0 +1,99 @@
+package formats
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"aws-rds-quiz-backend/models"
+
+	"gopkg.in/yaml.v3"
+)
+
+// explanationHeading separates the question text from its explanation in a
+// Markdown question file
+var explanationHeading = regexp.MustCompile(`(?i)^#{1,6}\s+explanation\s*$`)
+
+// MarkdownQuestion is a question read from a Markdown file
+type MarkdownQuestion struct {
+	ID       string // optional "id" from the front matter
+	Question models.QuestionRequest
+}
+
+// DecodeMarkdown reads a single question from a Markdown file. The file starts
+// with YAML front matter between "---" lines holding the options,
+// correctAnswer, category and difficulty (plus an optional stable id). The
+// body is the question text, followed by an optional "## Explanation"
+// section.
+func DecodeMarkdown(r io.Reader) (MarkdownQuestion, error) {
+	var (
+		result      MarkdownQuestion
+		frontMatter []string
+		body        []string
+		explanation []string
+		section     = "start"
+	)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch section {
+		case "start":
+			if strings.TrimSpace(strings.TrimPrefix(line, "\ufeff")) != "---" {
+				return result, fmt.Errorf("line 1: file must start with \"---\" front matter")
+			}
+			section = "front"
+		case "front":
+			if strings.TrimSpace(line) == "---" {
+				section = "body"
+				continue
+			}
+			frontMatter = append(frontMatter, line)
+		case "body":
+			if explanationHeading.MatchString(strings.TrimSpace(line)) {
+				section = "explanation"
+				continue
+			}
+			body = append(body, line)
+		case "explanation":
+			explanation = append(explanation, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return result, err
+	}
+	if section == "start" || section == "front" {
+		return result, fmt.Errorf("unterminated front matter")
+	}
+
+	var fields map[string]interface{}
+	if err := yaml.Unmarshal([]byte(strings.Join(frontMatter, "\n")), &fields); err != nil {
+		return result, fmt.Errorf("invalid front matter: %v", err)
+	}
+	if id, ok := fields["id"]; ok {
+		result.ID = strings.TrimSpace(fmt.Sprint(id))
+		delete(fields, "id")
+	}
+	for _, key := range []string{"question", "explanation"} {
+		if _, ok := fields[key]; ok {
+			return result, fmt.Errorf("%q belongs in the Markdown body, not the front matter", key)
+		}
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return result, fmt.Errorf("invalid front matter: %v", err)
+	}
+	result.Question, err = decodeQuestion(data)
+	if err != nil {
+		return result, fmt.Errorf("invalid front matter: %v", err)
+	}
+
+	result.Question.Question = strings.TrimSpace(strings.Join(body, "\n"))
+	result.Question.Explanation = strings.TrimSpace(strings.Join(explanation, "\n"))
+	return result, nil
+}
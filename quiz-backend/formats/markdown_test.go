@@ -0,0 +1,70 @@
+package formats
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecodeMarkdown(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		id          string
+		question    string
+		explanation string
+		wantErr     string
+	}{
+		{
+			name:        "full file",
+			input:       "---\nid: mysql-port\noptions: [\"3306\", \"5432\"]\ncorrectAnswer: 0\ncategory: RDS\n---\nWhat is the default\nMySQL port?\n\n## Explanation\n\nMySQL listens on 3306.\n",
+			id:          "mysql-port",
+			question:    "What is the default\nMySQL port?",
+			explanation: "MySQL listens on 3306.",
+		},
+		{
+			name:     "byte order mark and numeric id",
+			input:    "\ufeff---\nid: 42\noptions: [a, b]\n---\nPick a.\n",
+			id:       "42",
+			question: "Pick a.",
+		},
+		{
+			name:    "no front matter",
+			input:   "What is the default MySQL port?\n",
+			wantErr: "must start with",
+		},
+		{
+			name:    "unterminated front matter",
+			input:   "---\noptions: [a, b]\n",
+			wantErr: "unterminated front matter",
+		},
+		{
+			name:    "question in front matter",
+			input:   "---\nquestion: Port?\noptions: [a, b]\n---\n",
+			wantErr: "belongs in the Markdown body",
+		},
+		{
+			name:    "invalid yaml",
+			input:   "---\noptions: [a, b\n---\nPort?\n",
+			wantErr: "invalid front matter",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeMarkdown(strings.NewReader(tt.input))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("DecodeMarkdown() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DecodeMarkdown() error = %v", err)
+			}
+			if got.ID != tt.id || got.Question.Question != tt.question || got.Question.Explanation != tt.explanation {
+				t.Errorf("DecodeMarkdown() = id %q, question %q, explanation %q; want %q, %q, %q",
+					got.ID, got.Question.Question, got.Question.Explanation, tt.id, tt.question, tt.explanation)
+			}
+		})
+	}
+}
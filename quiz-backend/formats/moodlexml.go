@@ -0,0 +1,701 @@
+package formats
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"aws-rds-quiz-backend/models"
+)
+
+// FormatMoodleXML is the Moodle XML question bank format
+const FormatMoodleXML = "moodlexml"
+
+// Tag prefixes used to carry a question's difficulty and topic, which Moodle
+// has no fields for. Other tags map onto question tags.
+const (
+	moodleDifficultyTag = "difficulty:"
+	moodleTopicTag      = "topic:"
+)
+
+type moodleQuiz struct {
+	XMLName   xml.Name         `xml:"quiz"`
+	Questions []moodleQuestion `xml:"question"`
+}
+
+type moodleQuestion struct {
+	Type            string              `xml:"type,attr"`
+	Category        *moodleText         `xml:"category,omitempty"`
+	Name            *moodleText         `xml:"name,omitempty"`
+	QuestionText    *moodleText         `xml:"questiontext,omitempty"`
+	GeneralFeedback *moodleText         `xml:"generalfeedback,omitempty"`
+	DefaultGrade    string              `xml:"defaultgrade,omitempty"`
+	Single          string              `xml:"single,omitempty"`
+	ShuffleAnswers  string              `xml:"shuffleanswers,omitempty"`
+	AnswerNumbering string              `xml:"answernumbering,omitempty"`
+	UseCase         string              `xml:"usecase,omitempty"`
+	Answers         []moodleAnswer      `xml:"answer"`
+	Subquestions    []moodleSubquestion `xml:"subquestion"`
+	Units           *moodleUnits        `xml:"units,omitempty"`
+	Tags            *moodleTags         `xml:"tags,omitempty"`
+}
+
+// moodleSubquestion is one prompt of a matching question; a subquestion
+// without text only contributes a distractor answer
+type moodleSubquestion struct {
+	Format string     `xml:"format,attr,omitempty"`
+	Text   string     `xml:"text"`
+	Answer moodleText `xml:"answer"`
+}
+
+type moodleUnits struct {
+	Units []moodleUnit `xml:"unit"`
+}
+
+type moodleUnit struct {
+	Multiplier string `xml:"multiplier"`
+	Name       string `xml:"unit_name"`
+}
+
+type moodleTags struct {
+	Tags []moodleText `xml:"tag"`
+}
+
+type moodleText struct {
+	Format string       `xml:"format,attr,omitempty"`
+	Text   string       `xml:"text"`
+	Files  []moodleFile `xml:"file"`
+}
+
+type moodleAnswer struct {
+	Fraction  string       `xml:"fraction,attr"`
+	Format    string       `xml:"format,attr,omitempty"`
+	Text      string       `xml:"text"`
+	Files     []moodleFile `xml:"file"`
+	Tolerance string       `xml:"tolerance,omitempty"`
+	Feedback  *moodleText  `xml:"feedback,omitempty"`
+}
+
+// moodleFile is an image embedded in a text element, which the HTML text
+// refers to as @@PLUGINFILE@@/name
+type moodleFile struct {
+	Name     string `xml:"name,attr"`
+	Path     string `xml:"path,attr"`
+	Encoding string `xml:"encoding,attr"`
+	Data     string `xml:",chardata"`
+}
+
+// moodlePluginFile prefixes the sources of images embedded as files
+const moodlePluginFile = "@@PLUGINFILE@@/"
+
+// decodeMoodleXML reads multichoice, truefalse, shortanswer, matching and
+// numerical questions from a Moodle XML export.
+// Category pseudo-questions set the category of the questions after them.
+// Answer feedback on multichoice questions becomes the option rationales.
+func decodeMoodleXML(r io.Reader) ([]Row, error) {
+	decoder := xml.NewDecoder(r)
+	category := ""
+
+	var rows []Row
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid Moodle XML: %v", err)
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "question" {
+			continue
+		}
+		line, _ := decoder.InputPos()
+
+		var q moodleQuestion
+		if err := decoder.DecodeElement(&q, &start); err != nil {
+			return nil, fmt.Errorf("invalid Moodle XML at line %d: %v", line, err)
+		}
+
+		if q.Type == "category" {
+			if q.Category != nil {
+				category = giftCategory(q.Category.Text)
+			}
+			continue
+		}
+
+		row := Row{Line: line}
+		row.Files, row.Err = moodleImages(&q)
+		if row.Err == nil {
+			row.Question, row.Warnings, row.Err = moodleToRequest(q, category)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func moodleToRequest(q moodleQuestion, category string) (models.QuestionRequest, []string, error) {
+	var warnings []string
+	req := models.QuestionRequest{Category: category}
+
+	text := func(field string, t *moodleText) string {
+		if t == nil {
+			return ""
+		}
+		value, lossy := moodleTextValue(t.Format, t.Text)
+		if lossy {
+			warnings = append(warnings, fmt.Sprintf("HTML markup removed from %s", field))
+		}
+		return value
+	}
+
+	req.Question = text("question text", q.QuestionText)
+	req.Explanation = text("general feedback", q.GeneralFeedback)
+
+	var (
+		typeWarnings []string
+		err          error
+	)
+	switch q.Type {
+	case "multichoice":
+		typeWarnings, err = moodleMultichoice(q, &req)
+	case "truefalse":
+		typeWarnings, err = moodleTrueFalse(q, &req)
+	case "shortanswer":
+		typeWarnings, err = moodleShortAnswer(q, &req)
+	case "matching":
+		typeWarnings, err = moodleMatching(q, &req)
+	case "numerical":
+		typeWarnings, err = moodleNumerical(q, &req)
+	default:
+		err = fmt.Errorf("question type %q is not supported", q.Type)
+	}
+	warnings = append(warnings, typeWarnings...)
+	if err != nil {
+		return req, warnings, err
+	}
+
+	if q.Tags == nil {
+		return req, warnings, nil
+	}
+	for _, tag := range q.Tags.Tags {
+		switch {
+		case strings.HasPrefix(tag.Text, moodleDifficultyTag):
+			req.Difficulty = strings.TrimPrefix(tag.Text, moodleDifficultyTag)
+		case strings.HasPrefix(tag.Text, moodleTopicTag):
+			req.Topic = strings.TrimPrefix(tag.Text, moodleTopicTag)
+		default:
+			req.Tags = append(req.Tags, tag.Text)
+		}
+	}
+
+	return req, warnings, nil
+}
+
+// moodleImages replaces the embedded images in the question text and answers
+// with attachment references and returns their files
+func moodleImages(q *moodleQuestion) ([]File, error) {
+	embedded := map[string]File{}
+	collect := func(files []moodleFile) error {
+		for _, mf := range files {
+			data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(mf.Data), ""))
+			if err != nil {
+				return fmt.Errorf("embedded file %q is not valid base64: %v", mf.Name, err)
+			}
+			embedded[mf.Name] = newFile(mf.Name, "", data)
+		}
+		return nil
+	}
+	if q.QuestionText != nil {
+		if err := collect(q.QuestionText.Files); err != nil {
+			return nil, err
+		}
+	}
+	for _, answer := range q.Answers {
+		if err := collect(answer.Files); err != nil {
+			return nil, err
+		}
+	}
+	if len(embedded) == 0 {
+		return nil, nil
+	}
+
+	resolve := func(src string) (File, bool) {
+		name, err := url.PathUnescape(strings.TrimPrefix(src, moodlePluginFile))
+		if err != nil || !strings.HasPrefix(src, moodlePluginFile) {
+			return File{}, false
+		}
+		f, ok := embedded[name]
+		return f, ok
+	}
+	var files []File
+	if q.QuestionText != nil && moodleIsHTML(q.QuestionText.Format, q.QuestionText.Text) {
+		q.QuestionText.Text = imagesToRefs(q.QuestionText.Text, resolve, &files)
+	}
+	for i := range q.Answers {
+		if moodleIsHTML(q.Answers[i].Format, q.Answers[i].Text) {
+			q.Answers[i].Text = imagesToRefs(q.Answers[i].Text, resolve, &files)
+		}
+	}
+	return files, nil
+}
+
+// moodleMultichoice reads the answers of a multichoice question
+func moodleMultichoice(q moodleQuestion, req *models.QuestionRequest) ([]string, error) {
+	var warnings []string
+	single := q.Single != "false" && q.Single != "0"
+
+	var (
+		correct   []int
+		feedbacks []string
+	)
+	for i, answer := range q.Answers {
+		option, lossy := moodleTextValue(answer.Format, answer.Text)
+		if lossy {
+			warnings = append(warnings, fmt.Sprintf("HTML markup removed from answer %d", i+1))
+		}
+		req.Options = append(req.Options, option)
+
+		fraction, err := strconv.ParseFloat(answer.Fraction, 64)
+		if err != nil && answer.Fraction != "" {
+			return warnings, fmt.Errorf("answer %d has invalid fraction %q", i+1, answer.Fraction)
+		}
+		switch {
+		case single && fraction == 100:
+			if len(correct) > 0 {
+				return warnings, fmt.Errorf("more than one answer has fraction 100")
+			}
+			correct = append(correct, i)
+		case !single && fraction > 0:
+			correct = append(correct, i)
+		case single && fraction != 0:
+			return warnings, fmt.Errorf("partial credit fraction %s on answer %d is not supported", answer.Fraction, i+1)
+		}
+
+		feedback := ""
+		if answer.Feedback != nil {
+			var lossy bool
+			feedback, lossy = moodleTextValue(answer.Feedback.Format, answer.Feedback.Text)
+			if lossy {
+				warnings = append(warnings, fmt.Sprintf("HTML markup removed from feedback on answer %d", i+1))
+			}
+		}
+		feedbacks = append(feedbacks, feedback)
+	}
+	if len(correct) == 0 {
+		return warnings, fmt.Errorf("question has no correct answer")
+	}
+	req.Rationales = answerRationales(req, feedbacks, correct[0])
+
+	if single {
+		req.CorrectAnswer = correct[0]
+	} else {
+		// Multiple response questions must split the credit evenly across the
+		// correct answers to map onto partial scoring
+		share := 100 / float64(len(correct))
+		for _, index := range correct {
+			fraction, _ := strconv.ParseFloat(q.Answers[index].Fraction, 64)
+			if math.Abs(fraction-share) > 0.01 {
+				return warnings, fmt.Errorf("uneven answer fractions are not supported")
+			}
+		}
+		req.Type = models.QuestionTypeMultiple
+		req.CorrectAnswers = correct
+		req.Scoring = models.ScoringPartial
+	}
+	return warnings, nil
+}
+
+// moodleTrueFalse reads a truefalse question, whose answers are "true" and
+// "false" with the correct one at fraction 100
+func moodleTrueFalse(q moodleQuestion, req *models.QuestionRequest) ([]string, error) {
+	var warnings []string
+	found := false
+	var key models.TrueFalseKey
+	for i, answer := range q.Answers {
+		if moodleFraction(answer) != 100 {
+			continue
+		}
+		value, err := strconv.ParseBool(strings.TrimSpace(answer.Text))
+		if err != nil || found {
+			return warnings, fmt.Errorf("answer %d of a true/false question is invalid", i+1)
+		}
+		key.Answer, found = value, true
+		warnings = append(warnings, moodleDroppedFeedback(answer, i)...)
+	}
+	if !found {
+		return warnings, fmt.Errorf("question has no correct answer")
+	}
+	req.Type = models.QuestionTypeTrueFalse
+	req.AnswerKey = mustMarshal(key)
+	return warnings, nil
+}
+
+// moodleShortAnswer reads a shortanswer question; every answer at fraction
+// 100 is accepted. Moodle's "*" wildcard has no counterpart and is rejected.
+func moodleShortAnswer(q moodleQuestion, req *models.QuestionRequest) ([]string, error) {
+	var warnings []string
+	key := models.FillBlankKey{Match: models.MatchNormalized, CaseSensitive: q.UseCase == "1"}
+	for i, answer := range q.Answers {
+		fraction := moodleFraction(answer)
+		switch {
+		case fraction == 0:
+			continue
+		case fraction != 100:
+			return warnings, fmt.Errorf("partial credit fraction %s on answer %d is not supported", answer.Fraction, i+1)
+		case strings.Contains(answer.Text, "*"):
+			return warnings, fmt.Errorf("wildcard answer %q is not supported", answer.Text)
+		}
+		key.Accepted = append(key.Accepted, strings.TrimSpace(answer.Text))
+		warnings = append(warnings, moodleDroppedFeedback(answer, i)...)
+	}
+	if len(key.Accepted) == 0 {
+		return warnings, fmt.Errorf("question has no correct answer")
+	}
+	req.Type = models.QuestionTypeFillBlank
+	req.AnswerKey = mustMarshal(key)
+	return warnings, nil
+}
+
+// moodleMatching reads a matching question. Subquestions become the options
+// and their answers the targets; Moodle grades each pair, so the question
+// uses partial scoring.
+func moodleMatching(q moodleQuestion, req *models.QuestionRequest) ([]string, error) {
+	var warnings []string
+	var key models.MatchingKey
+	targets := make(map[string]int)
+	for i, sub := range q.Subquestions {
+		target := strings.TrimSpace(sub.Answer.Text)
+		index, ok := targets[target]
+		if !ok {
+			index = len(key.Targets)
+			targets[target] = index
+			key.Targets = append(key.Targets, target)
+		}
+
+		prompt, lossy := moodleTextValue(sub.Format, sub.Text)
+		if lossy {
+			warnings = append(warnings, fmt.Sprintf("HTML markup removed from subquestion %d", i+1))
+		}
+		if prompt == "" {
+			continue
+		}
+		req.Options = append(req.Options, prompt)
+		key.Pairs = append(key.Pairs, index)
+	}
+	req.Type = models.QuestionTypeMatching
+	req.Scoring = models.ScoringPartial
+	req.AnswerKey = mustMarshal(key)
+	return warnings, nil
+}
+
+// moodleNumerical reads a numerical question with a single fully correct
+// answer. The unit with multiplier 1 becomes the key's unit; other units are
+// dropped since known units convert automatically.
+func moodleNumerical(q moodleQuestion, req *models.QuestionRequest) ([]string, error) {
+	var warnings []string
+	key := models.NumericKey{ToleranceMode: models.ToleranceAbsolute}
+	found := false
+	for i, answer := range q.Answers {
+		fraction := moodleFraction(answer)
+		switch {
+		case fraction == 0:
+			continue
+		case fraction != 100:
+			return warnings, fmt.Errorf("partial credit fraction %s on answer %d is not supported", answer.Fraction, i+1)
+		case found:
+			return warnings, fmt.Errorf("numerical questions with several correct answers are not supported")
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(answer.Text), 64)
+		if err != nil {
+			return warnings, fmt.Errorf("answer %d is not a number: %q", i+1, answer.Text)
+		}
+		if answer.Tolerance != "" {
+			if key.Tolerance, err = strconv.ParseFloat(strings.TrimSpace(answer.Tolerance), 64); err != nil {
+				return warnings, fmt.Errorf("answer %d has invalid tolerance %q", i+1, answer.Tolerance)
+			}
+		}
+		key.Value, found = value, true
+		warnings = append(warnings, moodleDroppedFeedback(answer, i)...)
+	}
+	if !found {
+		return warnings, fmt.Errorf("question has no correct answer")
+	}
+
+	if q.Units != nil {
+		for _, u := range q.Units.Units {
+			multiplier, _ := strconv.ParseFloat(strings.TrimSpace(u.Multiplier), 64)
+			if multiplier == 1 && key.Unit == "" {
+				key.Unit = strings.TrimSpace(u.Name)
+			} else {
+				warnings = append(warnings, fmt.Sprintf("unit %q was dropped", u.Name))
+			}
+		}
+	}
+
+	req.Type = models.QuestionTypeNumeric
+	req.AnswerKey = mustMarshal(key)
+	return warnings, nil
+}
+
+// moodleFraction parses an answer's fraction, treating a missing one as 0
+func moodleFraction(answer moodleAnswer) float64 {
+	fraction, _ := strconv.ParseFloat(answer.Fraction, 64)
+	return fraction
+}
+
+// moodleDroppedFeedback warns about per-answer feedback, which only
+// multichoice questions can carry over as the explanation
+func moodleDroppedFeedback(answer moodleAnswer, i int) []string {
+	if answer.Feedback != nil && strings.TrimSpace(answer.Feedback.Text) != "" {
+		return []string{fmt.Sprintf("feedback on answer %d was dropped", i+1)}
+	}
+	return nil
+}
+
+// moodleTextValue converts a Moodle text element to plain text
+func moodleTextValue(format, text string) (string, bool) {
+	if moodleIsHTML(format, text) {
+		return htmlToText(text)
+	}
+	return strings.TrimSpace(text), false
+}
+
+func moodleIsHTML(format, text string) bool {
+	return format == "html" || format == "" && strings.Contains(text, "<")
+}
+
+// moodleRichText returns the format, text and embedded files of a text
+// element. Text with attachment references is written as HTML with the images
+// embedded as files; other text stays plain.
+func moodleRichText(i int, text string, set *attachmentSet, warnings *[]string) (string, string, []moodleFile) {
+	if set.load == nil || len(models.FindAttachmentRefs(text)) == 0 {
+		return "plain_text", text, nil
+	}
+	var files []File
+	body := htmlWithImages(i, text, set, moodleHTML, func(f File) string {
+		return moodlePluginFile + url.PathEscape(embeddedName(f))
+	}, &files, warnings)
+
+	embedded := make([]moodleFile, len(files))
+	for j, f := range files {
+		embedded[j] = moodleFile{Name: embeddedName(f), Path: "/", Encoding: "base64", Data: base64.StdEncoding.EncodeToString(f.Data)}
+	}
+	return "html", body, embedded
+}
+
+// moodleHTML escapes plain text for an HTML text element
+func moodleHTML(text string) string {
+	return strings.ReplaceAll(html.EscapeString(text), "\n", "<br />")
+}
+
+// encodeMoodleXML writes questions as Moodle XML questions of the matching
+// Moodle type, preceded by a category pseudo-question whenever the category
+// changes. Images referenced by the question text and choice options are
+// embedded as files. Ordering and sql questions and regular expression
+// answers have no Moodle counterpart and are skipped with a warning.
+func encodeMoodleXML(w io.Writer, questions []models.QuestionRequest, load Loader) ([]string, error) {
+	quiz := moodleQuiz{}
+	category := ""
+	set := newAttachmentSet(load)
+	var warnings []string
+
+	for i, q := range questions {
+		if i == 0 || q.Category != category {
+			category = q.Category
+			quiz.Questions = append(quiz.Questions, moodleQuestion{
+				Type:     "category",
+				Category: &moodleText{Text: "$course$/top/" + category},
+			})
+		}
+		if len(q.Variants) > 0 {
+			warnings = append(warnings, fmt.Sprintf("question %d: skipped, template questions are not exported to Moodle XML", i+1))
+			continue
+		}
+
+		format, text, files := moodleRichText(i, q.Question, set, &warnings)
+		mq := moodleQuestion{
+			Type:            "multichoice",
+			Name:            &moodleText{Text: questionName(q.Question)},
+			QuestionText:    &moodleText{Format: format, Text: text, Files: files},
+			GeneralFeedback: &moodleText{Format: "plain_text", Text: q.Explanation},
+			DefaultGrade:    "1",
+		}
+		switch q.Type {
+		case models.QuestionTypeTrueFalse:
+			var key models.TrueFalseKey
+			json.Unmarshal(q.AnswerKey, &key)
+			mq.Type = "truefalse"
+			mq.Answers = []moodleAnswer{
+				{Fraction: moodleBoolFraction(key.Answer), Format: "moodle_auto_format", Text: "true"},
+				{Fraction: moodleBoolFraction(!key.Answer), Format: "moodle_auto_format", Text: "false"},
+			}
+		case models.QuestionTypeFillBlank:
+			var key models.FillBlankKey
+			json.Unmarshal(q.AnswerKey, &key)
+			if key.Match == models.MatchRegex {
+				warnings = append(warnings, fmt.Sprintf("question %d: skipped, Moodle short answer has no regular expressions", i+1))
+				continue
+			}
+			if key.Match == models.MatchExact {
+				warnings = append(warnings, fmt.Sprintf("question %d: exact matching is exported as Moodle short answer matching", i+1))
+			}
+			mq.Type = "shortanswer"
+			mq.UseCase = "0"
+			if key.CaseSensitive {
+				mq.UseCase = "1"
+			}
+			for _, accepted := range key.Accepted {
+				mq.Answers = append(mq.Answers, moodleAnswer{Fraction: "100", Format: "moodle_auto_format", Text: accepted})
+			}
+		case models.QuestionTypeMatching:
+			if q.Scoring != models.ScoringPartial {
+				warnings = append(warnings, fmt.Sprintf("question %d: exact scoring is exported as Moodle partial credit", i+1))
+			}
+			mq.Type = "matching"
+			mq.ShuffleAnswers = "true"
+			mq.Subquestions = moodleSubquestions(q)
+			if len(q.AttachmentRefs()) > len(models.FindAttachmentRefs(q.Question)) {
+				warnings = append(warnings, fmt.Sprintf("question %d: images in matching prompts are exported as references", i+1))
+			}
+		case models.QuestionTypeNumeric:
+			var key models.NumericKey
+			json.Unmarshal(q.AnswerKey, &key)
+			mq.Type = "numerical"
+			mq.Answers = []moodleAnswer{{
+				Fraction:  "100",
+				Format:    "moodle_auto_format",
+				Text:      formatNumber(key.Value),
+				Tolerance: formatNumber(key.Allowance()),
+			}}
+			if key.Unit != "" {
+				mq.Units = &moodleUnits{Units: []moodleUnit{{Multiplier: "1", Name: key.Unit}}}
+			}
+		case models.QuestionTypeOrdering, models.QuestionTypeSQL:
+			warnings = append(warnings, fmt.Sprintf("question %d: skipped, %s questions are not exported to Moodle XML", i+1, q.Type))
+			continue
+		default:
+			mq.Single = "true"
+			mq.ShuffleAnswers = "true"
+			mq.AnswerNumbering = "abc"
+			fractions := moodleFractions(q)
+			if q.Type == models.QuestionTypeMultiple {
+				mq.Single = "false"
+				if q.Scoring != models.ScoringPartial {
+					warnings = append(warnings, fmt.Sprintf("question %d: exact scoring is exported as Moodle partial credit", i+1))
+				}
+			}
+			for j, option := range q.Options {
+				format, text, files := moodleRichText(i, option, set, &warnings)
+				answer := moodleAnswer{Fraction: fractions[j], Format: format, Text: text, Files: files}
+				if rationale := models.RationaleAt(q.Rationales, j); rationale != "" {
+					answer.Feedback = &moodleText{Format: "plain_text", Text: rationale}
+				}
+				mq.Answers = append(mq.Answers, answer)
+			}
+		}
+		if len(q.References) > 0 {
+			warnings = append(warnings, fmt.Sprintf("question %d: references are not exported, Moodle XML has no field for them", i+1))
+		}
+		var tags []moodleText
+		if q.Difficulty != "" {
+			tags = append(tags, moodleText{Text: moodleDifficultyTag + q.Difficulty})
+		}
+		if q.Topic != "" {
+			tags = append(tags, moodleText{Text: moodleTopicTag + q.Topic})
+		}
+		for _, tag := range q.Tags {
+			tags = append(tags, moodleText{Text: tag})
+		}
+		if len(tags) > 0 {
+			mq.Tags = &moodleTags{Tags: tags}
+		}
+		quiz.Questions = append(quiz.Questions, mq)
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString(xml.Header)
+	encoder := xml.NewEncoder(bw)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(quiz); err != nil {
+		return nil, err
+	}
+	bw.WriteString("\n")
+	return warnings, bw.Flush()
+}
+
+// moodleFractions returns the grade fraction for each option. Multiple
+// response questions split 100% across the correct options and give -100%
+// for each wrong one, so selecting everything earns nothing.
+func moodleFractions(q models.QuestionRequest) []string {
+	fractions := make([]string, len(q.Options))
+	if q.Type != models.QuestionTypeMultiple {
+		for j := range fractions {
+			fractions[j] = "0"
+		}
+		fractions[q.CorrectAnswer] = "100"
+		return fractions
+	}
+
+	for j := range fractions {
+		fractions[j] = "-100"
+	}
+	share := formatWeight(100 / float64(len(q.CorrectAnswers)))
+	for _, index := range q.CorrectAnswers {
+		fractions[index] = share
+	}
+	return fractions
+}
+
+// moodleSubquestions pairs each option with its target; unmatched targets
+// are written as subquestions without text, which Moodle shows as
+// distractors
+func moodleSubquestions(q models.QuestionRequest) []moodleSubquestion {
+	var key models.MatchingKey
+	json.Unmarshal(q.AnswerKey, &key)
+	used := make(map[int]bool, len(key.Pairs))
+	var subquestions []moodleSubquestion
+	for j, prompt := range q.Options {
+		used[key.Pairs[j]] = true
+		subquestions = append(subquestions, moodleSubquestion{
+			Format: "plain_text",
+			Text:   prompt,
+			Answer: moodleText{Text: key.Targets[key.Pairs[j]]},
+		})
+	}
+	for j, target := range key.Targets {
+		if !used[j] {
+			subquestions = append(subquestions, moodleSubquestion{Format: "plain_text", Answer: moodleText{Text: target}})
+		}
+	}
+	return subquestions
+}
+
+func moodleBoolFraction(correct bool) string {
+	if correct {
+		return "100"
+	}
+	return "0"
+}
+
+// questionName derives a short question name from its text
+func questionName(text string) string {
+	const maxLen = 60
+	text = strings.Join(strings.Fields(text), " ")
+	if len(text) <= maxLen {
+		return text
+	}
+	cut := strings.LastIndex(text[:maxLen], " ")
+	if cut <= 0 {
+		cut = maxLen
+	}
+	return text[:cut] + "..."
+}
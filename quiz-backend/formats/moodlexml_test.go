@@ -0,0 +1,59 @@
+package formats
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"aws-rds-quiz-backend/models"
+)
+
+func TestMoodleXMLRoundTrip(t *testing.T) {
+	partial := func(body string) string {
+		return strings.Replace(body, `"category"`, `"scoring": "partial", "category"`, 1)
+	}
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"single choice", singleChoice},
+		{"multiple choice", partial(multipleChoice)},
+		{"true false", trueFalse},
+		{"matching", partial(matching)},
+		{"fill blank", fillBlank},
+		{"numeric", numeric},
+		{"markup characters", `{"question": "Is <b>5 < 6</b> & true?", "options": ["yes & no", "<none>"], "correctAnswer": 0}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roundTrip(t, FormatMoodleXML, tt.body)
+		})
+	}
+}
+
+func TestMoodleXMLExportWarnings(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		warning string
+	}{
+		{"ordering", ordering, "skipped, ordering questions are not exported"},
+		{"sql", sqlExercise, "skipped, sql questions are not exported"},
+		{"exact matching", matching, "exact scoring is exported as Moodle partial credit"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			warnings, err := Encode(FormatMoodleXML, &buf, []models.QuestionRequest{request(t, tt.body)}, nil)
+			if err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			if len(warnings) != 1 || !strings.Contains(warnings[0], tt.warning) {
+				t.Errorf("Encode() warnings = %q, want one containing %q", warnings, tt.warning)
+			}
+		})
+	}
+}
@@ -0,0 +1,602 @@
+package formats
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"aws-rds-quiz-backend/models"
+)
+
+// FormatQTI is an IMS QTI 2.1 content package: a zip archive holding an
+// imsmanifest.xml and one assessmentItem file per question
+const FormatQTI = "qti"
+
+const (
+	qtiNamespace      = "http://www.imsglobal.org/xsd/imsqti_v2p1"
+	qtiSchemaLocation = "http://www.imsglobal.org/xsd/imsqti_v2p1 http://www.imsglobal.org/xsd/qti/qtiv2p1/imsqti_v2p1.xsd"
+	cpNamespace       = "http://www.imsglobal.org/xsd/imscp_v1p1"
+	lomNamespace      = "http://ltsc.ieee.org/xsd/LOM"
+	qtiItemType       = "imsqti_item_xmlv2p1"
+	qtiManifestName   = "imsmanifest.xml"
+	qtiMaxItemSize    = 1 << 20
+	qtiMaxFileSize    = 10 << 20
+	qtiMediaDir       = "media/"
+)
+
+// Difficulty values are mapped onto the LOM educational difficulty vocabulary
+var (
+	lomDifficulty = map[string]string{
+		models.DifficultyEasy:   "easy",
+		models.DifficultyMedium: "medium",
+		models.DifficultyHard:   "difficult",
+	}
+	lomDifficultyReverse = map[string]string{
+		"very easy":      models.DifficultyEasy,
+		"easy":           models.DifficultyEasy,
+		"medium":         models.DifficultyMedium,
+		"difficult":      models.DifficultyHard,
+		"very difficult": models.DifficultyHard,
+	}
+)
+
+type qtiManifest struct {
+	XMLName    xml.Name      `xml:"manifest"`
+	Xmlns      string        `xml:"xmlns,attr,omitempty"`
+	XmlnsLOM   string        `xml:"xmlns:imsmd,attr,omitempty"`
+	Identifier string        `xml:"identifier,attr"`
+	Metadata   *qtiSchema    `xml:"metadata,omitempty"`
+	Resources  []qtiResource `xml:"resources>resource"`
+}
+
+type qtiSchema struct {
+	Schema        string `xml:"schema"`
+	SchemaVersion string `xml:"schemaversion"`
+}
+
+type qtiResource struct {
+	Identifier string       `xml:"identifier,attr"`
+	Type       string       `xml:"type,attr"`
+	Href       string       `xml:"href,attr"`
+	Metadata   *qtiMetadata `xml:"metadata,omitempty"`
+	Files      []qtiFile    `xml:"file"`
+}
+
+type qtiFile struct {
+	Href string `xml:"href,attr"`
+}
+
+// qtiMetadata is the subset of IEEE LOM used to carry category and difficulty
+type qtiMetadata struct {
+	LOM qtiLOM `xml:"imsmd:lom"`
+}
+
+type qtiLOM struct {
+	Keywords   []qtiLangString `xml:"imsmd:general>imsmd:keyword>imsmd:string"`
+	Difficulty *qtiVocabulary  `xml:"imsmd:educational>imsmd:difficulty,omitempty"`
+}
+
+type qtiLangString struct {
+	Value string `xml:",chardata"`
+}
+
+type qtiVocabulary struct {
+	Source string `xml:"imsmd:source"`
+	Value  string `xml:"imsmd:value"`
+}
+
+// The manifest is decoded with namespace-agnostic tags since packages from
+// other tools use different prefixes for the LOM namespace
+type qtiManifestIn struct {
+	Resources []struct {
+		Identifier string   `xml:"identifier,attr"`
+		Type       string   `xml:"type,attr"`
+		Href       string   `xml:"href,attr"`
+		Keywords   []string `xml:"metadata>lom>general>keyword>string"`
+		Difficulty string   `xml:"metadata>lom>educational>difficulty>value"`
+	} `xml:"resources>resource"`
+}
+
+type qtiItem struct {
+	XMLName        xml.Name           `xml:"assessmentItem"`
+	Xmlns          string             `xml:"xmlns,attr,omitempty"`
+	XmlnsXSI       string             `xml:"xmlns:xsi,attr,omitempty"`
+	SchemaLocation string             `xml:"xsi:schemaLocation,attr,omitempty"`
+	Identifier     string             `xml:"identifier,attr"`
+	Title          string             `xml:"title,attr"`
+	Adaptive       string             `xml:"adaptive,attr"`
+	TimeDependent  string             `xml:"timeDependent,attr"`
+	Responses      []qtiResponseDecl  `xml:"responseDeclaration"`
+	Outcomes       []qtiOutcomeDecl   `xml:"outcomeDeclaration"`
+	Body           qtiItemBody        `xml:"itemBody"`
+	Processing     *qtiInnerXML       `xml:"responseProcessing"`
+	Feedback       []qtiModalFeedback `xml:"modalFeedback"`
+}
+
+type qtiResponseDecl struct {
+	Identifier  string      `xml:"identifier,attr"`
+	Cardinality string      `xml:"cardinality,attr"`
+	BaseType    string      `xml:"baseType,attr"`
+	Correct     []string    `xml:"correctResponse>value"`
+	Mapping     *qtiMapping `xml:"mapping,omitempty"`
+}
+
+// qtiMapping awards a share of the score per selected choice
+type qtiMapping struct {
+	LowerBound   string            `xml:"lowerBound,attr,omitempty"`
+	UpperBound   string            `xml:"upperBound,attr,omitempty"`
+	DefaultValue string            `xml:"defaultValue,attr"`
+	Entries      []qtiMappingEntry `xml:"mapEntry"`
+}
+
+type qtiMappingEntry struct {
+	MapKey      string `xml:"mapKey,attr"`
+	MappedValue string `xml:"mappedValue,attr"`
+}
+
+type qtiOutcomeDecl struct {
+	Identifier  string       `xml:"identifier,attr"`
+	Cardinality string       `xml:"cardinality,attr"`
+	BaseType    string       `xml:"baseType,attr"`
+	Default     *qtiInnerXML `xml:"defaultValue,omitempty"`
+}
+
+type qtiItemBody struct {
+	Paragraphs   []qtiInnerXML          `xml:"p"`
+	Interactions []qtiChoiceInteraction `xml:"choiceInteraction"`
+	Other        []qtiAnyElement        `xml:",any"`
+}
+
+type qtiChoiceInteraction struct {
+	ResponseIdentifier string       `xml:"responseIdentifier,attr"`
+	Shuffle            string       `xml:"shuffle,attr"`
+	MaxChoices         string       `xml:"maxChoices,attr"`
+	Prompt             *qtiInnerXML `xml:"prompt,omitempty"`
+	Choices            []qtiChoice  `xml:"simpleChoice"`
+}
+
+type qtiChoice struct {
+	Identifier string `xml:"identifier,attr"`
+	Inner      string `xml:",innerxml"`
+}
+
+type qtiModalFeedback struct {
+	OutcomeIdentifier string `xml:"outcomeIdentifier,attr"`
+	Identifier        string `xml:"identifier,attr"`
+	ShowHide          string `xml:"showHide,attr"`
+	Inner             string `xml:",innerxml"`
+}
+
+type qtiInnerXML struct {
+	Inner string `xml:",innerxml"`
+}
+
+type qtiAnyElement struct {
+	XMLName xml.Name
+}
+
+// qtiPartialProcessing scores the item from the response mapping and always
+// shows the explanation
+const qtiPartialProcessing = `
+    <setOutcomeValue identifier="SCORE"><mapResponse identifier="RESPONSE"/></setOutcomeValue>
+    <setOutcomeValue identifier="FEEDBACK"><baseValue baseType="identifier">GENERAL</baseValue></setOutcomeValue>
+  `
+
+// qtiResponseProcessing scores the item and always shows the explanation
+const qtiResponseProcessing = `
+    <responseCondition>
+      <responseIf>
+        <match><variable identifier="RESPONSE"/><correct identifier="RESPONSE"/></match>
+        <setOutcomeValue identifier="SCORE"><baseValue baseType="float">1</baseValue></setOutcomeValue>
+      </responseIf>
+      <responseElse>
+        <setOutcomeValue identifier="SCORE"><baseValue baseType="float">0</baseValue></setOutcomeValue>
+      </responseElse>
+    </responseCondition>
+    <setOutcomeValue identifier="FEEDBACK"><baseValue baseType="identifier">GENERAL</baseValue></setOutcomeValue>
+  `
+
+// decodeQTI reads single and multiple choice items from an IMS QTI 2.1 content package
+func decodeQTI(r io.Reader) ([]Row, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid QTI package: %v", err)
+	}
+
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, f := range archive.File {
+		files[f.Name] = f
+	}
+
+	manifestFile, ok := files[qtiManifestName]
+	if !ok {
+		return nil, fmt.Errorf("invalid QTI package: missing %s", qtiManifestName)
+	}
+	var manifest qtiManifestIn
+	if err := readZipXML(manifestFile, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", qtiManifestName, err)
+	}
+
+	var rows []Row
+	for _, resource := range manifest.Resources {
+		if !strings.HasPrefix(resource.Type, "imsqti_item_xmlv2p") {
+			continue
+		}
+
+		row := Row{Line: len(rows) + 1, Source: resource.Href}
+		file, ok := files[path.Clean(resource.Href)]
+		if !ok {
+			row.Err = fmt.Errorf("item file %s is missing from the package", resource.Href)
+			rows = append(rows, row)
+			continue
+		}
+
+		var item qtiItem
+		if err := readZipXML(file, &item); err != nil {
+			row.Err = fmt.Errorf("invalid assessmentItem: %v", err)
+			rows = append(rows, row)
+			continue
+		}
+
+		row.Files = qtiImages(&item, func(src string) (File, bool) {
+			if strings.Contains(src, ":") {
+				return File{}, false
+			}
+			f, ok := files[path.Join(path.Dir(resource.Href), src)]
+			if !ok {
+				return File{}, false
+			}
+			data, err := readZipFile(f, qtiMaxFileSize)
+			if err != nil {
+				return File{}, false
+			}
+			return newFile(path.Base(f.Name), "", data), true
+		})
+		row.Question, row.Warnings, row.Err = qtiToRequest(item)
+		if len(resource.Keywords) > 0 {
+			row.Question.Category = strings.TrimSpace(resource.Keywords[0])
+			for _, keyword := range resource.Keywords[1:] {
+				row.Warnings = append(row.Warnings, fmt.Sprintf("keyword %q was dropped", keyword))
+			}
+		}
+		if resource.Difficulty != "" {
+			difficulty, ok := lomDifficultyReverse[strings.ToLower(strings.TrimSpace(resource.Difficulty))]
+			if !ok {
+				row.Warnings = append(row.Warnings, fmt.Sprintf("unknown difficulty %q was dropped", resource.Difficulty))
+			}
+			row.Question.Difficulty = difficulty
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func qtiToRequest(item qtiItem) (models.QuestionRequest, []string, error) {
+	var (
+		req      models.QuestionRequest
+		warnings []string
+	)
+
+	text := func(field, inner string) string {
+		value, lossy := htmlToText(inner)
+		if lossy {
+			warnings = append(warnings, fmt.Sprintf("markup removed from %s", field))
+		}
+		return value
+	}
+
+	var paragraphs []string
+	for _, p := range item.Body.Paragraphs {
+		paragraphs = append(paragraphs, text("item body", p.Inner))
+	}
+	for _, other := range item.Body.Other {
+		name := other.XMLName.Local
+		if strings.HasSuffix(name, "Interaction") {
+			return req, warnings, fmt.Errorf("%s is not supported", name)
+		}
+		warnings = append(warnings, fmt.Sprintf("<%s> element in item body was dropped", name))
+	}
+
+	if len(item.Body.Interactions) != 1 {
+		return req, warnings, fmt.Errorf("expected exactly one choiceInteraction, found %d", len(item.Body.Interactions))
+	}
+	interaction := item.Body.Interactions[0]
+	if interaction.Prompt != nil {
+		paragraphs = append(paragraphs, text("prompt", interaction.Prompt.Inner))
+	}
+	req.Question = strings.TrimSpace(strings.Join(paragraphs, "\n"))
+
+	var decl *qtiResponseDecl
+	for i := range item.Responses {
+		if item.Responses[i].Identifier == interaction.ResponseIdentifier {
+			decl = &item.Responses[i]
+		}
+	}
+	if decl == nil || len(decl.Correct) == 0 {
+		return req, warnings, fmt.Errorf("item has no correct response")
+	}
+
+	choices := make(map[string]int, len(interaction.Choices))
+	for i, choice := range interaction.Choices {
+		req.Options = append(req.Options, text(fmt.Sprintf("choice %d", i+1), choice.Inner))
+		choices[choice.Identifier] = i
+	}
+	var correct []int
+	for _, value := range decl.Correct {
+		index, ok := choices[strings.TrimSpace(value)]
+		if !ok {
+			return req, warnings, fmt.Errorf("correct response %q does not match any choice", value)
+		}
+		correct = append(correct, index)
+	}
+
+	if decl.Cardinality != "multiple" {
+		if len(correct) != 1 {
+			return req, warnings, fmt.Errorf("expected exactly one correct response, found %d", len(correct))
+		}
+		req.CorrectAnswer = correct[0]
+	} else {
+		req.Type = models.QuestionTypeMultiple
+		req.CorrectAnswers = correct
+		req.Scoring = models.ScoringExact
+		if decl.Mapping != nil {
+			req.Scoring = models.ScoringPartial
+		}
+		if interaction.MaxChoices != strconv.Itoa(len(correct)) {
+			warnings = append(warnings, fmt.Sprintf("maxChoices=%s replaced by the number of correct responses", interaction.MaxChoices))
+		}
+	}
+
+	for i, feedback := range item.Feedback {
+		if i == 0 {
+			req.Explanation = text("modal feedback", feedback.Inner)
+			continue
+		}
+		warnings = append(warnings, fmt.Sprintf("modal feedback %q was dropped", feedback.Identifier))
+	}
+
+	return req, warnings, nil
+}
+
+// qtiImages replaces the images in the item body and choices that point at
+// files in the package with attachment references and returns their files
+func qtiImages(item *qtiItem, resolve func(src string) (File, bool)) []File {
+	var files []File
+	for i := range item.Body.Paragraphs {
+		item.Body.Paragraphs[i].Inner = imagesToRefs(item.Body.Paragraphs[i].Inner, resolve, &files)
+	}
+	for i := range item.Body.Interactions {
+		interaction := &item.Body.Interactions[i]
+		if interaction.Prompt != nil {
+			interaction.Prompt.Inner = imagesToRefs(interaction.Prompt.Inner, resolve, &files)
+		}
+		for j := range interaction.Choices {
+			interaction.Choices[j].Inner = imagesToRefs(interaction.Choices[j].Inner, resolve, &files)
+		}
+	}
+	return files
+}
+
+func readZipXML(f *zip.File, v interface{}) error {
+	if f.UncompressedSize64 > qtiMaxItemSize {
+		return fmt.Errorf("%s is too large", f.Name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(rc).Decode(v)
+}
+
+func readZipFile(f *zip.File, maxSize int64) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("%s is too large", f.Name)
+	}
+	return data, nil
+}
+
+// encodeQTI writes an IMS QTI 2.1 content package with one item per question.
+// Category and difficulty go into the LOM metadata of each item's manifest
+// resource and the explanation becomes the item's modal feedback. Images
+// referenced by the question text and options are packaged under media/.
+// Only choice interactions are written: true/false questions become a
+// True/False choice and the other non-choice types are skipped with a warning.
+func encodeQTI(w io.Writer, questions []models.QuestionRequest, load Loader) ([]string, error) {
+	archive := zip.NewWriter(w)
+	manifest := qtiManifest{
+		Xmlns:      cpNamespace,
+		XmlnsLOM:   lomNamespace,
+		Identifier: "aws-quiz-question-bank",
+		Metadata:   &qtiSchema{Schema: "IMS Content", SchemaVersion: "1.1"},
+	}
+
+	set := newAttachmentSet(load)
+	packaged := map[string]bool{}
+	var warnings []string
+	for i, q := range questions {
+		if len(q.Variants) > 0 {
+			warnings = append(warnings, fmt.Sprintf("question %d: skipped, template questions are not exported to QTI", i+1))
+			continue
+		}
+		switch q.Type {
+		case models.QuestionTypeOrdering, models.QuestionTypeMatching, models.QuestionTypeFillBlank, models.QuestionTypeNumeric, models.QuestionTypeSQL:
+			warnings = append(warnings, fmt.Sprintf("question %d: skipped, %s questions are not exported to QTI", i+1, q.Type))
+			continue
+		case models.QuestionTypeTrueFalse:
+			warnings = append(warnings, fmt.Sprintf("question %d: true/false is exported as a True/False choice interaction", i+1))
+		}
+		if len(q.Tags) > 0 || q.Topic != "" {
+			warnings = append(warnings, fmt.Sprintf("question %d: tags and topic are not exported to QTI", i+1))
+		}
+		if len(q.Rationales) > 0 || len(q.References) > 0 {
+			warnings = append(warnings, fmt.Sprintf("question %d: rationales and references are not exported to QTI", i+1))
+		}
+		identifier := fmt.Sprintf("item%d", i+1)
+		href := "items/" + identifier + ".xml"
+
+		var files []File
+		render := func(text string) string {
+			return htmlWithImages(i, text, set, escapeXML, func(f File) string {
+				return "../" + qtiMediaDir + embeddedName(f)
+			}, &files, &warnings)
+		}
+		if err := writeZipXML(archive, href, qtiItemFor(identifier, q, render)); err != nil {
+			return nil, err
+		}
+
+		resource := qtiResource{
+			Identifier: identifier,
+			Type:       qtiItemType,
+			Href:       href,
+			Files:      []qtiFile{{Href: href}},
+			Metadata:   &qtiMetadata{},
+		}
+		for _, f := range files {
+			media := qtiMediaDir + embeddedName(f)
+			resource.Files = append(resource.Files, qtiFile{Href: media})
+			if packaged[media] {
+				continue
+			}
+			packaged[media] = true
+			entry, err := archive.CreateHeader(&zip.FileHeader{Name: media, Method: zip.Store, Modified: time.Now()})
+			if err != nil {
+				return nil, err
+			}
+			if _, err := entry.Write(f.Data); err != nil {
+				return nil, err
+			}
+		}
+		if q.Category != "" {
+			resource.Metadata.LOM.Keywords = []qtiLangString{{Value: q.Category}}
+		}
+		if value, ok := lomDifficulty[q.Difficulty]; ok {
+			resource.Metadata.LOM.Difficulty = &qtiVocabulary{Source: "LOMv1.0", Value: value}
+		}
+		manifest.Resources = append(manifest.Resources, resource)
+	}
+
+	if err := writeZipXML(archive, qtiManifestName, manifest); err != nil {
+		return nil, err
+	}
+	return warnings, archive.Close()
+}
+
+// qtiItemFor builds the assessment item for a question; render converts the
+// question text and options to item body markup
+func qtiItemFor(identifier string, q models.QuestionRequest, render func(string) string) qtiItem {
+	item := qtiItem{
+		Xmlns:          qtiNamespace,
+		XmlnsXSI:       "http://www.w3.org/2001/XMLSchema-instance",
+		SchemaLocation: qtiSchemaLocation,
+		Identifier:     identifier,
+		Title:          questionName(q.Question),
+		Adaptive:       "false",
+		TimeDependent:  "false",
+		Responses:      []qtiResponseDecl{qtiResponseFor(q)},
+		Outcomes: []qtiOutcomeDecl{
+			{Identifier: "SCORE", Cardinality: "single", BaseType: "float", Default: &qtiInnerXML{Inner: "<value>0</value>"}},
+			{Identifier: "FEEDBACK", Cardinality: "single", BaseType: "identifier"},
+		},
+		Processing: &qtiInnerXML{Inner: qtiResponseProcessing},
+	}
+
+	item.Body.Paragraphs = []qtiInnerXML{{Inner: render(q.Question)}}
+	interaction := qtiChoiceInteraction{ResponseIdentifier: "RESPONSE", Shuffle: "false", MaxChoices: "1"}
+	if q.Type == models.QuestionTypeMultiple {
+		interaction.MaxChoices = strconv.Itoa(len(q.CorrectAnswers))
+		if q.Scoring == models.ScoringPartial {
+			item.Processing = &qtiInnerXML{Inner: qtiPartialProcessing}
+		}
+	}
+	for i, option := range q.Options {
+		interaction.Choices = append(interaction.Choices, qtiChoice{
+			Identifier: fmt.Sprintf("choice%d", i),
+			Inner:      render(option),
+		})
+	}
+	item.Body.Interactions = []qtiChoiceInteraction{interaction}
+
+	if q.Explanation != "" {
+		item.Feedback = []qtiModalFeedback{{
+			OutcomeIdentifier: "FEEDBACK",
+			Identifier:        "GENERAL",
+			ShowHide:          "show",
+			Inner:             escapeXML(q.Explanation),
+		}}
+	}
+	return item
+}
+
+// qtiResponseFor declares the correct response, plus a mapping that gives
+// each correct choice an equal share of the score for partial scoring
+func qtiResponseFor(q models.QuestionRequest) qtiResponseDecl {
+	decl := qtiResponseDecl{
+		Identifier:  "RESPONSE",
+		Cardinality: "single",
+		BaseType:    "identifier",
+	}
+	if q.Type != models.QuestionTypeMultiple {
+		decl.Correct = []string{fmt.Sprintf("choice%d", q.CorrectAnswer)}
+		return decl
+	}
+
+	decl.Cardinality = "multiple"
+	for _, index := range q.CorrectAnswers {
+		decl.Correct = append(decl.Correct, fmt.Sprintf("choice%d", index))
+	}
+	if q.Scoring == models.ScoringPartial {
+		share := formatWeight(1 / float64(len(q.CorrectAnswers)))
+		decl.Mapping = &qtiMapping{LowerBound: "0", UpperBound: "1", DefaultValue: "0"}
+		for _, value := range decl.Correct {
+			decl.Mapping.Entries = append(decl.Mapping.Entries, qtiMappingEntry{MapKey: value, MappedValue: share})
+		}
+	}
+	return decl
+}
+
+func writeZipXML(archive *zip.Writer, name string, v interface{}) error {
+	f, err := archive.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(f, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(f)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return err
+	}
+	_, err = io.WriteString(f, "\n")
+	return err
+}
+
+func escapeXML(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
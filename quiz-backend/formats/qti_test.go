@@ -0,0 +1,50 @@
+package formats
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"aws-rds-quiz-backend/models"
+)
+
+func TestQTIRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"single choice", singleChoice},
+		{"multiple choice", multipleChoice},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roundTrip(t, FormatQTI, tt.body)
+		})
+	}
+}
+
+func TestQTIExportWarnings(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		warning string
+	}{
+		{"numeric", numeric, "skipped, numeric questions are not exported to QTI"},
+		{"true false", trueFalse, "true/false is exported as a True/False choice interaction"},
+		{"tags", `{"question": "Port?", "options": ["3306", "5432"], "correctAnswer": 0, "tags": ["mysql"]}`, "tags and topic are not exported"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			warnings, err := Encode(FormatQTI, &buf, []models.QuestionRequest{request(t, tt.body)}, nil)
+			if err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			if len(warnings) != 1 || !strings.Contains(warnings[0], tt.warning) {
+				t.Errorf("Encode() warnings = %q, want one containing %q", warnings, tt.warning)
+			}
+		})
+	}
+}
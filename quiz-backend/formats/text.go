@@ -0,0 +1,31 @@
+package formats
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	htmlBreak = regexp.MustCompile(`(?i)<br\s*/?>|</p>\s*<p[^>]*>`)
+	htmlTag   = regexp.MustCompile(`<[^>]+>`)
+)
+
+// htmlToText converts an HTML fragment to plain text. The second result
+// reports whether markup other than paragraphs and line breaks was removed,
+// so callers can flag the conversion as lossy.
+func htmlToText(fragment string) (string, bool) {
+	text := htmlBreak.ReplaceAllString(fragment, "\n")
+	lossy := false
+	text = htmlTag.ReplaceAllStringFunc(text, func(tag string) string {
+		name := strings.ToLower(strings.Trim(tag, "</> "))
+		if i := strings.IndexAny(name, " \t\n/"); i >= 0 {
+			name = name[:i]
+		}
+		if name != "p" {
+			lossy = true
+		}
+		return ""
+	})
+	return strings.TrimSpace(html.UnescapeString(text)), lossy
+}
@@ -0,0 +1,92 @@
+package formats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"aws-rds-quiz-backend/models"
+
+	"gopkg.in/yaml.v3"
+)
+
+// decodeYAML reads a YAML sequence of questions, either at the top level or
+// under a "questions" key
+func decodeYAML(r io.Reader) ([]Row, error) {
+	var doc yaml.Node
+	if err := yaml.NewDecoder(r).Decode(&doc); err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("invalid YAML: %v", err)
+	}
+
+	root := &doc
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		root = root.Content[0]
+	}
+	if root.Kind == yaml.MappingNode {
+		root = mappingValue(root, "questions")
+	}
+	if root == nil || root.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("invalid YAML: expected a list of questions")
+	}
+
+	var rows []Row
+	for _, node := range root.Content {
+		rows = append(rows, decodeYAMLQuestion(node))
+	}
+	return rows, nil
+}
+
+// decodeYAMLQuestion converts one YAML node to a QuestionRequest by way of
+// JSON, so the JSON field names on the model are the only schema to maintain
+func decodeYAMLQuestion(node *yaml.Node) Row {
+	row := Row{Line: node.Line}
+
+	var value interface{}
+	if err := node.Decode(&value); err != nil {
+		row.Err = err
+		return row
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		row.Err = err
+		return row
+	}
+
+	row.Question, row.Err = decodeQuestion(data)
+	return row
+}
+
+// mappingValue returns the value node for key in a YAML mapping
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// encodeYAML writes questions under a top-level "questions" key, using the
+// same field names as the JSON API
+func encodeYAML(w io.Writer, questions []models.QuestionRequest) ([]string, error) {
+	data, err := json.Marshal(questions)
+	if err != nil {
+		return nil, err
+	}
+
+	var value []interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(map[string]interface{}{"questions": value}); err != nil {
+		return nil, err
+	}
+	return nil, encoder.Close()
+}
@@ -0,0 +1,162 @@
+package grading
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"aws-rds-quiz-backend/models"
+)
+
+// grader scores one answer to a question of a particular type, filling in
+// the type-specific fields of detail
+type grader func(q *models.Question, options []string, answer json.RawMessage, detail *models.QuizAnswerDetail) error
+
+// graders maps each question type to its grader
+var graders = map[string]grader{
+	models.QuestionTypeSingle:    gradeSingle,
+	models.QuestionTypeMultiple:  gradeMultiple,
+	models.QuestionTypeTrueFalse: gradeTrueFalse,
+	models.QuestionTypeOrdering:  gradeOrdering,
+	models.QuestionTypeMatching:  gradeMatching,
+	models.QuestionTypeFillBlank: gradeFillBlank,
+	models.QuestionTypeNumeric:   gradeNumeric,
+	models.QuestionTypeSQL:       gradeSQL,
+}
+
+// Grade scores a learner's answer to q. The returned detail is always
+// populated with the question and correct answer; err is set when the answer
+// does not have the shape the question type expects, in which case the
+// answer earns no credit.
+func Grade(q *models.Question, answer json.RawMessage) (models.QuizAnswerDetail, error) {
+	detail := models.QuizAnswerDetail{
+		QuestionID:    q.ID,
+		Revision:      q.Revision,
+		Type:          q.QuestionType(),
+		Question:      q.Question,
+		UserAnswer:    -1,
+		CorrectAnswer: q.CorrectAnswer,
+	}
+
+	options, err := q.DecodeOptions()
+	if err != nil {
+		return detail, fmt.Errorf("failed to parse question options: %v", err)
+	}
+
+	grade, ok := graders[detail.Type]
+	if !ok {
+		return detail, fmt.Errorf("unsupported question type %q", detail.Type)
+	}
+	err = grade(q, options, answer, &detail)
+	addFeedback(q, &detail)
+	if err != nil {
+		detail.IsCorrect = false
+		detail.Credit = 0
+		return detail, err
+	}
+
+	detail.IsCorrect = detail.Credit >= 1
+	return detail, nil
+}
+
+// addFeedback fills in the references and the rationales of the picked and
+// correct options
+func addFeedback(q *models.Question, detail *models.QuizAnswerDetail) {
+	detail.References = q.DecodeReferences()
+	rationales := q.DecodeRationales()
+	if len(rationales) == 0 {
+		return
+	}
+
+	switch detail.Type {
+	case models.QuestionTypeSingle:
+		detail.SelectedRationale = models.RationaleAt(rationales, detail.UserAnswer)
+		detail.CorrectRationale = models.RationaleAt(rationales, q.CorrectAnswer)
+	case models.QuestionTypeMultiple:
+		for _, index := range detail.SelectedAnswers {
+			detail.SelectedRationales = append(detail.SelectedRationales, models.RationaleAt(rationales, index))
+		}
+		for _, index := range detail.CorrectAnswers {
+			detail.CorrectRationales = append(detail.CorrectRationales, models.RationaleAt(rationales, index))
+		}
+	}
+}
+
+// gradeSingle expects a single option index
+func gradeSingle(q *models.Question, options []string, answer json.RawMessage, detail *models.QuizAnswerDetail) error {
+	detail.CorrectOption = optionAt(options, q.CorrectAnswer)
+
+	var selected int
+	if err := json.Unmarshal(answer, &selected); err != nil {
+		return fmt.Errorf("answer must be an option index")
+	}
+
+	detail.UserAnswer = selected
+	detail.SelectedOption = optionAt(options, selected)
+	if selected == q.CorrectAnswer {
+		detail.Credit = 1
+	}
+	return nil
+}
+
+// gradeMultiple expects a list of option indices. Exact scoring gives credit
+// only for exactly the correct set; partial scoring gives a share of the
+// point for each correct option picked, and nothing if more options than
+// required were picked.
+func gradeMultiple(q *models.Question, options []string, answer json.RawMessage, detail *models.QuizAnswerDetail) error {
+	correct, err := q.DecodeCorrectAnswers()
+	if err != nil {
+		return fmt.Errorf("failed to parse correct answers: %v", err)
+	}
+	detail.CorrectAnswers = correct
+	detail.CorrectOptions = optionsAt(options, correct)
+	if len(correct) > 0 {
+		detail.CorrectOption = options[correct[0]]
+	}
+
+	var selected []int
+	if err := json.Unmarshal(answer, &selected); err != nil {
+		return fmt.Errorf("answer must be a list of option indices")
+	}
+	seen := make(map[int]bool, len(selected))
+	for _, index := range selected {
+		if index < 0 || index >= len(options) {
+			return fmt.Errorf("option index %d is out of range", index)
+		}
+		if seen[index] {
+			return fmt.Errorf("option index %d is selected more than once", index)
+		}
+		seen[index] = true
+	}
+	detail.SelectedAnswers = selected
+	detail.SelectedOptions = optionsAt(options, selected)
+
+	hits := 0
+	for _, index := range correct {
+		if seen[index] {
+			hits++
+		}
+	}
+
+	switch {
+	case hits == len(correct) && len(selected) == len(correct):
+		detail.Credit = 1
+	case q.Scoring == models.ScoringPartial && len(selected) <= len(correct):
+		detail.Credit = float64(hits) / float64(len(correct))
+	}
+	return nil
+}
+
+func optionAt(options []string, index int) string {
+	if index >= 0 && index < len(options) {
+		return options[index]
+	}
+	return ""
+}
+
+func optionsAt(options []string, indices []int) []string {
+	result := make([]string, 0, len(indices))
+	for _, index := range indices {
+		result = append(result, optionAt(options, index))
+	}
+	return result
+}
@@ -0,0 +1,134 @@
+package grading
+
+import (
+	"encoding/json"
+	"testing"
+
+	"aws-rds-quiz-backend/models"
+)
+
+// question builds a question from its authoring request, as the API would
+func question(t *testing.T, request string) *models.Question {
+	t.Helper()
+	var req models.QuestionRequest
+	if err := json.Unmarshal([]byte(request), &req); err != nil {
+		t.Fatalf("invalid request: %v", err)
+	}
+	req.Normalize()
+	if err := req.Validate(); err != nil {
+		t.Fatalf("invalid question: %v", err)
+	}
+	var q models.Question
+	if err := req.ApplyTo(&q); err != nil {
+		t.Fatalf("failed to apply request: %v", err)
+	}
+	return &q
+}
+
+func TestGrade(t *testing.T) {
+	const (
+		single     = `{"question": "Default MySQL port?", "options": ["3306", "5432", "1521", "1433"], "correctAnswer": 0}`
+		exact      = `{"question": "Pick two", "type": "multiple_choice", "options": ["a", "b", "c", "d"], "correctAnswers": [1, 3]}`
+		partial    = `{"question": "Pick two", "type": "multiple_choice", "options": ["a", "b", "c", "d"], "correctAnswers": [1, 3], "scoring": "partial"}`
+		trueFalse  = `{"question": "Aurora is MySQL compatible", "type": "true_false", "answerKey": {"answer": true}}`
+		ordering   = `{"question": "Order", "type": "ordering", "options": ["x", "y", "z"], "answerKey": {"order": [2, 0, 1]}, "scoring": "partial"}`
+		matching   = `{"question": "Match", "type": "matching", "options": ["MySQL", "PostgreSQL"], "answerKey": {"targets": ["3306", "5432", "1521"], "pairs": [0, 1]}}`
+		fillBlank  = `{"question": "Default MySQL port?", "type": "fill_blank", "answerKey": {"accepted": ["3306"]}}`
+		fillRegex  = `{"question": "Engine?", "type": "fill_blank", "answerKey": {"accepted": ["(aurora-)?mysql"], "match": "regex"}}`
+		numeric    = `{"question": "Max backup retention?", "type": "numeric", "answerKey": {"value": 35, "unit": "days"}}`
+		relative   = `{"question": "Max storage?", "type": "numeric", "answerKey": {"value": 64, "tolerance": 0.05, "toleranceMode": "relative", "unit": "TiB"}}`
+		sqlFixture = `"fixture": "CREATE TABLE db (name TEXT, engine TEXT); INSERT INTO db VALUES ('a', 'mysql'), ('b', 'postgres'), ('c', 'mysql');"`
+		sqlOrdered = `{"question": "MySQL databases", "type": "sql", "answerKey": {` + sqlFixture + `, "reference": "SELECT name FROM db WHERE engine = 'mysql' ORDER BY name"}}`
+		sqlAnyOrd  = `{"question": "MySQL databases", "type": "sql", "answerKey": {` + sqlFixture + `, "reference": "SELECT name FROM db WHERE engine = 'mysql'", "ignoreOrder": true}}`
+	)
+
+	tests := []struct {
+		name     string
+		question string
+		answer   string
+		credit   float64
+		invalid  bool
+	}{
+		{"single correct", single, `0`, 1, false},
+		{"single wrong", single, `2`, 0, false},
+		{"single out of range", single, `7`, 0, false},
+		{"single wrong shape", single, `"0"`, 0, true},
+		{"multiple exact set", exact, `[3, 1]`, 1, false},
+		{"multiple exact subset", exact, `[1]`, 0, false},
+		{"multiple partial subset", partial, `[1]`, 0.5, false},
+		{"multiple partial too many", partial, `[0, 1, 3]`, 0, false},
+		{"multiple out of range", partial, `[1, 4]`, 0, true},
+		{"multiple repeated index", exact, `[1, 1]`, 0, true},
+		{"true false boolean", trueFalse, `true`, 1, false},
+		{"true false index", trueFalse, `1`, 0, false},
+		{"true false wrong shape", trueFalse, `"yes"`, 0, true},
+		{"ordering correct", ordering, `[2, 0, 1]`, 1, false},
+		{"ordering partial", ordering, `[2, 1, 0]`, 1.0 / 3, false},
+		{"ordering repeated index", ordering, `[2, 2, 1]`, 0, true},
+		{"ordering missing item", ordering, `[2, 0]`, 0, true},
+		{"matching correct", matching, `[0, 1]`, 1, false},
+		{"matching distractor", matching, `[0, 2]`, 0, false},
+		{"fill blank normalized", fillBlank, `" 3306. "`, 1, false},
+		{"fill blank wrong", fillBlank, `"5432"`, 0, false},
+		{"fill blank regex", fillRegex, `"aurora-mysql"`, 1, false},
+		{"fill blank regex partial match", fillRegex, `"aurora-mysql8"`, 0, false},
+		{"numeric exact", numeric, `35`, 1, false},
+		{"numeric converted unit", numeric, `"5 weeks"`, 1, false},
+		{"numeric outside tolerance", numeric, `34`, 0, false},
+		{"numeric relative tolerance", relative, `"62 TiB"`, 1, false},
+		{"numeric relative outside", relative, `"60 TiB"`, 0, false},
+		{"sql matching query", sqlOrdered, `"SELECT name FROM db WHERE engine = 'mysql' ORDER BY 1"`, 1, false},
+		{"sql wrong order", sqlOrdered, `"SELECT name FROM db WHERE engine = 'mysql' ORDER BY name DESC"`, 0, false},
+		{"sql order ignored", sqlAnyOrd, `"SELECT name FROM db WHERE engine = 'mysql' ORDER BY name DESC"`, 1, false},
+		{"sql write denied", sqlOrdered, `"DELETE FROM db"`, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detail, err := Grade(question(t, tt.question), json.RawMessage(tt.answer))
+			if tt.invalid != (err != nil) {
+				t.Fatalf("Grade() error = %v, want invalid %v", err, tt.invalid)
+			}
+			if diff := detail.Credit - tt.credit; diff > 1e-9 || diff < -1e-9 {
+				t.Errorf("Grade() credit = %v, want %v", detail.Credit, tt.credit)
+			}
+			if detail.IsCorrect != (tt.credit == 1) {
+				t.Errorf("Grade() isCorrect = %v with credit %v", detail.IsCorrect, detail.Credit)
+			}
+		})
+	}
+}
+
+func TestGradeSQLDiff(t *testing.T) {
+	q := question(t, `{"question": "Names", "type": "sql", "answerKey": {"fixture": "CREATE TABLE t (n TEXT); INSERT INTO t VALUES ('a'), ('b');", "reference": "SELECT n FROM t ORDER BY n"}}`)
+
+	tests := []struct {
+		name   string
+		answer string
+		check  func(t *testing.T, detail *models.QuizAnswerDetail)
+	}{
+		{"missing row", `"SELECT n FROM t WHERE n = 'a'"`, func(t *testing.T, detail *models.QuizAnswerDetail) {
+			if detail.SQLDiff == nil || len(detail.SQLDiff.Missing) != 1 {
+				t.Errorf("sqlDiff = %+v, want one missing row", detail.SQLDiff)
+			}
+		}},
+		{"query error", `"SELECT nope FROM t"`, func(t *testing.T, detail *models.QuizAnswerDetail) {
+			if detail.SQLDiff == nil || detail.SQLDiff.Error == "" {
+				t.Errorf("sqlDiff = %+v, want the query's error", detail.SQLDiff)
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detail, err := Grade(q, json.RawMessage(tt.answer))
+			if err != nil {
+				t.Fatalf("Grade() error = %v", err)
+			}
+			if detail.Credit != 0 {
+				t.Errorf("Grade() credit = %v, want 0", detail.Credit)
+			}
+			tt.check(t, &detail)
+		})
+	}
+}
@@ -0,0 +1,252 @@
+package grading
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"aws-rds-quiz-backend/models"
+	"aws-rds-quiz-backend/sqlsandbox"
+)
+
+// gradeTrueFalse expects a boolean, or the index of the True/False option
+func gradeTrueFalse(q *models.Question, options []string, answer json.RawMessage, detail *models.QuizAnswerDetail) error {
+	detail.CorrectOption = optionAt(options, q.CorrectAnswer)
+
+	var value bool
+	if err := json.Unmarshal(answer, &value); err == nil {
+		detail.UserAnswer = 1
+		if value {
+			detail.UserAnswer = 0
+		}
+	} else if err := json.Unmarshal(answer, &detail.UserAnswer); err != nil || detail.UserAnswer < 0 || detail.UserAnswer > 1 {
+		detail.UserAnswer = -1
+		return fmt.Errorf("answer must be true or false")
+	}
+
+	detail.SelectedOption = optionAt(options, detail.UserAnswer)
+	if detail.UserAnswer == q.CorrectAnswer {
+		detail.Credit = 1
+	}
+	return nil
+}
+
+// gradeOrdering expects every option index once, in the learner's order.
+// Partial scoring gives a share of the point for each item in its correct
+// position.
+func gradeOrdering(q *models.Question, options []string, answer json.RawMessage, detail *models.QuizAnswerDetail) error {
+	var key models.OrderingKey
+	if err := q.DecodeAnswerKey(&key); err != nil {
+		return fmt.Errorf("failed to parse answer key: %v", err)
+	}
+	detail.CorrectAnswers = key.Order
+	detail.CorrectOptions = optionsAt(options, key.Order)
+
+	var order []int
+	if err := json.Unmarshal(answer, &order); err != nil {
+		return fmt.Errorf("answer must be a list of option indices")
+	}
+	if len(order) != len(options) {
+		return fmt.Errorf("answer must order all %d options", len(options))
+	}
+	seen := make(map[int]bool, len(order))
+	for _, index := range order {
+		if index < 0 || index >= len(options) {
+			return fmt.Errorf("option index %d is out of range", index)
+		}
+		if seen[index] {
+			return fmt.Errorf("option index %d is listed more than once", index)
+		}
+		seen[index] = true
+	}
+	detail.SelectedAnswers = order
+	detail.SelectedOptions = optionsAt(options, order)
+
+	hits := 0
+	for i, index := range order {
+		if key.Order[i] == index {
+			hits++
+		}
+	}
+	detail.Credit = listCredit(q, hits, len(order))
+	return nil
+}
+
+// gradeMatching expects one target index per option. Partial scoring gives a
+// share of the point for each correct pair.
+func gradeMatching(q *models.Question, options []string, answer json.RawMessage, detail *models.QuizAnswerDetail) error {
+	var key models.MatchingKey
+	if err := q.DecodeAnswerKey(&key); err != nil {
+		return fmt.Errorf("failed to parse answer key: %v", err)
+	}
+	detail.CorrectAnswers = key.Pairs
+	detail.CorrectOptions = optionsAt(key.Targets, key.Pairs)
+
+	var pairs []int
+	if err := json.Unmarshal(answer, &pairs); err != nil {
+		return fmt.Errorf("answer must be a list of target indices")
+	}
+	if len(pairs) != len(options) {
+		return fmt.Errorf("answer must match all %d options", len(options))
+	}
+	for _, target := range pairs {
+		if target < 0 || target >= len(key.Targets) {
+			return fmt.Errorf("target index %d is out of range", target)
+		}
+	}
+	detail.SelectedAnswers = pairs
+	detail.SelectedOptions = optionsAt(key.Targets, pairs)
+
+	hits := 0
+	for i, target := range pairs {
+		if key.Pairs[i] == target {
+			hits++
+		}
+	}
+	detail.Credit = listCredit(q, hits, len(pairs))
+	return nil
+}
+
+// listCredit scores ordering and matching answers with hits of n items right
+func listCredit(q *models.Question, hits, n int) float64 {
+	switch {
+	case hits == n:
+		return 1
+	case q.Scoring == models.ScoringPartial:
+		return float64(hits) / float64(n)
+	}
+	return 0
+}
+
+// gradeFillBlank expects a string compared against the accepted answers
+func gradeFillBlank(q *models.Question, options []string, answer json.RawMessage, detail *models.QuizAnswerDetail) error {
+	var key models.FillBlankKey
+	if err := q.DecodeAnswerKey(&key); err != nil {
+		return fmt.Errorf("failed to parse answer key: %v", err)
+	}
+	detail.AcceptedAnswers = key.Accepted
+	if len(key.Accepted) > 0 {
+		detail.CorrectOption = key.Accepted[0]
+	}
+
+	var text string
+	if err := json.Unmarshal(answer, &text); err != nil {
+		return fmt.Errorf("answer must be a string")
+	}
+	detail.TextAnswer = text
+	detail.SelectedOption = text
+
+	for _, accepted := range key.Accepted {
+		if blankMatches(key, accepted, text) {
+			detail.Credit = 1
+			break
+		}
+	}
+	return nil
+}
+
+// blankMatches compares a learner's text with one accepted answer using the
+// key's match mode
+func blankMatches(key models.FillBlankKey, accepted, text string) bool {
+	switch key.Match {
+	case models.MatchExact:
+		if key.CaseSensitive {
+			return text == accepted
+		}
+		return strings.EqualFold(text, accepted)
+	case models.MatchRegex:
+		pattern := "^(?:" + accepted + ")$"
+		if !key.CaseSensitive {
+			pattern = "(?i)" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		return err == nil && re.MatchString(strings.TrimSpace(text))
+	default:
+		text, accepted = normalizeBlank(text), normalizeBlank(accepted)
+		if key.CaseSensitive {
+			return text == accepted
+		}
+		return strings.EqualFold(text, accepted)
+	}
+}
+
+// normalizeBlank collapses whitespace and drops surrounding punctuation
+func normalizeBlank(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	return strings.TrimFunc(s, func(r rune) bool {
+		return unicode.IsPunct(r) || unicode.IsSpace(r)
+	})
+}
+
+// gradeNumeric expects a number in the key's unit, or a string with a value
+// and a unit that converts to it. An answer in an unrelated unit is wrong
+// rather than malformed.
+func gradeNumeric(q *models.Question, options []string, answer json.RawMessage, detail *models.QuizAnswerDetail) error {
+	var key models.NumericKey
+	if err := q.DecodeAnswerKey(&key); err != nil {
+		return fmt.Errorf("failed to parse answer key: %v", err)
+	}
+	allowed := key.Allowance()
+	detail.ExpectedValue = &key.Value
+	detail.Tolerance = &allowed
+	detail.Unit = key.Unit
+
+	var (
+		value    float64
+		unitName string
+		text     string
+	)
+	if err := json.Unmarshal(answer, &value); err != nil {
+		if err := json.Unmarshal(answer, &text); err != nil {
+			return fmt.Errorf("answer must be a number or a string such as \"35 days\"")
+		}
+		if value, unitName, err = models.ParseQuantity(text); err != nil {
+			return err
+		}
+		detail.TextAnswer = text
+	}
+
+	converted, ok := models.ConvertUnit(value, unitName, key.Unit)
+	if !ok {
+		return nil
+	}
+	detail.SubmittedValue = &converted
+	if key.Accepts(converted) {
+		detail.Credit = 1
+	}
+	return nil
+}
+
+// gradeSQL expects a query, which is run against a fresh copy of the fixture
+// and compared with the reference query's result. A query that fails to run
+// is a wrong answer and the failure is reported in the diff.
+func gradeSQL(q *models.Question, options []string, answer json.RawMessage, detail *models.QuizAnswerDetail) error {
+	var key models.SQLKey
+	if err := q.DecodeAnswerKey(&key); err != nil {
+		return fmt.Errorf("failed to parse answer key: %v", err)
+	}
+
+	var query string
+	if err := json.Unmarshal(answer, &query); err != nil {
+		return fmt.Errorf("answer must be an SQL query string")
+	}
+	detail.TextAnswer = query
+
+	expected, err := sqlsandbox.Run(key.Fixture, key.Reference, sqlsandbox.DefaultLimits)
+	if err != nil {
+		return fmt.Errorf("reference query failed: %v", err)
+	}
+	actual, err := sqlsandbox.Run(key.Fixture, query, sqlsandbox.DefaultLimits)
+	if err != nil {
+		detail.SQLDiff = &sqlsandbox.Diff{Error: err.Error()}
+		return nil
+	}
+
+	detail.SQLDiff = sqlsandbox.Compare(expected, actual, key.IgnoreOrder)
+	if detail.SQLDiff == nil {
+		detail.Credit = 1
+	}
+	return nil
+}
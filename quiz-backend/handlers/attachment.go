@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+
+	"aws-rds-quiz-backend/attachments"
+	"aws-rds-quiz-backend/models"
+	"aws-rds-quiz-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// multipartOverhead leaves room for the multipart framing around an upload
+const multipartOverhead = 64 << 10
+
+// UploadAttachment stores an image or diagram for use in questions. The file
+// may be sent as the multipart field "file" or as the raw request body, named
+// by the "filename" query parameter. Uploading content that is already
+// stored returns the existing attachment.
+func UploadAttachment(db *gorm.DB, store *attachments.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, store.MaxSize()+multipartOverhead)
+
+		body, filename, err := importSource(c)
+		if err != nil {
+			utils.BadRequestResponse(c, err.Error())
+			return
+		}
+		defer body.Close()
+		if filename == "" {
+			filename = c.Query("filename")
+		}
+
+		attachment, err := store.Save(db, body, filename)
+		switch {
+		case errors.Is(err, attachments.ErrTooLarge):
+			utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, err.Error())
+			return
+		case errors.Is(err, attachments.ErrUnsupportedType):
+			utils.ErrorResponse(c, http.StatusUnsupportedMediaType, err.Error())
+			return
+		case err != nil:
+			utils.InternalServerErrorResponse(c, "Failed to store attachment")
+			return
+		}
+
+		utils.CreatedResponse(c, attachment.ToResponse(), "Attachment uploaded successfully")
+	}
+}
+
+// GetAttachment downloads an attachment by its SHA-256 hash. Content never
+// changes under a hash, so responses are cached indefinitely. SVG images are
+// served in a sandbox so that scripts inside them cannot run.
+func GetAttachment(db *gorm.DB, store *attachments.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sha := strings.ToLower(c.Param("sha256"))
+
+		var attachment models.Attachment
+		if err := db.Where("sha256 = ?", sha).First(&attachment).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				utils.NotFoundResponse(c, "Attachment not found")
+				return
+			}
+			utils.InternalServerErrorResponse(c, "Failed to fetch attachment")
+			return
+		}
+
+		file, err := store.Open(sha)
+		if err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to read attachment")
+			return
+		}
+		defer file.Close()
+
+		header := c.Writer.Header()
+		header.Set("Content-Type", attachment.MIMEType)
+		header.Set("ETag", `"`+sha+`"`)
+		header.Set("Cache-Control", "public, max-age=31536000, immutable")
+		header.Set("X-Content-Type-Options", "nosniff")
+		header.Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; sandbox")
+		if attachment.Filename != "" {
+			header.Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": attachment.Filename}))
+		}
+		http.ServeContent(c.Writer, c.Request, "", attachment.CreatedAt, file)
+	}
+}
+
+// checkAttachments rejects a question that references attachments which
+// were never uploaded, writing the error response itself
+func checkAttachments(c *gin.Context, db *gorm.DB, req *models.QuestionRequest) bool {
+	missing, err := models.MissingAttachments(db, req.AttachmentHashes())
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to check attachments")
+		return false
+	}
+	if len(missing) > 0 {
+		utils.ValidationErrorResponse(c, "Unknown attachment: "+missing[0])
+		return false
+	}
+	return true
+}
@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"net/http"
+
+	"aws-rds-quiz-backend/models"
+	"aws-rds-quiz-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// bankKey is the context key under which ResolveBank stores the bank
+const bankKey = "bank"
+
+// ResolveBank loads the bank named by the :bank path parameter, or the
+// default bank on routes without one, for the question and quiz handlers
+func ResolveBank(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		bank, ok := findBank(c, db)
+		if !ok {
+			c.Abort()
+			return
+		}
+		c.Set(bankKey, &bank)
+		c.Next()
+	}
+}
+
+// currentBank returns the bank resolved for the request
+func currentBank(c *gin.Context) *models.Bank {
+	return c.MustGet(bankKey).(*models.Bank)
+}
+
+// GetBanks returns all question banks, the default one first
+func GetBanks(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var banks []models.Bank
+		if err := db.Order("is_default DESC, slug").Find(&banks).Error; err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to fetch banks")
+			return
+		}
+
+		responses := make([]models.BankResponse, 0, len(banks))
+		for i := range banks {
+			count, ok := publishedCount(c, db, &banks[i])
+			if !ok {
+				return
+			}
+			responses = append(responses, banks[i].ToResponse(count))
+		}
+		utils.SuccessResponse(c, responses, "Banks retrieved successfully")
+	}
+}
+
+// GetBank returns a question bank by slug
+func GetBank(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		bank, ok := findBank(c, db)
+		if !ok {
+			return
+		}
+		count, ok := publishedCount(c, db, &bank)
+		if !ok {
+			return
+		}
+		utils.SuccessResponse(c, bank.ToResponse(count), "Bank retrieved successfully")
+	}
+}
+
+// CreateBank creates a question bank. The X-User-ID user becomes its owner
+// when no owners are given.
+func CreateBank(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.BankRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ValidationErrorResponse(c, "Invalid request body")
+			return
+		}
+		req.Normalize()
+		if len(req.Owners) == 0 && currentUser(c) != "" {
+			req.Owners = []string{currentUser(c)}
+		}
+		if err := req.Validate(); err != nil {
+			utils.ValidationErrorResponse(c, err.Error())
+			return
+		}
+		if !slugAvailable(c, db, req.Slug, 0) {
+			return
+		}
+
+		var bank models.Bank
+		if err := req.ApplyTo(&bank); err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to encode bank")
+			return
+		}
+		if err := db.Create(&bank).Error; err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to create bank")
+			return
+		}
+
+		utils.CreatedResponse(c, bank.ToResponse(0), "Bank created successfully")
+	}
+}
+
+// UpdateBank replaces a question bank's metadata; only its owners may
+func UpdateBank(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		bank, ok := findBank(c, db)
+		if !ok || !requireBankOwner(c, &bank) {
+			return
+		}
+
+		var req models.BankRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ValidationErrorResponse(c, "Invalid request body")
+			return
+		}
+		req.Normalize()
+		if err := req.Validate(); err != nil {
+			utils.ValidationErrorResponse(c, err.Error())
+			return
+		}
+		if !slugAvailable(c, db, req.Slug, bank.ID) {
+			return
+		}
+
+		if err := req.ApplyTo(&bank); err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to encode bank")
+			return
+		}
+		if err := db.Save(&bank).Error; err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to update bank")
+			return
+		}
+
+		count, ok := publishedCount(c, db, &bank)
+		if !ok {
+			return
+		}
+		utils.SuccessResponse(c, bank.ToResponse(count), "Bank updated successfully")
+	}
+}
+
+// DeleteBank deletes an empty question bank; only its owners may, and the
+// default bank is kept
+func DeleteBank(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		bank, ok := findBank(c, db)
+		if !ok || !requireBankOwner(c, &bank) {
+			return
+		}
+		if bank.IsDefault {
+			utils.ErrorResponse(c, http.StatusConflict, "The default bank cannot be deleted")
+			return
+		}
+
+		// Trashed questions still belong to the bank until they are purged
+		var count int64
+		if err := db.Unscoped().Model(&models.Question{}).Scopes(models.InBank(bank.ID)).Count(&count).Error; err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to count questions")
+			return
+		}
+		if count > 0 {
+			utils.ErrorResponse(c, http.StatusConflict, "Bank still has questions")
+			return
+		}
+
+		if err := db.Delete(&bank).Error; err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to delete bank")
+			return
+		}
+		utils.SuccessResponse(c, gin.H{"slug": bank.Slug}, "Bank deleted successfully")
+	}
+}
+
+// findBank loads the bank named by the :bank path parameter, or the default
+// bank without one, writing the error response itself when it fails
+func findBank(c *gin.Context, db *gorm.DB) (models.Bank, bool) {
+	bank, err := models.FindBank(db, c.Param("bank"))
+	if err == gorm.ErrRecordNotFound {
+		utils.NotFoundResponse(c, "Bank not found")
+		return bank, false
+	}
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch bank")
+		return bank, false
+	}
+	return bank, true
+}
+
+// requireBankOwner refuses changes to a bank by anyone but its owners
+func requireBankOwner(c *gin.Context, bank *models.Bank) bool {
+	if bank.IsOwner(currentUser(c)) {
+		return true
+	}
+	utils.ErrorResponse(c, http.StatusForbidden, "Only the bank's owners may change it")
+	return false
+}
+
+// slugAvailable checks that no other bank uses slug
+func slugAvailable(c *gin.Context, db *gorm.DB, slug string, id uint) bool {
+	var count int64
+	if err := db.Model(&models.Bank{}).Where("slug = ? AND id <> ?", slug, id).Count(&count).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to check bank slug")
+		return false
+	}
+	if count > 0 {
+		utils.ErrorResponse(c, http.StatusConflict, "A bank with slug "+slug+" already exists")
+		return false
+	}
+	return true
+}
+
+// publishedCount counts the published questions in a bank
+func publishedCount(c *gin.Context, db *gorm.DB, bank *models.Bank) (int64, bool) {
+	var count int64
+	err := db.Model(&models.Question{}).Scopes(models.InBank(bank.ID)).
+		Where("questions.status = ?", models.StatusPublished).Count(&count).Error
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to count questions")
+		return 0, false
+	}
+	return count, true
+}
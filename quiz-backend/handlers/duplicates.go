@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"log"
+	"strconv"
+
+	"aws-rds-quiz-backend/models"
+	"aws-rds-quiz-backend/similarity"
+	"aws-rds-quiz-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// questionWithDuplicates is a saved question together with the existing
+// questions it closely resembles
+type questionWithDuplicates struct {
+	models.QuestionResponse
+	PossibleDuplicates []similarity.Match `json:"possibleDuplicates,omitempty"`
+}
+
+// GetDuplicateClusters groups the questions into clusters of likely
+// duplicates. It accepts the same filters as GetAllQuestions and an optional
+// similarity threshold between 0 and 1.
+func GetDuplicateClusters(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		threshold := similarity.DefaultThreshold
+		if value := c.Query("threshold"); value != "" {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil || parsed <= 0 || parsed > 1 {
+				utils.BadRequestResponse(c, "Invalid threshold parameter. Must be greater than 0 and at most 1")
+				return
+			}
+			threshold = parsed
+		}
+
+		query, ok := filterQuestions(c, db)
+		if !ok {
+			return
+		}
+
+		index, err := similarity.Bank(query)
+		if err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to compare questions")
+			return
+		}
+
+		utils.SuccessResponse(c, gin.H{
+			"threshold": threshold,
+			"clusters":  index.Clusters(threshold),
+		}, "Duplicate clusters retrieved successfully")
+	}
+}
+
+// withDuplicates flags the questions in the bank that question closely
+// resembles. Flagging is advisory, so a failure only drops the flags.
+func withDuplicates(db *gorm.DB, bank *models.Bank, question *models.Question, response models.QuestionResponse) questionWithDuplicates {
+	result := questionWithDuplicates{QuestionResponse: response}
+
+	req, err := question.ToRequest()
+	if err != nil {
+		return result
+	}
+	index, err := similarity.Bank(db.Scopes(models.InBank(bank.ID)))
+	if err != nil {
+		log.Printf("Warning: duplicate check for question %d failed: %v", question.ID, err)
+		return result
+	}
+	result.PossibleDuplicates = index.Similar(similarity.FromRequest(question.ID, 0, &req), similarity.DefaultThreshold)
+	return result
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"aws-rds-quiz-backend/config"
+	"aws-rds-quiz-backend/models"
+	"aws-rds-quiz-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// withholdKey is the context key ExamMode marks requests with
+const withholdKey = "withholdAnswers"
+
+// ExamMode makes the routes it is applied to serve questions without their
+// answers when the configuration withholds them
+func ExamMode(cfg config.ExamConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if cfg.WithholdAnswers {
+			c.Set(withholdKey, true)
+		}
+		c.Next()
+	}
+}
+
+// EditorsOnly restricts the authoring and administration routes it is applied
+// to, which return questions with their answers or could grant access to
+// them, to requests bearing the configured editor token while the
+// configuration withholds answers. X-User-ID is not authenticated, so it
+// cannot decide this. Without a token the routes are closed in exam mode.
+func EditorsOnly(cfg config.ExamConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if cfg.WithholdAnswers && !isEditor(c, cfg.EditorToken) {
+			utils.ErrorResponse(c, http.StatusForbidden, "Authoring routes need the editor token while answers are withheld")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// isEditor reports whether the request bears token as its bearer token
+func isEditor(c *gin.Context, token string) bool {
+	bearer, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	return ok && token != "" && subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) == 1
+}
+
+// withholding reports whether the request is served in exam mode
+func withholding(c *gin.Context) bool {
+	return c.GetBool(withholdKey)
+}
+
+// rejectWithheld refuses routes that would give the answers away while they
+// are withheld
+func rejectWithheld(c *gin.Context, message string) bool {
+	if !withholding(c) {
+		return false
+	}
+	utils.ErrorResponse(c, http.StatusForbidden, message)
+	return true
+}
+
+// withholdAnswers strips the answers from questions served in exam mode
+func withholdAnswers(responses []models.QuestionResponse) {
+	for i := range responses {
+		responses[i].WithholdAnswers()
+	}
+}
+
+// applyReviewPolicy leaves the graded answers out of a result of session
+// until its review policy reveals them
+func applyReviewPolicy(resp *models.QuizSubmissionResponse, session *models.QuizSession, now time.Time) {
+	reveal, after := session.Reveals(now)
+	if reveal {
+		return
+	}
+	resp.Answers = nil
+	resp.AnswersWithheld = true
+	resp.ReviewAfter = after
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"aws-rds-quiz-backend/config"
+	"aws-rds-quiz-backend/database"
+	"aws-rds-quiz-backend/i18n"
+	"aws-rds-quiz-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const editorToken = "editor-secret"
+
+// examRouter serves the routes exam mode guards, as main does, from a fresh
+// database holding the seed questions
+func examRouter(t *testing.T, exam config.ExamConfig) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	db, err := database.InitDB(config.DatabaseConfig{Driver: "sqlite", DBName: filepath.Join(t.TempDir(), "quiz.db"), LogLevel: "silent"})
+	if err != nil {
+		t.Fatalf("InitDB() error = %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	locales, err := i18n.NewLocales(config.TranslationsConfig{SourceLocale: "en"})
+	if err != nil {
+		t.Fatalf("NewLocales() error = %v", err)
+	}
+	templates := config.TemplatesConfig{VariantSecret: "variant-secret"}
+
+	r := gin.New()
+	v1 := r.Group("/api/v1")
+	editor := EditorsOnly(exam)
+	v1.PUT("/banks/:bank", editor, UpdateBank(db))
+	v1.GET("/admin/trash", editor, GetTrash(db, config.TrashConfig{RetentionDays: 30}))
+
+	g := v1.Group("", ResolveBank(db))
+	withhold := ExamMode(exam)
+	g.GET("/questions/export", editor, ExportQuestions(db, nil))
+	g.GET("/questions/:id", withhold, GetQuestionByID(db, locales, templates))
+	g.POST("/questions", editor, CreateQuestion(db))
+	g.POST("/quiz/sessions", withhold, CreateQuizSession(db, locales, exam))
+	g.POST("/quiz/sessions/:id/submit", SubmitQuizSession(db))
+	g.POST("/quiz/submit", withhold, SubmitQuiz(db, templates))
+	g.GET("/quiz/results/:id", GetQuizResult(db))
+	return r
+}
+
+// call sends a request with an optional JSON body and bearer token and
+// returns the status and the decoded response
+func call(r *gin.Engine, method, path, body, token string) (int, map[string]interface{}) {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(userHeader, "mallory")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var resp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w.Code, resp
+}
+
+func TestExamModeAccess(t *testing.T) {
+	examMode := config.ExamConfig{WithholdAnswers: true, DefaultReview: models.ReviewNever, EditorToken: editorToken}
+	tokenless := examMode
+	tokenless.EditorToken = ""
+	practice := config.ExamConfig{DefaultReview: models.ReviewImmediate}
+
+	newQuestion := `{"question": "Which port does MySQL use?", "options": ["3306", "5432"], "correctAnswer": 0, "category": "RDS"}`
+	tests := []struct {
+		name   string
+		exam   config.ExamConfig
+		method string
+		path   string
+		body   string
+		token  string
+		status int
+	}{
+		{"export without token", examMode, http.MethodGet, "/api/v1/questions/export?format=json", "", "", http.StatusForbidden},
+		{"export with wrong token", examMode, http.MethodGet, "/api/v1/questions/export?format=json", "", "guess", http.StatusForbidden},
+		{"export with token", examMode, http.MethodGet, "/api/v1/questions/export?format=json", "", editorToken, http.StatusOK},
+		{"export with no token configured", tokenless, http.MethodGet, "/api/v1/questions/export?format=json", "", "", http.StatusForbidden},
+		{"export outside exam mode", practice, http.MethodGet, "/api/v1/questions/export?format=json", "", "", http.StatusOK},
+		{"claim the default bank", examMode, http.MethodPut, "/api/v1/banks/default", `{"name": "Mine", "owners": ["mallory"]}`, "", http.StatusForbidden},
+		{"author a question", examMode, http.MethodPost, "/api/v1/questions", newQuestion, "", http.StatusForbidden},
+		{"author a question with token", examMode, http.MethodPost, "/api/v1/questions", newQuestion, editorToken, http.StatusCreated},
+		{"list the trash", examMode, http.MethodGet, "/api/v1/admin/trash", "", "", http.StatusForbidden},
+		{"submit without a session", examMode, http.MethodPost, "/api/v1/quiz/submit", `{"answers": {"1": 0}, "timeSpent": 1000}`, "", http.StatusForbidden},
+		{"submit without a session outside exam mode", practice, http.MethodPost, "/api/v1/quiz/submit", `{"answers": {"1": 0}, "timeSpent": 1000}`, "", http.StatusOK},
+		{"practice session", examMode, http.MethodPost, "/api/v1/quiz/sessions", `{"mode": "practice"}`, "", http.StatusForbidden},
+		{"revealing review policy", examMode, http.MethodPost, "/api/v1/quiz/sessions", `{"review": "immediate"}`, "", http.StatusForbidden},
+		{"exam session", examMode, http.MethodPost, "/api/v1/quiz/sessions", `{}`, "", http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := examRouter(t, tt.exam)
+			if status, resp := call(r, tt.method, tt.path, tt.body, tt.token); status != tt.status {
+				t.Errorf("%s %s = %d %v, want %d", tt.method, tt.path, status, resp["error"], tt.status)
+			}
+		})
+	}
+}
+
+func TestExamModeWithholdsAnswers(t *testing.T) {
+	r := examRouter(t, config.ExamConfig{WithholdAnswers: true, DefaultReview: models.ReviewNever, EditorToken: editorToken})
+
+	status, resp := call(r, http.MethodGet, "/api/v1/questions/1", "", "")
+	if status != http.StatusOK {
+		t.Fatalf("GET /questions/1 = %d", status)
+	}
+	question := resp["data"].(map[string]interface{})
+	if _, ok := question["correctAnswer"]; ok || question["answersWithheld"] != true {
+		t.Errorf("GET /questions/1 = %v, want the answer withheld", question)
+	}
+
+	status, resp = call(r, http.MethodPost, "/api/v1/quiz/sessions", `{"count": 2}`, "")
+	if status != http.StatusCreated {
+		t.Fatalf("POST /quiz/sessions = %d %v", status, resp["error"])
+	}
+	session := resp["data"].(map[string]interface{})
+	answers := map[string]int{}
+	for _, q := range session["questions"].([]interface{}) {
+		answers[fmt.Sprint(q.(map[string]interface{})["id"])] = 0
+	}
+	body, _ := json.Marshal(map[string]interface{}{"answers": answers})
+
+	status, resp = call(r, http.MethodPost, fmt.Sprintf("/api/v1/quiz/sessions/%v/submit", session["id"]), string(body), "")
+	if status != http.StatusOK {
+		t.Fatalf("POST /quiz/sessions/:id/submit = %d %v", status, resp["error"])
+	}
+	result := resp["data"].(map[string]interface{})
+	if result["answers"] != nil || result["answersWithheld"] != true {
+		t.Errorf("submission = %v, want the graded answers withheld", result)
+	}
+
+	status, resp = call(r, http.MethodGet, fmt.Sprintf("/api/v1/quiz/results/%v", result["id"]), "", "")
+	if status != http.StatusOK {
+		t.Fatalf("GET /quiz/results/:id = %d %v", status, resp["error"])
+	}
+	result = resp["data"].(map[string]interface{})
+	if result["answers"] != nil || result["answersWithheld"] != true {
+		t.Errorf("result = %v, want the graded answers withheld", result)
+	}
+}
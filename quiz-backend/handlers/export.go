@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"aws-rds-quiz-backend/attachments"
+	"aws-rds-quiz-backend/formats"
+	"aws-rds-quiz-backend/models"
+	"aws-rds-quiz-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ExportQuestions writes the question bank as a downloadable file in the
+// format given by the "format" query parameter (json, csv, yaml, gift,
+// moodlexml or qti). The question list filters apply. Attachments referenced
+// by the questions are embedded in the file.
+func ExportQuestions(db *gorm.DB, store *attachments.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		format := strings.ToLower(c.DefaultQuery("format", formats.FormatJSON))
+		if formats.ContentType(format) == "" {
+			utils.BadRequestResponse(c, "Unsupported export format: "+format)
+			return
+		}
+
+		query, ok := filterQuestions(c, db)
+		if !ok {
+			return
+		}
+		query = query.Scopes(models.WithTaxonomy).Order("category, id")
+		if category := c.Query("category"); category != "" {
+			query = query.Where("category = ?", category)
+		}
+
+		var questions []models.Question
+		if err := query.Find(&questions).Error; err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to fetch questions")
+			return
+		}
+
+		requests := make([]models.QuestionRequest, 0, len(questions))
+		for _, q := range questions {
+			req, err := q.ToRequest()
+			if err != nil {
+				utils.InternalServerErrorResponse(c, "Failed to parse question options")
+				return
+			}
+			requests = append(requests, req)
+		}
+
+		var buf bytes.Buffer
+		warnings, err := formats.Encode(format, &buf, requests, store.Loader(db))
+		if err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to export questions: "+err.Error())
+			return
+		}
+
+		// Lossy conversions are reported alongside the file itself
+		c.Header("X-Export-Warnings", strconv.Itoa(len(warnings)))
+		for _, warning := range warnings {
+			c.Writer.Header().Add("X-Export-Warning", warning)
+		}
+
+		c.Header("Content-Disposition", `attachment; filename="questions`+formats.Extension(format)+`"`)
+		c.Data(http.StatusOK, formats.ContentType(format), buf.Bytes())
+	}
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"aws-rds-quiz-backend/attachments"
+	"aws-rds-quiz-backend/formats"
+	"aws-rds-quiz-backend/importer"
+	"aws-rds-quiz-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxImportSize caps the size of an uploaded import file
+const maxImportSize = 10 << 20
+
+// ImportQuestions bulk-imports questions from an uploaded file. The file may
+// be sent as the multipart field "file" or as the raw request body; the
+// format comes from the "format" query parameter or the file extension.
+// Questions join the request's bank as drafts, and attachments embedded in
+// the file are added to store. Publishing on import is left to the command
+// line, so questions imported over HTTP always go through review.
+func ImportQuestions(db *gorm.DB, store *attachments.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+
+		body, filename, err := importSource(c)
+		if err != nil {
+			utils.BadRequestResponse(c, err.Error())
+			return
+		}
+		defer body.Close()
+
+		format := strings.ToLower(c.Query("format"))
+		if format == "" {
+			format = formats.FormatFromFilename(filename)
+		}
+		if format == "" {
+			utils.BadRequestResponse(c, "Unable to determine import format. Pass ?format=")
+			return
+		}
+
+		rows, err := formats.Decode(format, body)
+		if err != nil {
+			utils.ValidationErrorResponse(c, err.Error())
+			return
+		}
+
+		report, err := importer.Import(db, rows, importer.Options{
+			DryRun:  c.Query("dryRun") == "true",
+			Partial: c.Query("partial") == "true",
+			Author:  currentUser(c),
+			Bank:    currentBank(c),
+			Store:   store,
+		})
+		if err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to import questions: "+err.Error())
+			return
+		}
+
+		if report.Rejected > 0 && !report.Committed && c.Query("dryRun") != "true" {
+			utils.ErrorResponseWithData(c, http.StatusUnprocessableEntity, report,
+				fmt.Sprintf("Import aborted: %d rows rejected", report.Rejected))
+			return
+		}
+
+		utils.SuccessResponse(c, report, "Import processed successfully")
+	}
+}
+
+// importSource returns the uploaded file, falling back to the raw body
+func importSource(c *gin.Context) (io.ReadCloser, string, error) {
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		header, err := c.FormFile("file")
+		if err != nil {
+			return nil, "", fmt.Errorf("missing \"file\" field in upload")
+		}
+		file, err := header.Open()
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read uploaded file")
+		}
+		return file, header.Filename, nil
+	}
+
+	return c.Request.Body, "", nil
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"aws-rds-quiz-backend/config"
+	"aws-rds-quiz-backend/lint"
+	"aws-rds-quiz-backend/models"
+	"aws-rds-quiz-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// LintQuestions runs the question linter over the bank. It accepts the same
+// filters as GetAllQuestions; "rule" restricts the run to the named rules and
+// "disable" turns rules off, both repeatable.
+func LintQuestions(db *gorm.DB, cfg config.LintConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query, ok := filterQuestions(c, db)
+		if !ok {
+			return
+		}
+
+		var questions []models.Question
+		if err := query.Order("questions.id").Find(&questions).Error; err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to fetch questions")
+			return
+		}
+
+		cfg.Disabled = append(append([]string{}, cfg.Disabled...), c.QueryArray("disable")...)
+		report, err := lint.Run(questions, cfg, c.QueryArray("rule"))
+		if err != nil {
+			utils.BadRequestResponse(c, err.Error())
+			return
+		}
+
+		utils.SuccessResponse(c, report, "Lint completed successfully")
+	}
+}
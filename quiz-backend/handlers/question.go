@@ -1,11 +1,13 @@
 package handlers
 
 import (
-	"encoding/json"
 	"math/rand"
+	"net/http"
 	"strconv"
 	"time"
 
+	"aws-rds-quiz-backend/config"
+	"aws-rds-quiz-backend/i18n"
 	"aws-rds-quiz-backend/models"
 	"aws-rds-quiz-backend/utils"
 
@@ -13,12 +15,20 @@ import (
 	"gorm.io/gorm"
 )
 
-// GetAllQuestions returns all questions
-func GetAllQuestions(db *gorm.DB) gin.HandlerFunc {
+// GetAllQuestions returns all published questions, optionally filtered by
+// tag, topic subtree and exam domain, in the negotiated language. In exam
+// mode the answers are withheld, as by every route serving questions to
+// learners.
+func GetAllQuestions(db *gorm.DB, locales *i18n.Locales) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var questions []models.Question
+		query, ok := filterQuestions(c, db)
+		if !ok {
+			return
+		}
+		query = query.Where("questions.status = ?", models.StatusPublished)
 
-		if err := db.Find(&questions).Error; err != nil {
+		var questions []models.Question
+		if err := query.Scopes(models.WithTaxonomy).Find(&questions).Error; err != nil {
 			utils.InternalServerErrorResponse(c, "Failed to fetch questions")
 			return
 		}
@@ -26,116 +36,350 @@ func GetAllQuestions(db *gorm.DB) gin.HandlerFunc {
 		// Convert to response format
 		var responses []models.QuestionResponse
 		for _, q := range questions {
-			var options []string
-			if err := json.Unmarshal([]byte(q.Options), &options); err != nil {
+			response, err := q.ToResponse()
+			if err != nil {
 				utils.InternalServerErrorResponse(c, "Failed to parse question options")
 				return
 			}
 
-			responses = append(responses, models.QuestionResponse{
-				ID:            q.ID,
-				Question:      q.Question,
-				Options:       options,
-				CorrectAnswer: q.CorrectAnswer,
-				Explanation:   q.Explanation,
-				Category:      q.Category,
-				Difficulty:    q.Difficulty,
-			})
+			responses = append(responses, response)
+		}
+		if !localize(c, db, locales, responses) {
+			return
+		}
+		if withholding(c) {
+			withholdAnswers(responses)
 		}
 
 		utils.SuccessResponse(c, responses, "Questions retrieved successfully")
 	}
 }
 
-// GetRandomQuestions returns random questions
-func GetRandomQuestions(db *gorm.DB) gin.HandlerFunc {
+// GetRandomQuestions returns random published questions, accepting the same
+// filters and languages as GetAllQuestions
+func GetRandomQuestions(db *gorm.DB, locales *i18n.Locales, cfg config.TemplatesConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		countStr := c.DefaultQuery("count", "10")
-		count, err := strconv.Atoi(countStr)
-		if err != nil || count <= 0 || count > 50 {
-			utils.BadRequestResponse(c, "Invalid count parameter. Must be between 1 and 50")
+		responses, ok := randomQuestions(c, db, locales)
+		if !ok {
 			return
 		}
+		signServedVariants(cfg, responses)
+		if withholding(c) {
+			withholdAnswers(responses)
+		}
 
-		var questions []models.Question
-		if err := db.Find(&questions).Error; err != nil {
-			utils.InternalServerErrorResponse(c, "Failed to fetch questions")
-			return
+		utils.SuccessResponse(c, responses, "Random questions retrieved successfully")
+	}
+}
+
+// randomQuestions picks up to ?count= random published questions matching the
+// request's filters and prepares them for serving, writing the error response
+// itself when that fails
+func randomQuestions(c *gin.Context, db *gorm.DB, locales *i18n.Locales) ([]models.QuestionResponse, bool) {
+	countStr := c.DefaultQuery("count", "10")
+	count, err := strconv.Atoi(countStr)
+	if err != nil || count <= 0 || count > 50 {
+		utils.BadRequestResponse(c, "Invalid count parameter. Must be between 1 and 50")
+		return nil, false
+	}
+
+	query, ok := filterQuestions(c, db)
+	if !ok {
+		return nil, false
+	}
+	query = query.Where("questions.status = ?", models.StatusPublished)
+
+	var questions []models.Question
+	if err := query.Scopes(models.WithTaxonomy).Find(&questions).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch questions")
+		return nil, false
+	}
+
+	// Shuffle questions
+	rand.Seed(time.Now().UnixNano())
+	rand.Shuffle(len(questions), func(i, j int) {
+		questions[i], questions[j] = questions[j], questions[i]
+	})
+
+	// Limit to requested count
+	if count > len(questions) {
+		count = len(questions)
+	}
+	questions = questions[:count]
+
+	// Convert to response format
+	var responses []models.QuestionResponse
+	for _, q := range questions {
+		response, err := q.ToResponse()
+		if err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to parse question options")
+			return nil, false
 		}
 
-		// Shuffle questions
-		rand.Seed(time.Now().UnixNano())
-		rand.Shuffle(len(questions), func(i, j int) {
-			questions[i], questions[j] = questions[j], questions[i]
-		})
+		responses = append(responses, response)
+	}
+	if !localize(c, db, locales, responses) {
+		return nil, false
+	}
 
-		// Limit to requested count
-		if count > len(questions) {
-			count = len(questions)
+	// Serve a random variant of each template
+	for i := range responses {
+		if len(responses[i].Variants) == 0 {
+			continue
+		}
+		if err := responses[i].Instantiate(rand.Intn(len(responses[i].Variants))); err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to instantiate question template")
+			return nil, false
 		}
-		questions = questions[:count]
+	}
+	return responses, true
+}
 
-		// Convert to response format
-		var responses []models.QuestionResponse
-		for _, q := range questions {
-			var options []string
-			if err := json.Unmarshal([]byte(q.Options), &options); err != nil {
-				utils.InternalServerErrorResponse(c, "Failed to parse question options")
+// signServedVariants sets the servedVariant of every instantiated template
+// among responses, so the submission names a variant it was served rather
+// than a variant index of its choosing
+func signServedVariants(cfg config.TemplatesConfig, responses []models.QuestionResponse) {
+	for i := range responses {
+		if responses[i].Variant != nil {
+			responses[i].ServedVariant = models.SignVariant([]byte(cfg.VariantSecret), responses[i].ID, *responses[i].Variant)
+		}
+	}
+}
+
+// GetQuestionByID returns a specific published question by ID in the
+// negotiated language. A template question is returned as is, or as one of
+// its variants with ?variant=.
+func GetQuestionByID(db *gorm.DB, locales *i18n.Locales, cfg config.TemplatesConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		variant := -1
+		if variantStr := c.Query("variant"); variantStr != "" {
+			var err error
+			variant, err = strconv.Atoi(variantStr)
+			if err != nil || variant < 0 {
+				utils.BadRequestResponse(c, "Invalid variant parameter")
 				return
 			}
+		}
 
-			responses = append(responses, models.QuestionResponse{
-				ID:            q.ID,
-				Question:      q.Question,
-				Options:       options,
-				CorrectAnswer: q.CorrectAnswer,
-				Explanation:   q.Explanation,
-				Category:      q.Category,
-				Difficulty:    q.Difficulty,
-			})
+		question, ok := findPublishedQuestion(c, db)
+		if !ok {
+			return
 		}
 
-		utils.SuccessResponse(c, responses, "Random questions retrieved successfully")
+		// Convert to response format
+		response, err := question.ToResponse()
+		if err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to parse question options")
+			return
+		}
+		responses := []models.QuestionResponse{response}
+		if !localize(c, db, locales, responses) {
+			return
+		}
+		if variant >= 0 {
+			if !question.IsTemplate() {
+				utils.BadRequestResponse(c, "Question is not a template")
+				return
+			}
+			if err := responses[0].Instantiate(variant); err != nil {
+				utils.BadRequestResponse(c, "Invalid variant parameter: "+err.Error())
+				return
+			}
+			signServedVariants(cfg, responses)
+		}
+		if withholding(c) {
+			withholdAnswers(responses)
+		}
+
+		utils.SuccessResponse(c, responses[0], "Question retrieved successfully")
 	}
 }
 
-// GetQuestionByID returns a specific question by ID
-func GetQuestionByID(db *gorm.DB) gin.HandlerFunc {
+// CreateQuestion creates a draft question in the request's bank authored by
+// the X-User-ID user, flagging existing questions it closely resembles
+func CreateQuestion(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		idStr := c.Param("id")
-		id, err := strconv.ParseUint(idStr, 10, 32)
+		var req models.QuestionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ValidationErrorResponse(c, "Invalid request body")
+			return
+		}
+
+		bank := currentBank(c)
+		req.Normalize()
+		if err := bank.Check(&req); err != nil {
+			utils.ValidationErrorResponse(c, err.Error())
+			return
+		}
+		if !checkAttachments(c, db, &req) {
+			return
+		}
+
+		question := models.Question{BankID: bank.ID, Source: models.SourceAPI, Status: models.StatusDraft, Author: currentUser(c)}
+		if err := req.ApplyTo(&question); err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to encode question options")
+			return
+		}
+
+		if err := db.Create(&question).Error; err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to create question")
+			return
+		}
+
+		response, err := question.ToResponse()
 		if err != nil {
-			utils.BadRequestResponse(c, "Invalid question ID")
+			utils.InternalServerErrorResponse(c, "Failed to parse question options")
 			return
 		}
 
-		var question models.Question
-		if err := db.First(&question, id).Error; err != nil {
-			if err == gorm.ErrRecordNotFound {
-				utils.NotFoundResponse(c, "Question not found")
-				return
-			}
-			utils.InternalServerErrorResponse(c, "Failed to fetch question")
+		utils.CreatedResponse(c, withDuplicates(db, bank, &question, response), "Question created successfully")
+	}
+}
+
+// UpdateQuestion replaces all fields of an existing question
+func UpdateQuestion(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		question, ok := findQuestion(c, db)
+		if !ok {
 			return
 		}
 
-		// Convert to response format
-		var options []string
-		if err := json.Unmarshal([]byte(question.Options), &options); err != nil {
+		var req models.QuestionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ValidationErrorResponse(c, "Invalid request body")
+			return
+		}
+
+		saveQuestion(c, db, &question, &req)
+	}
+}
+
+// PatchQuestion updates only the fields present in the request body
+func PatchQuestion(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		question, ok := findQuestion(c, db)
+		if !ok {
+			return
+		}
+
+		var patch models.QuestionPatchRequest
+		if err := c.ShouldBindJSON(&patch); err != nil {
+			utils.ValidationErrorResponse(c, "Invalid request body")
+			return
+		}
+
+		req, err := question.ToRequest()
+		if err != nil {
 			utils.InternalServerErrorResponse(c, "Failed to parse question options")
 			return
 		}
+		patch.ApplyTo(&req)
+
+		saveQuestion(c, db, &question, &req)
+	}
+}
+
+// DeleteQuestion soft-deletes a question
+func DeleteQuestion(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		question, ok := findQuestion(c, db)
+		if !ok || rejectFileBacked(c, &question) {
+			return
+		}
+
+		if err := db.Delete(&question).Error; err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to delete question")
+			return
+		}
+
+		utils.SuccessResponse(c, gin.H{"id": question.ID}, "Question deleted successfully")
+	}
+}
+
+// findQuestion loads the question named by the :id path parameter, writing
+// the error response itself when the lookup fails
+func findQuestion(c *gin.Context, db *gorm.DB) (models.Question, bool) {
+	var question models.Question
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		utils.BadRequestResponse(c, "Invalid question ID")
+		return question, false
+	}
+
+	if err := db.Scopes(models.WithTaxonomy, models.InBank(currentBank(c).ID)).First(&question, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.NotFoundResponse(c, "Question not found")
+			return question, false
+		}
+		utils.InternalServerErrorResponse(c, "Failed to fetch question")
+		return question, false
+	}
+
+	return question, true
+}
+
+// findPublishedQuestion loads the question named by the :id path parameter
+// like findQuestion, treating drafts, questions in review and retired
+// questions as not found, since learners are only served published ones
+func findPublishedQuestion(c *gin.Context, db *gorm.DB) (models.Question, bool) {
+	return findQuestion(c, db.Where("questions.status = ?", models.StatusPublished))
+}
+
+// rejectFileBacked refuses to modify questions owned by the Markdown question
+// directory, since the next sync would overwrite the change
+func rejectFileBacked(c *gin.Context, question *models.Question) bool {
+	if question.Source != models.SourceMarkdown {
+		return false
+	}
+	utils.ErrorResponse(c, http.StatusConflict, "Question is managed by a Markdown file; edit the file instead")
+	return true
+}
+
+// saveQuestion validates req, applies it to question and persists the result.
+// An edited published question goes back into review and stops being served
+// until it is approved again.
+func saveQuestion(c *gin.Context, db *gorm.DB, question *models.Question, req *models.QuestionRequest) {
+	if rejectFileBacked(c, question) {
+		return
+	}
 
-		response := models.QuestionResponse{
-			ID:            question.ID,
-			Question:      question.Question,
-			Options:       options,
-			CorrectAnswer: question.CorrectAnswer,
-			Explanation:   question.Explanation,
-			Category:      question.Category,
-			Difficulty:    question.Difficulty,
+	bank := currentBank(c)
+	req.Normalize()
+	if err := bank.Check(req); err != nil {
+		utils.ValidationErrorResponse(c, err.Error())
+		return
+	}
+	if !checkAttachments(c, db, req) {
+		return
+	}
+
+	if err := req.ApplyTo(question); err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to encode question options")
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		entry, edited := question.Edited(currentUser(c))
+		if err := tx.Save(question).Error; err != nil {
+			return err
+		}
+		if edited {
+			return tx.Create(&entry).Error
 		}
+		return nil
+	})
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to update question")
+		return
+	}
 
-		utils.SuccessResponse(c, response, "Question retrieved successfully")
+	response, err := question.ToResponse()
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to parse question options")
+		return
 	}
+
+	utils.SuccessResponse(c, withDuplicates(db, bank, question, response), "Question updated successfully")
 }
@@ -2,8 +2,15 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
 	"strconv"
+	"time"
 
+	"aws-rds-quiz-backend/config"
+	"aws-rds-quiz-backend/grading"
 	"aws-rds-quiz-backend/models"
 	"aws-rds-quiz-backend/utils"
 
@@ -11,98 +18,66 @@ import (
 	"gorm.io/gorm"
 )
 
-// SubmitQuiz handles quiz submission, scoring, and result storage
-func SubmitQuiz(db *gorm.DB) gin.HandlerFunc {
+// SubmitQuiz handles quiz submission, scoring, and result storage. Every
+// answered question must be published in the request's bank. In exam mode
+// answers are only accepted against a quiz session, as grading arbitrary
+// answers would reveal the correct ones. Submitting without a session is
+// deprecated, which every response says in its Deprecation and Warning headers.
+func SubmitQuiz(db *gorm.DB, cfg config.TemplatesConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		c.Header("Deprecation", "true")
+		c.Header("Warning", `299 - "Submitting without a quiz session is deprecated; use /quiz/sessions"`)
+		if rejectWithheld(c, "Submit answers to a quiz session while answers are withheld") {
+			return
+		}
+
 		var req models.QuizSubmissionRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
 			utils.ValidationErrorResponse(c, "Invalid request body")
 			return
 		}
 
-		// Fetch all questions
-		var questions []models.Question
-		if err := db.Find(&questions).Error; err != nil {
-			utils.InternalServerErrorResponse(c, "Failed to fetch questions")
+		bank := currentBank(c)
+		if id, err := foreignQuestion(db, bank, req.Answers); err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to check questions")
+			return
+		} else if id != 0 {
+			utils.ValidationErrorResponse(c, fmt.Sprintf("Question %d is not in the %s bank", id, bank.Slug))
+			return
+		}
+		if id, err := unpublishedQuestion(db, bank, req.Answers); err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to check questions")
+			return
+		} else if id != 0 {
+			utils.ValidationErrorResponse(c, fmt.Sprintf("Question %d is not published", id))
+			return
+		}
+
+		variants, ok := servedVariants(c, cfg, req.ServedVariants)
+		if !ok {
 			return
 		}
 
 		// Calculate score and build answer details
-		var (
-			score         int
-			answerDetails []models.QuizAnswerDetail
-		)
-		total := len(req.Answers)
-		for qidStr, userAnswer := range req.Answers {
-			qid, err := strconv.ParseUint(qidStr, 10, 32)
-			if err != nil {
-				continue
-			}
-			var question models.Question
-			if err := db.First(&question, qid).Error; err != nil {
-				continue
-			}
-			var options []string
-			_ = json.Unmarshal([]byte(question.Options), &options)
-			isCorrect := userAnswer == question.CorrectAnswer
-			if isCorrect {
-				score++
-			}
-			answerDetails = append(answerDetails, models.QuizAnswerDetail{
-				QuestionID:    question.ID,
-				UserAnswer:    userAnswer,
-				CorrectAnswer: question.CorrectAnswer,
-				IsCorrect:     isCorrect,
-				Question:      question.Question,
-				SelectedOption: func() string {
-					if userAnswer >= 0 && userAnswer < len(options) {
-						return options[userAnswer]
-					}
-					return ""
-				}(),
-				CorrectOption: func() string {
-					if question.CorrectAnswer >= 0 && question.CorrectAnswer < len(options) {
-						return options[question.CorrectAnswer]
-					}
-					return ""
-				}(),
-			})
-		}
-
-		percentage := 0.0
-		if total > 0 {
-			percentage = float64(score) / float64(total) * 100
-		}
-
-		answersJSON, _ := json.Marshal(req.Answers)
-		quiz := models.QuizSubmission{
-			UserID:     req.UserID,
-			Answers:    string(answersJSON),
-			TimeSpent:  req.TimeSpent,
-			Score:      score,
-			Total:      total,
-			Percentage: percentage,
+		answerDetails, err := gradeAnswers(db, req.Answers, req.Revisions, variants, true)
+		if err != nil {
+			utils.ValidationErrorResponse(c, "Invalid answer for "+err.Error())
+			return
 		}
+
+		quiz := newSubmission(bank, req.UserID, req.Answers, answerDetails, len(req.Answers), req.TimeSpent)
 		if err := db.Create(&quiz).Error; err != nil {
 			utils.InternalServerErrorResponse(c, "Failed to save quiz submission")
 			return
 		}
 
-		resp := models.QuizSubmissionResponse{
-			ID:         quiz.ID,
-			UserID:     quiz.UserID,
-			Score:      quiz.Score,
-			Total:      quiz.Total,
-			Percentage: quiz.Percentage,
-			TimeSpent:  quiz.TimeSpent,
-			Answers:    answerDetails,
-			CreatedAt:  quiz.CreatedAt,
-		}
+		resp := submissionResponse(&quiz, answerDetails)
 		utils.SuccessResponse(c, resp, "Quiz submitted successfully")
 	}
 }
 
-// GetQuizResult returns a quiz result by submission ID
+// GetQuizResult returns a quiz result by submission ID. The graded answers of
+// a session's result are left out until its review policy reveals them.
 func GetQuizResult(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		idStr := c.Param("id")
@@ -112,7 +87,7 @@ func GetQuizResult(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 		var quiz models.QuizSubmission
-		if err := db.First(&quiz, id).Error; err != nil {
+		if err := db.Where("bank_id = ?", currentBank(c).ID).First(&quiz, id).Error; err != nil {
 			if err == gorm.ErrRecordNotFound {
 				utils.NotFoundResponse(c, "Quiz result not found")
 				return
@@ -121,52 +96,314 @@ func GetQuizResult(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 		// Parse answers
-		var answers map[string]int
+		var answers map[string]json.RawMessage
 		_ = json.Unmarshal([]byte(quiz.Answers), &answers)
-		// Fetch all questions for details
 		var answerDetails []models.QuizAnswerDetail
-		for qidStr, userAnswer := range answers {
-			qid, err := strconv.ParseUint(qidStr, 10, 32)
-			if err != nil {
+		if quiz.Details != "" {
+			_ = json.Unmarshal([]byte(quiz.Details), &answerDetails)
+		} else {
+			answerDetails = regradeSubmission(db, &quiz, answers)
+		}
+		resp := submissionResponse(&quiz, answerDetails)
+		if quiz.SessionID != nil {
+			var session models.QuizSession
+			err := db.First(&session, *quiz.SessionID).Error
+			switch {
+			case err == gorm.ErrRecordNotFound:
+				// Without its session the review policy is unknown, so the
+				// answers stay withheld
+				resp.Answers = nil
+				resp.AnswersWithheld = true
+			case err != nil:
+				utils.InternalServerErrorResponse(c, "Failed to fetch quiz session")
+				return
+			default:
+				resp.Unanswered = session.Unanswered(answers)
+				applyReviewPolicy(&resp, &session, time.Now())
+			}
+		}
+		utils.SuccessResponse(c, resp, "Quiz result retrieved successfully")
+	}
+}
+
+// DeleteQuizResult moves a quiz submission to the trash
+func DeleteQuizResult(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			utils.BadRequestResponse(c, "Invalid quiz result ID")
+			return
+		}
+
+		result := db.Where("bank_id = ?", currentBank(c).ID).Delete(&models.QuizSubmission{}, id)
+		if result.Error != nil {
+			utils.InternalServerErrorResponse(c, "Failed to delete quiz result")
+			return
+		}
+		if result.RowsAffected == 0 {
+			utils.NotFoundResponse(c, "Quiz result not found")
+			return
+		}
+
+		utils.SuccessResponse(c, gin.H{"id": id}, "Quiz result deleted successfully")
+	}
+}
+
+// regradeSubmission rebuilds the answer details of a submission stored before
+// they were kept, and keeps them so it is graded only once
+func regradeSubmission(db *gorm.DB, quiz *models.QuizSubmission, answers map[string]json.RawMessage) []models.QuizAnswerDetail {
+	// Submissions made before revisions were tracked have none recorded
+	// and are graded against the current question
+	var revisions map[string]int
+	_ = json.Unmarshal([]byte(quiz.Revisions), &revisions)
+	var variants map[string]int
+	_ = json.Unmarshal([]byte(quiz.Variants), &variants)
+	// Stored answers are never rejected, and questions moved to the trash
+	// since are still graded
+	details, _ := gradeAnswers(db.Unscoped().Session(&gorm.Session{}), answers, revisions, variants, false)
+	if detailsJSON, err := json.Marshal(details); err == nil {
+		if err := db.Model(quiz).UpdateColumn("details", string(detailsJSON)).Error; err != nil {
+			log.Printf("Failed to store details of quiz result %d: %v", quiz.ID, err)
+		}
+	}
+	return details
+}
+
+// newSubmission scores graded answers out of total questions for storing
+func newSubmission(bank *models.Bank, userID string, answers map[string]json.RawMessage, details []models.QuizAnswerDetail, total int, timeSpent int64) models.QuizSubmission {
+	var score float64
+	for _, detail := range details {
+		score += detail.Credit
+	}
+	percentage := 0.0
+	if total > 0 {
+		percentage = score / float64(total) * 100
+	}
+
+	answersJSON, _ := json.Marshal(answers)
+	revisionsJSON, _ := json.Marshal(gradedRevisions(details))
+	variantsJSON, _ := json.Marshal(gradedVariants(details))
+	detailsJSON, _ := json.Marshal(details)
+	return models.QuizSubmission{
+		BankID:     bank.ID,
+		UserID:     userID,
+		Answers:    string(answersJSON),
+		Revisions:  string(revisionsJSON),
+		Variants:   string(variantsJSON),
+		Details:    string(detailsJSON),
+		TimeSpent:  timeSpent,
+		Score:      score,
+		Total:      total,
+		Percentage: percentage,
+	}
+}
+
+// submissionResponse converts a stored submission and its graded answers
+// into the API response format
+func submissionResponse(quiz *models.QuizSubmission, details []models.QuizAnswerDetail) models.QuizSubmissionResponse {
+	return models.QuizSubmissionResponse{
+		ID:         quiz.ID,
+		UserID:     quiz.UserID,
+		SessionID:  quiz.SessionID,
+		Score:      quiz.Score,
+		Total:      quiz.Total,
+		Percentage: quiz.Percentage,
+		TimeSpent:  quiz.TimeSpent,
+		Answers:    details,
+		CreatedAt:  quiz.CreatedAt,
+	}
+}
+
+// gradeAnswers grades every answer against its question, at the given
+// revision when there is one, and returns the details ordered by question ID.
+// Template questions are graded against the variant that was served. With
+// strict set, an invalid or unknown question ID, an answer whose shape does
+// not match its question type, an unknown revision or a missing or unknown
+// variant is an error; otherwise the answer is graded as wrong, or left
+// ungraded when its variant is not known, and a question that no longer
+// exists is graded against its latest revision, or left ungraded when none is
+// left.
+func gradeAnswers(db *gorm.DB, answers map[string]json.RawMessage, revisions, variants map[string]int, strict bool) ([]models.QuizAnswerDetail, error) {
+	var answerDetails []models.QuizAnswerDetail
+	for qidStr, answer := range answers {
+		qid, err := strconv.ParseUint(qidStr, 10, 32)
+		if err != nil {
+			if strict {
+				return nil, fmt.Errorf("question %q: invalid question ID", qidStr)
+			}
+			answerDetails = append(answerDetails, ungradedAnswer(0, fmt.Sprintf("invalid question ID %q", qidStr)))
+			continue
+		}
+		question, err := questionAt(db, uint(qid), revisions[qidStr])
+		if err == errUnknownRevision && strict {
+			return nil, fmt.Errorf("question %d: unknown revision %d", qid, revisions[qidStr])
+		}
+		if err == gorm.ErrRecordNotFound && !strict {
+			question, err = latestRevision(db, uint(qid))
+		}
+		if err == gorm.ErrRecordNotFound {
+			if strict {
+				return nil, fmt.Errorf("question %d: question not found", qid)
+			}
+			answerDetails = append(answerDetails, ungradedAnswer(uint(qid), "question no longer exists"))
+			continue
+		}
+		if err != nil {
+			if strict {
+				return nil, fmt.Errorf("question %d: %v", qid, err)
+			}
+			answerDetails = append(answerDetails, ungradedAnswer(uint(qid), "failed to load question"))
+			continue
+		}
+		var variant *int
+		if question.IsTemplate() {
+			index, ok := variants[qidStr]
+			if !ok {
+				if strict {
+					return nil, fmt.Errorf("question %d: template question needs the variant served", qid)
+				}
+				answerDetails = append(answerDetails, ungradedAnswer(uint(qid), "variant served is not known"))
 				continue
 			}
-			var question models.Question
-			if err := db.First(&question, qid).Error; err != nil {
+			instance, err := question.Variant(index)
+			if err != nil {
+				if strict {
+					return nil, fmt.Errorf("question %d: %v", qid, err)
+				}
+				answerDetails = append(answerDetails, ungradedAnswer(uint(qid), fmt.Sprintf("variant %d: %v", index, err)))
 				continue
 			}
-			var options []string
-			_ = json.Unmarshal([]byte(question.Options), &options)
-			isCorrect := userAnswer == question.CorrectAnswer
-			answerDetails = append(answerDetails, models.QuizAnswerDetail{
-				QuestionID:    question.ID,
-				UserAnswer:    userAnswer,
-				CorrectAnswer: question.CorrectAnswer,
-				IsCorrect:     isCorrect,
-				Question:      question.Question,
-				SelectedOption: func() string {
-					if userAnswer >= 0 && userAnswer < len(options) {
-						return options[userAnswer]
-					}
-					return ""
-				}(),
-				CorrectOption: func() string {
-					if question.CorrectAnswer >= 0 && question.CorrectAnswer < len(options) {
-						return options[question.CorrectAnswer]
-					}
-					return ""
-				}(),
-			})
-		}
-		resp := models.QuizSubmissionResponse{
-			ID:         quiz.ID,
-			UserID:     quiz.UserID,
-			Score:      quiz.Score,
-			Total:      quiz.Total,
-			Percentage: quiz.Percentage,
-			TimeSpent:  quiz.TimeSpent,
-			Answers:    answerDetails,
-			CreatedAt:  quiz.CreatedAt,
+			question = instance
+			variant = &index
+		}
+		detail, err := grading.Grade(&question, answer)
+		if err != nil && strict {
+			return nil, fmt.Errorf("question %d: %v", question.ID, err)
+		}
+		detail.Variant = variant
+		answerDetails = append(answerDetails, detail)
+	}
+
+	sort.Slice(answerDetails, func(i, j int) bool {
+		return answerDetails[i].QuestionID < answerDetails[j].QuestionID
+	})
+	return answerDetails, nil
+}
+
+// servedVariants resolves the servedVariant named for each question to the
+// variant index it was served, writing the error response itself when one is
+// not a token of a variant served of that question
+func servedVariants(c *gin.Context, cfg config.TemplatesConfig, served map[string]string) (map[string]int, bool) {
+	variants := make(map[string]int, len(served))
+	for qidStr, token := range served {
+		qid, err := strconv.ParseUint(qidStr, 10, 32)
+		variant, ok := models.VerifyVariant([]byte(cfg.VariantSecret), uint(qid), token)
+		if err != nil || !ok {
+			utils.ValidationErrorResponse(c, fmt.Sprintf("Served variant %q was not served for question %s", token, qidStr))
+			return nil, false
+		}
+		variants[qidStr] = variant
+	}
+	return variants, true
+}
+
+// ungradedAnswer is the detail of an answer that could not be graded
+func ungradedAnswer(qid uint, reason string) models.QuizAnswerDetail {
+	return models.QuizAnswerDetail{QuestionID: qid, UserAnswer: -1, CorrectAnswer: -1, Ungraded: reason}
+}
+
+var errUnknownRevision = errors.New("unknown revision")
+
+// questionAt loads a question as it was at revision, or its current version
+// when revision is 0
+func questionAt(db *gorm.DB, id uint, revision int) (models.Question, error) {
+	if revision == 0 {
+		var question models.Question
+		err := db.First(&question, id).Error
+		return question, err
+	}
+
+	var snapshot models.QuestionRevision
+	err := db.Where("question_id = ? AND revision = ?", id, revision).First(&snapshot).Error
+	if err == gorm.ErrRecordNotFound {
+		return models.Question{}, errUnknownRevision
+	}
+	if err != nil {
+		return models.Question{}, err
+	}
+	return snapshot.ToQuestion(), nil
+}
+
+// foreignQuestion returns the ID of an answered question that exists in
+// another bank, or 0 when there is none
+func foreignQuestion(db *gorm.DB, bank *models.Bank, answers map[string]json.RawMessage) (uint, error) {
+	var ids []uint
+	for qidStr := range answers {
+		if qid, err := strconv.ParseUint(qidStr, 10, 32); err == nil {
+			ids = append(ids, uint(qid))
+		}
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	var foreign []uint
+	err := db.Model(&models.Question{}).Where("id IN ? AND bank_id <> ?", ids, bank.ID).Order("id").Limit(1).Pluck("id", &foreign).Error
+	if err != nil || len(foreign) == 0 {
+		return 0, err
+	}
+	return foreign[0], nil
+}
+
+// unpublishedQuestion returns the ID of an answered question of the bank that
+// is not published, or 0 when there is none
+func unpublishedQuestion(db *gorm.DB, bank *models.Bank, answers map[string]json.RawMessage) (uint, error) {
+	var ids []uint
+	for qidStr := range answers {
+		if qid, err := strconv.ParseUint(qidStr, 10, 32); err == nil {
+			ids = append(ids, uint(qid))
+		}
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	var unpublished []uint
+	err := db.Model(&models.Question{}).Where("id IN ? AND bank_id = ? AND status <> ?", ids, bank.ID, models.StatusPublished).Order("id").Limit(1).Pluck("id", &unpublished).Error
+	if err != nil || len(unpublished) == 0 {
+		return 0, err
+	}
+	return unpublished[0], nil
+}
+
+// latestRevision loads the last recorded version of a question, which outlives
+// the question when it is purged from the trash
+func latestRevision(db *gorm.DB, id uint) (models.Question, error) {
+	var snapshot models.QuestionRevision
+	if err := db.Where("question_id = ?", id).Order("revision DESC").First(&snapshot).Error; err != nil {
+		return models.Question{}, err
+	}
+	return snapshot.ToQuestion(), nil
+}
+
+// gradedRevisions maps each graded question ID to the revision it was graded
+// against, for storing with the submission
+func gradedRevisions(details []models.QuizAnswerDetail) map[string]int {
+	revisions := make(map[string]int, len(details))
+	for _, detail := range details {
+		revisions[strconv.FormatUint(uint64(detail.QuestionID), 10)] = detail.Revision
+	}
+	return revisions
+}
+
+// gradedVariants maps each graded template question ID to the variant it was
+// graded against, for storing with the submission
+func gradedVariants(details []models.QuizAnswerDetail) map[string]int {
+	variants := make(map[string]int)
+	for _, detail := range details {
+		if detail.Variant != nil {
+			variants[strconv.FormatUint(uint64(detail.QuestionID), 10)] = *detail.Variant
 		}
-		utils.SuccessResponse(c, resp, "Quiz result retrieved successfully")
 	}
+	return variants
 }
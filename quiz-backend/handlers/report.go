@@ -0,0 +1,301 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"aws-rds-quiz-backend/models"
+	"aws-rds-quiz-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ReportQuestion records a learner's report that a published question is
+// wrong, outdated or ambiguous, optionally tied to the submission it was seen
+// in
+func ReportQuestion(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		question, ok := findPublishedQuestion(c, db)
+		if !ok {
+			return
+		}
+
+		var req models.QuestionReportRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ValidationErrorResponse(c, "Invalid request body")
+			return
+		}
+		req.Normalize()
+		if err := req.Validate(); err != nil {
+			utils.ValidationErrorResponse(c, err.Error())
+			return
+		}
+
+		report := models.QuestionReport{
+			BankID:     question.BankID,
+			QuestionID: question.ID,
+			Revision:   question.Revision,
+			Reason:     req.Reason,
+			Comment:    req.Comment,
+			Reporter:   currentUser(c),
+			Status:     models.ReportOpen,
+		}
+		if report.Reporter == "" {
+			report.Reporter = req.UserID
+		}
+		if req.SubmissionID != nil {
+			revision, ok := submittedRevision(c, db, *req.SubmissionID, question.ID)
+			if !ok {
+				return
+			}
+			report.SubmissionID = req.SubmissionID
+			if revision != 0 {
+				report.Revision = revision
+			}
+		}
+
+		if err := db.Create(&report).Error; err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to save report")
+			return
+		}
+
+		utils.CreatedResponse(c, report, "Report submitted successfully")
+	}
+}
+
+// GetQuestionReports returns the reports on a question, oldest first. Only
+// the bank's owners see its reports.
+func GetQuestionReports(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := requireModerator(c); !ok {
+			return
+		}
+
+		question, ok := findQuestion(c, db)
+		if !ok {
+			return
+		}
+
+		reports := []models.QuestionReport{}
+		if err := db.Where("question_id = ?", question.ID).Order("id").Find(&reports).Error; err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to fetch reports")
+			return
+		}
+
+		utils.SuccessResponse(c, reports, "Reports retrieved successfully")
+	}
+}
+
+// GetReports returns the bank's moderation queue, oldest first: open and
+// triaged reports, or those with the given ?status=, optionally only one
+// ?reason=. Only the bank's owners see its reports.
+func GetReports(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := requireModerator(c); !ok {
+			return
+		}
+
+		query := db.Where("bank_id = ?", currentBank(c).ID)
+		if status := c.Query("status"); status != "" {
+			if !models.IsReportStatus(status) {
+				utils.BadRequestResponse(c, "Invalid status parameter")
+				return
+			}
+			query = query.Where("status = ?", status)
+		} else {
+			query = query.Where("status IN ?", []string{models.ReportOpen, models.ReportTriaged})
+		}
+		if reason := c.Query("reason"); reason != "" {
+			if !models.IsReportReason(reason) {
+				utils.BadRequestResponse(c, "Invalid reason parameter")
+				return
+			}
+			query = query.Where("reason = ?", reason)
+		}
+
+		var reports []models.QuestionReport
+		if err := query.Order("id").Find(&reports).Error; err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to fetch reports")
+			return
+		}
+
+		ids := make([]uint, len(reports))
+		for i, report := range reports {
+			ids[i] = report.QuestionID
+		}
+		// Reported questions may have been moved to the trash since
+		var questions []models.Question
+		if len(ids) > 0 {
+			if err := db.Unscoped().Where("id IN ?", ids).Find(&questions).Error; err != nil {
+				utils.InternalServerErrorResponse(c, "Failed to fetch reported questions")
+				return
+			}
+		}
+		byID := make(map[uint]*models.Question, len(questions))
+		for i := range questions {
+			byID[questions[i].ID] = &questions[i]
+		}
+
+		queue := []models.ReportedQuestion{}
+		for _, report := range reports {
+			entry := models.ReportedQuestion{QuestionReport: report}
+			if q, ok := byID[report.QuestionID]; ok {
+				entry.Question, entry.CurrentRevision = q.Question, q.Revision
+			}
+			queue = append(queue, entry)
+		}
+
+		utils.SuccessResponse(c, queue, "Reports retrieved successfully")
+	}
+}
+
+// ModerateReport moves a report through the moderation queue. Only the
+// bank's owners moderate its reports.
+func ModerateReport(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		moderator, ok := requireModerator(c)
+		if !ok {
+			return
+		}
+		bank := currentBank(c)
+
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			utils.BadRequestResponse(c, "Invalid report ID")
+			return
+		}
+		var report models.QuestionReport
+		if err := db.Where("bank_id = ?", bank.ID).First(&report, id).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				utils.NotFoundResponse(c, "Report not found")
+				return
+			}
+			utils.InternalServerErrorResponse(c, "Failed to fetch report")
+			return
+		}
+
+		var req models.ModerationRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ValidationErrorResponse(c, "Invalid request body")
+			return
+		}
+		if err := report.Moderate(moderator, req); err != nil {
+			utils.ValidationErrorResponse(c, err.Error())
+			return
+		}
+
+		if err := db.Save(&report).Error; err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to update report")
+			return
+		}
+
+		utils.SuccessResponse(c, report, "Report updated successfully")
+	}
+}
+
+// requireModerator returns the X-User-ID user when they are listed among the
+// owners of the request's bank and so moderate its reports, writing the error
+// response itself otherwise. A bank without owners has no moderators, as
+// reports name their reporters.
+func requireModerator(c *gin.Context) (string, bool) {
+	moderator := currentUser(c)
+	if moderator == "" {
+		utils.BadRequestResponse(c, "The "+userHeader+" header is required")
+		return "", false
+	}
+	if !currentBank(c).IsListedOwner(moderator) {
+		utils.ErrorResponse(c, http.StatusForbidden, "Only the bank's owners may moderate its reports")
+		return "", false
+	}
+	return moderator, true
+}
+
+// submittedRevision checks that a question was answered in one of the bank's
+// submissions and returns the revision it was graded against, 0 when none
+// was recorded, writing the error response itself otherwise
+func submittedRevision(c *gin.Context, db *gorm.DB, submissionID, questionID uint) (int, bool) {
+	var submission models.QuizSubmission
+	if err := db.Where("bank_id = ?", currentBank(c).ID).First(&submission, submissionID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.ValidationErrorResponse(c, fmt.Sprintf("Submission %d not found", submissionID))
+			return 0, false
+		}
+		utils.InternalServerErrorResponse(c, "Failed to fetch submission")
+		return 0, false
+	}
+
+	key := strconv.FormatUint(uint64(questionID), 10)
+	var answers map[string]json.RawMessage
+	_ = json.Unmarshal([]byte(submission.Answers), &answers)
+	if _, ok := answers[key]; !ok {
+		utils.ValidationErrorResponse(c, fmt.Sprintf("Question %d was not answered in submission %d", questionID, submissionID))
+		return 0, false
+	}
+
+	var revisions map[string]int
+	_ = json.Unmarshal([]byte(submission.Revisions), &revisions)
+	return revisions[key], true
+}
+
+// VoteExplanation records whether the voter found a published question's
+// explanation helpful, replacing their earlier vote, and returns the updated
+// tally
+func VoteExplanation(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		voter := currentUser(c)
+		if voter == "" {
+			utils.BadRequestResponse(c, "The "+userHeader+" header is required")
+			return
+		}
+
+		question, ok := findPublishedQuestion(c, db)
+		if !ok {
+			return
+		}
+
+		var req models.ExplanationVoteRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ValidationErrorResponse(c, "Invalid request body")
+			return
+		}
+
+		vote := models.ExplanationVote{QuestionID: question.ID, Voter: voter}
+		if err := db.Where(&vote).FirstOrInit(&vote).Error; err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to fetch vote")
+			return
+		}
+		vote.Helpful = *req.Helpful
+		vote.Revision = question.Revision
+		if err := db.Save(&vote).Error; err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to save vote")
+			return
+		}
+
+		respondWithVotes(c, db, &question, voter, "Vote recorded successfully")
+	}
+}
+
+// GetExplanationVotes returns the tally of votes on a question's explanation,
+// including the caller's own vote when they send X-User-ID
+func GetExplanationVotes(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		question, ok := findPublishedQuestion(c, db)
+		if !ok {
+			return
+		}
+		respondWithVotes(c, db, &question, currentUser(c), "Votes retrieved successfully")
+	}
+}
+
+// respondWithVotes writes the vote tally of a question
+func respondWithVotes(c *gin.Context, db *gorm.DB, question *models.Question, voter, message string) {
+	var votes []models.ExplanationVote
+	if err := db.Where("question_id = ?", question.ID).Find(&votes).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch votes")
+		return
+	}
+	utils.SuccessResponse(c, models.SummarizeVotes(question, votes, voter), message)
+}
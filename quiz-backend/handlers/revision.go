@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"aws-rds-quiz-backend/models"
+	"aws-rds-quiz-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetQuestionRevisions returns a question's revisions, oldest first, each
+// with the changes from the revision before it
+func GetQuestionRevisions(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		question, ok := findQuestion(c, db)
+		if !ok {
+			return
+		}
+
+		var revisions []models.QuestionRevision
+		if err := db.Where("question_id = ?", question.ID).Order("revision").Find(&revisions).Error; err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to fetch question revisions")
+			return
+		}
+
+		responses := make([]models.QuestionRevisionResponse, 0, len(revisions))
+		var previous *models.QuestionRequest
+		for _, revision := range revisions {
+			snapshot := revision.ToQuestion()
+			req, err := snapshot.ToRequest()
+			if err != nil {
+				utils.InternalServerErrorResponse(c, "Failed to parse question revision")
+				return
+			}
+
+			response := models.QuestionRevisionResponse{
+				Revision:  revision.Revision,
+				CreatedAt: revision.CreatedAt,
+				Question:  req,
+			}
+			if previous != nil {
+				if response.Changes, err = models.DiffRequests(*previous, req); err != nil {
+					utils.InternalServerErrorResponse(c, "Failed to compare question revisions")
+					return
+				}
+			}
+			responses = append(responses, response)
+			previous = &req
+		}
+
+		utils.SuccessResponse(c, responses, "Question revisions retrieved successfully")
+	}
+}
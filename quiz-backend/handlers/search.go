@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"aws-rds-quiz-backend/i18n"
+	"aws-rds-quiz-backend/models"
+	"aws-rds-quiz-backend/search"
+	"aws-rds-quiz-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SearchQuestions runs a full-text search over the text, options and
+// explanations of published questions, accepting the same filters as
+// GetAllQuestions. Matching uses
+// the source text; the hits are served in the negotiated language. Search is
+// unavailable in exam mode, since it matches explanations.
+func SearchQuestions(db *gorm.DB, locales *i18n.Locales) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if rejectWithheld(c, "Search is unavailable while answers are withheld") {
+			return
+		}
+
+		text := strings.TrimSpace(c.Query("q"))
+		if text == "" {
+			utils.BadRequestResponse(c, "Missing search text parameter q")
+			return
+		}
+
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+		if err != nil || limit <= 0 || limit > 100 {
+			utils.BadRequestResponse(c, "Invalid limit parameter. Must be between 1 and 100")
+			return
+		}
+
+		query, ok := filterQuestions(c, db)
+		if !ok {
+			return
+		}
+		query = query.Where("questions.status = ?", models.StatusPublished)
+
+		result, err := search.Questions(query, text, limit)
+		if err != nil {
+			if errors.Is(err, search.ErrNoTerms) {
+				utils.BadRequestResponse(c, err.Error())
+				return
+			}
+			utils.InternalServerErrorResponse(c, "Failed to search questions")
+			return
+		}
+
+		questions := make([]models.QuestionResponse, len(result.Hits))
+		for i, hit := range result.Hits {
+			questions[i] = hit.Question
+		}
+		if !localize(c, db, locales, questions) {
+			return
+		}
+		for i := range result.Hits {
+			result.Hits[i].Question = questions[i]
+		}
+
+		utils.SuccessResponse(c, result, "Search completed successfully")
+	}
+}
@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"aws-rds-quiz-backend/config"
+	"aws-rds-quiz-backend/i18n"
+	"aws-rds-quiz-backend/models"
+	"aws-rds-quiz-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var errSessionSubmitted = errors.New("quiz session already submitted")
+
+// CreateQuizSession serves random published questions like
+// GetRandomQuestions and records them, in order, as a session the answers
+// are later submitted against. The X-User-ID user, if any, owns the session.
+// The optional body sets the mode, review policy and time limit; exam
+// sessions serve the questions without their answers. In exam mode every
+// session is an exam with the configured review policy and time limit, which
+// the body may not change.
+func CreateQuizSession(db *gorm.DB, locales *i18n.Locales, cfg config.ExamConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.QuizSessionRequest
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				utils.ValidationErrorResponse(c, "Invalid request body")
+				return
+			}
+		}
+		if withholding(c) && req.Mode == "" {
+			req.Mode = models.ModeExam
+		}
+		req.Normalize(cfg.DefaultReview, cfg.TimeLimit)
+		if err := req.Validate(); err != nil {
+			utils.ValidationErrorResponse(c, err.Error())
+			return
+		}
+		if withholding(c) && req.Mode != models.ModeExam {
+			utils.ErrorResponse(c, http.StatusForbidden, "Only exam sessions are served while answers are withheld")
+			return
+		}
+		// Otherwise a learner could pick a policy that reveals the answers
+		if withholding(c) && (req.Review != cfg.DefaultReview || req.TimeLimit != cfg.TimeLimit) {
+			utils.ErrorResponse(c, http.StatusForbidden, "The review policy and time limit are fixed while answers are withheld")
+			return
+		}
+
+		responses, ok := randomQuestions(c, db, locales)
+		if !ok {
+			return
+		}
+		if len(responses) == 0 {
+			utils.ValidationErrorResponse(c, "No published questions match the filters")
+			return
+		}
+
+		served := make([]models.SessionQuestion, len(responses))
+		for i, response := range responses {
+			served[i] = models.SessionQuestion{QuestionID: response.ID, Revision: response.Revision, Variant: response.Variant}
+		}
+		questionsJSON, err := json.Marshal(served)
+		if err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to encode session questions")
+			return
+		}
+
+		session := models.QuizSession{
+			BankID:    currentBank(c).ID,
+			UserID:    currentUser(c),
+			Questions: string(questionsJSON),
+			Mode:      req.Mode,
+			Review:    req.Review,
+			StartedAt: time.Now(),
+		}
+		if req.TimeLimit > 0 {
+			closesAt := session.StartedAt.Add(time.Duration(req.TimeLimit) * time.Second)
+			session.ClosesAt = &closesAt
+		}
+		if err := db.Create(&session).Error; err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to create quiz session")
+			return
+		}
+		if session.IsExam() {
+			withholdAnswers(responses)
+		}
+
+		resp := models.QuizSessionResponse{
+			ID:        session.ID,
+			UserID:    session.UserID,
+			Mode:      session.Mode,
+			Review:    session.Review,
+			StartedAt: session.StartedAt,
+			ClosesAt:  session.ClosesAt,
+			Questions: responses,
+		}
+		utils.CreatedResponse(c, resp, "Quiz session started successfully")
+	}
+}
+
+// SubmitQuizSession grades answers against the questions, revisions and
+// variants the session served. Answers to questions outside the session are
+// rejected, unanswered questions count toward the total, and the time spent
+// is measured from the start of the session. A session is submitted once,
+// before its time limit runs out. The result of an exam leaves out the graded
+// answers until its review policy reveals them.
+func SubmitQuizSession(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			utils.BadRequestResponse(c, "Invalid quiz session ID")
+			return
+		}
+
+		bank := currentBank(c)
+		var session models.QuizSession
+		if err := db.Where("bank_id = ?", bank.ID).First(&session, id).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				utils.NotFoundResponse(c, "Quiz session not found")
+				return
+			}
+			utils.InternalServerErrorResponse(c, "Failed to fetch quiz session")
+			return
+		}
+		if session.UserID != "" && currentUser(c) != session.UserID {
+			utils.ErrorResponse(c, http.StatusForbidden, "Quiz session belongs to another user")
+			return
+		}
+		if session.SubmissionID != nil {
+			utils.ErrorResponse(c, http.StatusConflict, "Quiz session already submitted")
+			return
+		}
+		now := time.Now()
+		if session.Closed(now) {
+			utils.ErrorResponse(c, http.StatusConflict, "Quiz session closed at "+session.ClosesAt.Format(time.RFC3339))
+			return
+		}
+
+		var req models.SessionSubmissionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ValidationErrorResponse(c, "Invalid request body")
+			return
+		}
+		for qid := range req.Answers {
+			if !session.Served(qid) {
+				utils.ValidationErrorResponse(c, fmt.Sprintf("Question %s was not served in this session", qid))
+				return
+			}
+		}
+
+		revisions, variants := session.Grading()
+		answerDetails, err := gradeAnswers(db, req.Answers, revisions, variants, true)
+		if err != nil {
+			utils.ValidationErrorResponse(c, "Invalid answer for "+err.Error())
+			return
+		}
+
+		total := len(session.DecodeQuestions())
+		timeSpent := now.Sub(session.StartedAt).Milliseconds()
+		quiz := newSubmission(bank, session.UserID, req.Answers, answerDetails, total, timeSpent)
+		quiz.SessionID = &session.ID
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&quiz).Error; err != nil {
+				return err
+			}
+			// Guards against the session being submitted concurrently
+			result := tx.Model(&models.QuizSession{}).Where("id = ? AND submission_id IS NULL", session.ID).Update("submission_id", quiz.ID)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return errSessionSubmitted
+			}
+			return nil
+		})
+		if err == errSessionSubmitted {
+			utils.ErrorResponse(c, http.StatusConflict, "Quiz session already submitted")
+			return
+		}
+		if err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to save quiz submission")
+			return
+		}
+
+		resp := submissionResponse(&quiz, answerDetails)
+		resp.Unanswered = session.Unanswered(req.Answers)
+		applyReviewPolicy(&resp, &session, now)
+		utils.SuccessResponse(c, resp, "Quiz submitted successfully")
+	}
+}
@@ -0,0 +1,471 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"aws-rds-quiz-backend/models"
+	"aws-rds-quiz-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// filterQuestions scopes a question query to the request's bank and applies
+// the question list filters: "category",
+// "difficulty", "tag" (repeatable, a question must carry every tag), "topic"
+// (a topic ID, matching its whole subtree) and "domain" (an exam domain ID,
+// matching every topic mapped to it and their subtrees)
+func filterQuestions(c *gin.Context, db *gorm.DB) (*gorm.DB, bool) {
+	query := db.Scopes(models.InBank(currentBank(c).ID))
+	if category := c.Query("category"); category != "" {
+		query = query.Where("questions.category = ?", category)
+	}
+	if difficulty := c.Query("difficulty"); difficulty != "" {
+		query = query.Where("questions.difficulty = ?", strings.ToLower(difficulty))
+	}
+	for _, tag := range c.QueryArray("tag") {
+		tagged := db.Table("question_tags").
+			Select("question_tags.question_id").
+			Joins("JOIN tags ON tags.id = question_tags.tag_id").
+			Where("tags.name = ?", models.NormalizeTag(tag))
+		query = query.Where("questions.id IN (?)", tagged)
+	}
+
+	if topicID := c.Query("topic"); topicID != "" {
+		id, err := strconv.ParseUint(topicID, 10, 32)
+		if err != nil {
+			utils.BadRequestResponse(c, "Invalid topic ID")
+			return nil, false
+		}
+		var topic models.Topic
+		if err := db.First(&topic, id).Error; err != nil {
+			utils.NotFoundResponse(c, "Topic not found")
+			return nil, false
+		}
+		subtree := db.Model(&models.Topic{}).Select("id").Where("path LIKE ?", topic.Path+"%")
+		query = query.Where("questions.topic_id IN (?)", subtree)
+	}
+
+	if domainID := c.Query("domain"); domainID != "" {
+		id, err := strconv.ParseUint(domainID, 10, 32)
+		if err != nil {
+			utils.BadRequestResponse(c, "Invalid exam domain ID")
+			return nil, false
+		}
+		mapped := db.Table("topics AS t").
+			Select("t.id").
+			Joins("JOIN topics AS m ON t.path LIKE m.path || '%'").
+			Joins("JOIN topic_exam_domains AS d ON d.topic_id = m.id").
+			Where("d.exam_domain_id = ?", id)
+		query = query.Where("questions.topic_id IN (?)", mapped)
+	}
+	return query, true
+}
+
+// parseID reads the :id path parameter
+func parseID(c *gin.Context, what string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.BadRequestResponse(c, "Invalid "+what+" ID")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// GetTags returns all tags
+func GetTags(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var tags []models.Tag
+		if err := db.Order("name").Find(&tags).Error; err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to fetch tags")
+			return
+		}
+		utils.SuccessResponse(c, tags, "Tags retrieved successfully")
+	}
+}
+
+// CreateTag creates a tag
+func CreateTag(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.TagRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ValidationErrorResponse(c, "Invalid request body")
+			return
+		}
+
+		tag := models.Tag{}
+		if !renameTag(c, db, &tag, req.Name) {
+			return
+		}
+		if err := db.Create(&tag).Error; err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to create tag")
+			return
+		}
+		utils.CreatedResponse(c, tag, "Tag created successfully")
+	}
+}
+
+// UpdateTag renames a tag
+func UpdateTag(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var tag models.Tag
+		if !findByID(c, db, &tag, "tag") {
+			return
+		}
+		var req models.TagRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ValidationErrorResponse(c, "Invalid request body")
+			return
+		}
+
+		if !renameTag(c, db, &tag, req.Name) {
+			return
+		}
+		if err := db.Save(&tag).Error; err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to update tag")
+			return
+		}
+		utils.SuccessResponse(c, tag, "Tag updated successfully")
+	}
+}
+
+// DeleteTag deletes a tag and removes it from its questions
+func DeleteTag(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var tag models.Tag
+		if !findByID(c, db, &tag, "tag") {
+			return
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec("DELETE FROM question_tags WHERE tag_id = ?", tag.ID).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&tag).Error
+		})
+		if err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to delete tag")
+			return
+		}
+		utils.SuccessResponse(c, gin.H{"id": tag.ID}, "Tag deleted successfully")
+	}
+}
+
+// renameTag validates a new tag name, rejecting names already in use
+func renameTag(c *gin.Context, db *gorm.DB, tag *models.Tag, name string) bool {
+	name = models.NormalizeTag(name)
+	if name == "" || len(name) > 50 {
+		utils.ValidationErrorResponse(c, "Tag name must be between 1 and 50 characters")
+		return false
+	}
+	var count int64
+	if err := db.Model(&models.Tag{}).Where("name = ? AND id <> ?", name, tag.ID).Count(&count).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to check tag name")
+		return false
+	}
+	if count > 0 {
+		utils.ErrorResponse(c, http.StatusConflict, "A tag with this name already exists")
+		return false
+	}
+	tag.Name = name
+	return true
+}
+
+// GetTopics returns every topic, ordered so that each topic follows its
+// parent
+func GetTopics(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var topics []models.Topic
+		if err := db.Preload("ExamDomains").Order("full_name").Find(&topics).Error; err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to fetch topics")
+			return
+		}
+		utils.SuccessResponse(c, topics, "Topics retrieved successfully")
+	}
+}
+
+// GetTopic returns a single topic
+func GetTopic(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var topic models.Topic
+		if !findByID(c, db.Preload("ExamDomains"), &topic, "topic") {
+			return
+		}
+		utils.SuccessResponse(c, topic, "Topic retrieved successfully")
+	}
+}
+
+// CreateTopic creates a topic under an optional parent
+func CreateTopic(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.TopicRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ValidationErrorResponse(c, "Invalid request body")
+			return
+		}
+		saveTopic(c, db, &models.Topic{}, &req)
+	}
+}
+
+// UpdateTopic renames a topic, moves it to another parent and replaces its
+// exam domains. Its subtree moves with it.
+func UpdateTopic(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var topic models.Topic
+		if !findByID(c, db, &topic, "topic") {
+			return
+		}
+		var req models.TopicRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ValidationErrorResponse(c, "Invalid request body")
+			return
+		}
+		saveTopic(c, db, &topic, &req)
+	}
+}
+
+// DeleteTopic deletes a topic without children. Its questions are left
+// without a topic.
+func DeleteTopic(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var topic models.Topic
+		if !findByID(c, db, &topic, "topic") {
+			return
+		}
+
+		var children int64
+		if err := db.Model(&models.Topic{}).Where("parent_id = ?", topic.ID).Count(&children).Error; err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to check topic children")
+			return
+		}
+		if children > 0 {
+			utils.ErrorResponse(c, http.StatusConflict, "Topic has child topics; delete or move them first")
+			return
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&models.Question{}).Unscoped().Where("topic_id = ?", topic.ID).UpdateColumn("topic_id", nil).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&topic).Association("ExamDomains").Clear(); err != nil {
+				return err
+			}
+			return tx.Delete(&topic).Error
+		})
+		if err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to delete topic")
+			return
+		}
+		utils.SuccessResponse(c, gin.H{"id": topic.ID}, "Topic deleted successfully")
+	}
+}
+
+// saveTopic validates req, applies it to topic and persists the topic, its
+// subtree's paths and its exam domains
+func saveTopic(c *gin.Context, db *gorm.DB, topic *models.Topic, req *models.TopicRequest) {
+	name := strings.TrimSpace(req.Name)
+	if err := models.ValidateTopicName(name); err != nil {
+		utils.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	var parent *models.Topic
+	if req.ParentID != nil {
+		parent = &models.Topic{}
+		if err := db.First(parent, *req.ParentID).Error; err != nil {
+			utils.ValidationErrorResponse(c, "Parent topic not found")
+			return
+		}
+		if topic.ID != 0 && strings.HasPrefix(parent.Path, topic.Path) {
+			utils.ValidationErrorResponse(c, "A topic cannot be moved under itself or its descendants")
+			return
+		}
+	}
+
+	siblings := db.Model(&models.Topic{}).Where("name = ? AND id <> ?", name, topic.ID)
+	if parent == nil {
+		siblings = siblings.Where("parent_id IS NULL")
+	} else {
+		siblings = siblings.Where("parent_id = ?", parent.ID)
+	}
+	var count int64
+	if err := siblings.Count(&count).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to check topic name")
+		return
+	}
+	if count > 0 {
+		utils.ErrorResponse(c, http.StatusConflict, "A topic with this name already exists under the same parent")
+		return
+	}
+
+	domains := []models.ExamDomain{}
+	if len(req.ExamDomainIDs) > 0 {
+		if err := db.Find(&domains, req.ExamDomainIDs).Error; err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to fetch exam domains")
+			return
+		}
+		if len(domains) != len(uniqueIDs(req.ExamDomainIDs)) {
+			utils.ValidationErrorResponse(c, "Unknown exam domain ID")
+			return
+		}
+	}
+
+	created := topic.ID == 0
+	oldPath, oldFullName := topic.Path, topic.FullName
+	topic.Name, topic.ParentID = name, req.ParentID
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if created {
+			if err := tx.Omit("ExamDomains").Create(topic).Error; err != nil {
+				return err
+			}
+		}
+		topic.SetLineage(parent)
+		if err := tx.Omit("ExamDomains").Save(topic).Error; err != nil {
+			return err
+		}
+		if !created {
+			if err := topic.MoveSubtree(tx, oldPath, oldFullName); err != nil {
+				return err
+			}
+		}
+		return tx.Model(topic).Association("ExamDomains").Replace(domains)
+	})
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to save topic")
+		return
+	}
+
+	if created {
+		utils.CreatedResponse(c, topic, "Topic created successfully")
+	} else {
+		utils.SuccessResponse(c, topic, "Topic updated successfully")
+	}
+}
+
+// GetExamDomains returns all exam domains
+func GetExamDomains(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var domains []models.ExamDomain
+		if err := db.Order("exam, code").Find(&domains).Error; err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to fetch exam domains")
+			return
+		}
+		utils.SuccessResponse(c, domains, "Exam domains retrieved successfully")
+	}
+}
+
+// CreateExamDomain creates an exam domain
+func CreateExamDomain(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.ExamDomainRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ValidationErrorResponse(c, "Invalid request body")
+			return
+		}
+		domain := models.ExamDomain{}
+		saveExamDomain(c, db, &domain, &req)
+	}
+}
+
+// UpdateExamDomain replaces an exam domain's fields
+func UpdateExamDomain(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var domain models.ExamDomain
+		if !findByID(c, db, &domain, "exam domain") {
+			return
+		}
+		var req models.ExamDomainRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ValidationErrorResponse(c, "Invalid request body")
+			return
+		}
+		saveExamDomain(c, db, &domain, &req)
+	}
+}
+
+// DeleteExamDomain deletes an exam domain and its topic mappings
+func DeleteExamDomain(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var domain models.ExamDomain
+		if !findByID(c, db, &domain, "exam domain") {
+			return
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec("DELETE FROM topic_exam_domains WHERE exam_domain_id = ?", domain.ID).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&domain).Error
+		})
+		if err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to delete exam domain")
+			return
+		}
+		utils.SuccessResponse(c, gin.H{"id": domain.ID}, "Exam domain deleted successfully")
+	}
+}
+
+// saveExamDomain applies req to domain and persists it, rejecting a
+// duplicate exam and code
+func saveExamDomain(c *gin.Context, db *gorm.DB, domain *models.ExamDomain, req *models.ExamDomainRequest) {
+	exam, code, name := strings.ToUpper(strings.TrimSpace(req.Exam)), strings.TrimSpace(req.Code), strings.TrimSpace(req.Name)
+	if exam == "" || code == "" || name == "" {
+		utils.ValidationErrorResponse(c, "Exam, code and name are required")
+		return
+	}
+
+	var count int64
+	if err := db.Model(&models.ExamDomain{}).Where("exam = ? AND code = ? AND id <> ?", exam, code, domain.ID).Count(&count).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to check exam domain")
+		return
+	}
+	if count > 0 {
+		utils.ErrorResponse(c, http.StatusConflict, "This exam already has a domain with this code")
+		return
+	}
+
+	created := domain.ID == 0
+	domain.Exam, domain.Code, domain.Name = exam, code, name
+	if err := db.Save(domain).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to save exam domain")
+		return
+	}
+	if created {
+		utils.CreatedResponse(c, domain, "Exam domain created successfully")
+	} else {
+		utils.SuccessResponse(c, domain, "Exam domain updated successfully")
+	}
+}
+
+// findByID loads the record named by the :id path parameter into dest,
+// writing the error response itself when the lookup fails
+func findByID(c *gin.Context, db *gorm.DB, dest interface{}, what string) bool {
+	id, ok := parseID(c, what)
+	if !ok {
+		return false
+	}
+	if err := db.First(dest, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.NotFoundResponse(c, strings.ToUpper(what[:1])+what[1:]+" not found")
+			return false
+		}
+		utils.InternalServerErrorResponse(c, "Failed to fetch "+what)
+		return false
+	}
+	return true
+}
+
+// uniqueIDs removes duplicate IDs
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	var result []uint
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
+}
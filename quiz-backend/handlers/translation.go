@@ -0,0 +1,357 @@
+package handlers
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"aws-rds-quiz-backend/i18n"
+	"aws-rds-quiz-backend/models"
+	"aws-rds-quiz-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetQuestionTranslations lists a question's translations, each marked
+// current or stale
+func GetQuestionTranslations(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		question, ok := findQuestion(c, db)
+		if !ok {
+			return
+		}
+		source, err := question.ToResponse()
+		if err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to parse question options")
+			return
+		}
+
+		var translations []models.QuestionTranslation
+		if err := db.Where("question_id = ?", question.ID).Order("locale").Find(&translations).Error; err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to fetch translations")
+			return
+		}
+
+		responses := make([]models.TranslationResponse, 0, len(translations))
+		for _, t := range translations {
+			responses = append(responses, t.ToResponse(source))
+		}
+		utils.SuccessResponse(c, responses, "Translations retrieved successfully")
+	}
+}
+
+// PutQuestionTranslation creates or replaces a question's translation into
+// the :locale locale, recording the X-User-ID user as the translator
+func PutQuestionTranslation(db *gorm.DB, locales *i18n.Locales) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		question, ok := findQuestion(c, db)
+		if !ok {
+			return
+		}
+		locale, ok := translationLocale(c, locales)
+		if !ok {
+			return
+		}
+
+		var req models.TranslationRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ValidationErrorResponse(c, "Invalid request body")
+			return
+		}
+		source, err := question.ToResponse()
+		if err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to parse question options")
+			return
+		}
+		req.Normalize()
+		if err := req.Validate(source); err != nil {
+			utils.ValidationErrorResponse(c, err.Error())
+			return
+		}
+		if !checkAttachments(c, db, &models.QuestionRequest{Question: req.Question, Options: req.Options}) {
+			return
+		}
+
+		translation := models.QuestionTranslation{QuestionID: question.ID, Locale: locale}
+		if err := db.Where(&translation).FirstOrInit(&translation).Error; err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to fetch translation")
+			return
+		}
+		created := translation.ID == 0
+		if err := req.ApplyTo(&translation, source); err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to encode translation options")
+			return
+		}
+		translation.Translator = currentUser(c)
+		if err := db.Save(&translation).Error; err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to save translation")
+			return
+		}
+
+		if created {
+			utils.CreatedResponse(c, translation.ToResponse(source), "Translation created successfully")
+			return
+		}
+		utils.SuccessResponse(c, translation.ToResponse(source), "Translation updated successfully")
+	}
+}
+
+// DeleteQuestionTranslation removes a question's translation into :locale
+func DeleteQuestionTranslation(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		question, ok := findQuestion(c, db)
+		if !ok {
+			return
+		}
+		locale, err := i18n.Canonical(c.Param("locale"))
+		if err != nil {
+			utils.BadRequestResponse(c, err.Error())
+			return
+		}
+
+		result := db.Where("question_id = ? AND locale = ?", question.ID, locale).Delete(&models.QuestionTranslation{})
+		if result.Error != nil {
+			utils.InternalServerErrorResponse(c, "Failed to delete translation")
+			return
+		}
+		if result.RowsAffected == 0 {
+			utils.NotFoundResponse(c, "Translation not found")
+			return
+		}
+
+		utils.SuccessResponse(c, gin.H{"id": question.ID, "locale": locale}, "Translation deleted successfully")
+	}
+}
+
+// GetTranslationCoverage counts, for each translation locale, the published
+// questions whose translation is current, stale or missing. It accepts the
+// same filters as GetAllQuestions.
+func GetTranslationCoverage(db *gorm.DB, locales *i18n.Locales) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sources, ok := translationSources(c, db)
+		if !ok {
+			return
+		}
+		translations, ok := sourceTranslations(c, db, sources, nil)
+		if !ok {
+			return
+		}
+
+		targets := map[string]bool{}
+		for _, locale := range locales.Targets() {
+			targets[locale] = true
+		}
+		var stored []string
+		if err := db.Model(&models.QuestionTranslation{}).Distinct().Pluck("locale", &stored).Error; err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to fetch translations")
+			return
+		}
+		for _, locale := range stored {
+			targets[locale] = true
+		}
+
+		coverage := models.TranslationCoverage{
+			SourceLocale: locales.Source(),
+			Total:        len(sources),
+			Locales:      []models.LocaleCoverage{},
+		}
+		for locale := range targets {
+			counts := models.LocaleCoverage{Locale: locale}
+			for _, source := range sources {
+				switch translationStatus(translations[source.ID][locale], source) {
+				case models.TranslationCurrent:
+					counts.Current++
+				case models.TranslationStale:
+					counts.Stale++
+				default:
+					counts.Missing++
+				}
+			}
+			if len(sources) > 0 {
+				counts.Percentage = math.Round(float64(counts.Current)/float64(len(sources))*1000) / 10
+			}
+			coverage.Locales = append(coverage.Locales, counts)
+		}
+		sort.Slice(coverage.Locales, func(i, j int) bool {
+			return coverage.Locales[i].Locale < coverage.Locales[j].Locale
+		})
+
+		utils.SuccessResponse(c, coverage, "Translation coverage retrieved successfully")
+	}
+}
+
+// GetPendingTranslations lists the published questions whose translation
+// into ?locale= is missing or stale, optionally only one ?status=. It accepts
+// the same filters as GetAllQuestions.
+func GetPendingTranslations(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		locale, err := i18n.Canonical(c.Query("locale"))
+		if err != nil {
+			utils.BadRequestResponse(c, "Invalid or missing locale parameter")
+			return
+		}
+		status := c.Query("status")
+		if status != "" && status != models.TranslationMissing && status != models.TranslationStale {
+			utils.BadRequestResponse(c, "Invalid status parameter. Must be missing or stale")
+			return
+		}
+
+		sources, ok := translationSources(c, db)
+		if !ok {
+			return
+		}
+		translations, ok := sourceTranslations(c, db, sources, []string{locale})
+		if !ok {
+			return
+		}
+
+		pending := []models.PendingTranslation{}
+		for _, source := range sources {
+			t := translations[source.ID][locale]
+			itemStatus := translationStatus(t, source)
+			if itemStatus == models.TranslationCurrent || (status != "" && itemStatus != status) {
+				continue
+			}
+			item := models.PendingTranslation{
+				QuestionID: source.ID,
+				Revision:   source.Revision,
+				Question:   source.Question,
+				Status:     itemStatus,
+			}
+			if t != nil {
+				item.SourceRevision = t.SourceRevision
+			}
+			pending = append(pending, item)
+		}
+
+		utils.SuccessResponse(c, pending, "Pending translations retrieved successfully")
+	}
+}
+
+// localize serves each response in the best locale the client asked for,
+// with ?lang= or Accept-Language, that has a current translation. Stale
+// translations are skipped, since their options may no longer line up with
+// the answer key; responses without a usable translation stay in the source
+// locale. It writes the error response itself when it fails.
+func localize(c *gin.Context, db *gorm.DB, locales *i18n.Locales, responses []models.QuestionResponse) bool {
+	c.Header("Vary", "Accept-Language")
+	chain, err := locales.Chain(c.Query("lang"), c.GetHeader("Accept-Language"))
+	if err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return false
+	}
+
+	translations := map[uint]map[string]*models.QuestionTranslation{}
+	if len(chain) > 0 {
+		var ok bool
+		if translations, ok = sourceTranslations(c, db, responses, chain); !ok {
+			return false
+		}
+	}
+
+	var served []string
+	seen := map[string]bool{}
+	for i := range responses {
+		responses[i].Locale = locales.Source()
+		for _, locale := range chain {
+			if t := translations[responses[i].ID][locale]; translationStatus(t, responses[i]) == models.TranslationCurrent {
+				responses[i].Localize(t)
+				break
+			}
+		}
+		if !seen[responses[i].Locale] {
+			seen[responses[i].Locale] = true
+			served = append(served, responses[i].Locale)
+		}
+	}
+	if len(served) > 0 {
+		c.Header("Content-Language", strings.Join(served, ", "))
+	}
+	return true
+}
+
+// translationLocale reads and checks the :locale path parameter, writing the
+// error response itself when it is not accepted
+func translationLocale(c *gin.Context, locales *i18n.Locales) (string, bool) {
+	locale, err := i18n.Canonical(c.Param("locale"))
+	if err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return "", false
+	}
+	if locale == locales.Source() {
+		utils.ValidationErrorResponse(c, "Questions are written in "+locale+"; edit the question itself instead")
+		return "", false
+	}
+	if !locales.Allows(locale) {
+		utils.ValidationErrorResponse(c, "Translations are only kept for: "+strings.Join(locales.Targets(), ", "))
+		return "", false
+	}
+	return locale, true
+}
+
+// translationSources loads the filtered published questions in the source
+// locale
+func translationSources(c *gin.Context, db *gorm.DB) ([]models.QuestionResponse, bool) {
+	query, ok := filterQuestions(c, db)
+	if !ok {
+		return nil, false
+	}
+	query = query.Where("questions.status = ?", models.StatusPublished)
+
+	var questions []models.Question
+	if err := query.Order("questions.id").Find(&questions).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch questions")
+		return nil, false
+	}
+	sources := make([]models.QuestionResponse, 0, len(questions))
+	for _, q := range questions {
+		response, err := q.ToResponse()
+		if err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to parse question options")
+			return nil, false
+		}
+		sources = append(sources, response)
+	}
+	return sources, true
+}
+
+// sourceTranslations loads the translations of the given questions, keyed by
+// question ID and locale. A nil locales loads every locale.
+func sourceTranslations(c *gin.Context, db *gorm.DB, sources []models.QuestionResponse, locales []string) (map[uint]map[string]*models.QuestionTranslation, bool) {
+	byQuestion := make(map[uint]map[string]*models.QuestionTranslation)
+	if len(sources) == 0 {
+		return byQuestion, true
+	}
+
+	ids := make([]uint, len(sources))
+	for i, source := range sources {
+		ids[i] = source.ID
+	}
+	query := db.Where("question_id IN ?", ids)
+	if locales != nil {
+		query = query.Where("locale IN ?", locales)
+	}
+	var translations []models.QuestionTranslation
+	if err := query.Find(&translations).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch translations")
+		return nil, false
+	}
+
+	for i := range translations {
+		t := &translations[i]
+		if byQuestion[t.QuestionID] == nil {
+			byQuestion[t.QuestionID] = make(map[string]*models.QuestionTranslation)
+		}
+		byQuestion[t.QuestionID][t.Locale] = t
+	}
+	return byQuestion, true
+}
+
+// translationStatus reports whether t, which may be nil, translates source
+func translationStatus(t *models.QuestionTranslation, source models.QuestionResponse) string {
+	if t == nil {
+		return models.TranslationMissing
+	}
+	return t.StatusFor(source)
+}
@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"aws-rds-quiz-backend/config"
+	"aws-rds-quiz-backend/models"
+	"aws-rds-quiz-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetTrash lists soft-deleted questions and submissions, most recently
+// deleted first, optionally only one ?kind=
+func GetTrash(db *gorm.DB, cfg config.TrashConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		kinds, ok := trashKinds(c, c.Query("kind"))
+		if !ok {
+			return
+		}
+
+		items := []models.TrashItem{}
+		for _, kind := range kinds {
+			found, err := trashedItems(db, kind, cfg, time.Time{})
+			if err != nil {
+				utils.InternalServerErrorResponse(c, "Failed to fetch trash")
+				return
+			}
+			items = append(items, found...)
+		}
+		sort.SliceStable(items, func(i, j int) bool {
+			return items[i].DeletedAt.After(items[j].DeletedAt)
+		})
+
+		utils.SuccessResponse(c, items, "Trash retrieved successfully")
+	}
+}
+
+// RestoreTrashItem brings a soft-deleted question or submission back
+func RestoreTrashItem(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		kind, id, ok := trashPath(c)
+		if !ok {
+			return
+		}
+
+		var model interface{}
+		if kind == models.TrashQuestions {
+			var question models.Question
+			if !findTrashed(c, db, &question, id) || rejectFileBacked(c, &question) {
+				return
+			}
+			model = &question
+		} else {
+			var submission models.QuizSubmission
+			if !findTrashed(c, db, &submission, id) {
+				return
+			}
+			model = &submission
+		}
+
+		// Only deleted_at changes, so the save hooks need not run
+		if err := db.Unscoped().Model(model).Where("id = ?", id).UpdateColumn("deleted_at", nil).Error; err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to restore item")
+			return
+		}
+
+		utils.SuccessResponse(c, gin.H{"kind": kind, "id": id}, "Item restored successfully")
+	}
+}
+
+// PurgeTrashItem permanently deletes a soft-deleted question or submission
+// once its retention window has passed
+func PurgeTrashItem(db *gorm.DB, cfg config.TrashConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		kind, id, ok := trashPath(c)
+		if !ok {
+			return
+		}
+
+		var deleted gorm.DeletedAt
+		if kind == models.TrashQuestions {
+			var question models.Question
+			if !findTrashed(c, db, &question, id) {
+				return
+			}
+			deleted = question.DeletedAt
+		} else {
+			var submission models.QuizSubmission
+			if !findTrashed(c, db, &submission, id) {
+				return
+			}
+			deleted = submission.DeletedAt
+		}
+		if purgeAfter := retentionEnd(deleted.Time, cfg); time.Now().Before(purgeAfter) {
+			utils.ErrorResponse(c, http.StatusConflict, "Item is kept until "+purgeAfter.Format(time.RFC3339))
+			return
+		}
+
+		var err error
+		if kind == models.TrashQuestions {
+			err = models.PurgeQuestions(db, []uint{id})
+		} else {
+			err = db.Unscoped().Delete(&models.QuizSubmission{}, id).Error
+		}
+		if err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to purge item")
+			return
+		}
+
+		utils.SuccessResponse(c, gin.H{"kind": kind, "id": id}, "Item purged successfully")
+	}
+}
+
+// PurgeTrash permanently deletes every soft-deleted question and submission,
+// or only those of one ?kind=, whose retention window has passed
+func PurgeTrash(db *gorm.DB, cfg config.TrashConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		kinds, ok := trashKinds(c, c.Query("kind"))
+		if !ok {
+			return
+		}
+
+		cutoff := time.Now().AddDate(0, 0, -cfg.RetentionDays)
+		var report models.PurgeReport
+		for _, kind := range kinds {
+			expired, err := trashedItems(db, kind, cfg, cutoff)
+			if err != nil {
+				utils.InternalServerErrorResponse(c, "Failed to fetch trash")
+				return
+			}
+			ids := make([]uint, len(expired))
+			for i, item := range expired {
+				ids[i] = item.ID
+			}
+
+			if kind == models.TrashQuestions {
+				err = models.PurgeQuestions(db, ids)
+				report.Questions = len(ids)
+			} else if len(ids) > 0 {
+				err = db.Unscoped().Delete(&models.QuizSubmission{}, ids).Error
+				report.Submissions = len(ids)
+			}
+			if err != nil {
+				utils.InternalServerErrorResponse(c, "Failed to purge trash")
+				return
+			}
+		}
+
+		utils.SuccessResponse(c, report, "Trash purged successfully")
+	}
+}
+
+// trashKinds reads the kinds of item a trash request applies to, all of them
+// when kind is empty, writing the error response itself when it is unknown
+func trashKinds(c *gin.Context, kind string) ([]string, bool) {
+	if kind == "" {
+		return models.TrashKinds, true
+	}
+	for _, known := range models.TrashKinds {
+		if kind == known {
+			return []string{kind}, true
+		}
+	}
+	utils.BadRequestResponse(c, "Invalid kind parameter. Must be questions or submissions")
+	return nil, false
+}
+
+// trashPath reads the :kind and :id path parameters, writing the error
+// response itself when they are invalid
+func trashPath(c *gin.Context) (string, uint, bool) {
+	kinds, ok := trashKinds(c, c.Param("kind"))
+	if !ok {
+		return "", 0, false
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.BadRequestResponse(c, "Invalid item ID")
+		return "", 0, false
+	}
+	return kinds[0], uint(id), true
+}
+
+// findTrashed loads the soft-deleted row id into model, writing the error
+// response itself when it is not in the trash
+func findTrashed(c *gin.Context, db *gorm.DB, model interface{}, id uint) bool {
+	err := db.Unscoped().Where("deleted_at IS NOT NULL").First(model, id).Error
+	if err == gorm.ErrRecordNotFound {
+		utils.NotFoundResponse(c, "Item not found in trash")
+		return false
+	}
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch trash")
+		return false
+	}
+	return true
+}
+
+// trashedItems lists the soft-deleted items of one kind, only those deleted
+// before a non-zero cutoff
+func trashedItems(db *gorm.DB, kind string, cfg config.TrashConfig, cutoff time.Time) ([]models.TrashItem, error) {
+	query := db.Unscoped().Where("deleted_at IS NOT NULL")
+	if !cutoff.IsZero() {
+		query = query.Where("deleted_at < ?", cutoff)
+	}
+
+	var items []models.TrashItem
+	if kind == models.TrashQuestions {
+		var questions []models.Question
+		if err := query.Find(&questions).Error; err != nil {
+			return nil, err
+		}
+		for _, q := range questions {
+			items = append(items, trashItem(kind, q.ID, q.Question, q.DeletedAt.Time, cfg))
+		}
+		return items, nil
+	}
+
+	var submissions []models.QuizSubmission
+	if err := query.Find(&submissions).Error; err != nil {
+		return nil, err
+	}
+	for _, s := range submissions {
+		user := s.UserID
+		if user == "" {
+			user = "anonymous"
+		}
+		summary := fmt.Sprintf("%s scored %.0f%% on %d questions", user, s.Percentage, s.Total)
+		items = append(items, trashItem(kind, s.ID, summary, s.DeletedAt.Time, cfg))
+	}
+	return items, nil
+}
+
+func trashItem(kind string, id uint, summary string, deletedAt time.Time, cfg config.TrashConfig) models.TrashItem {
+	return models.TrashItem{
+		Kind:       kind,
+		ID:         id,
+		Summary:    summary,
+		DeletedAt:  deletedAt,
+		PurgeAfter: retentionEnd(deletedAt, cfg),
+	}
+}
+
+// retentionEnd is when an item deleted at deletedAt may be purged
+func retentionEnd(deletedAt time.Time, cfg config.TrashConfig) time.Time {
+	return deletedAt.AddDate(0, 0, cfg.RetentionDays)
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"strings"
+
+	"aws-rds-quiz-backend/models"
+	"aws-rds-quiz-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// userHeader names the user acting on the editorial workflow
+const userHeader = "X-User-ID"
+
+// queueItem is a question in an author's or reviewer's queue with its most
+// recent editorial action, such as a rejection and its comment
+type queueItem struct {
+	models.QuestionResponse
+	LastTransition *models.QuestionTransition `json:"lastTransition,omitempty"`
+}
+
+// currentUser returns the user named by the X-User-ID header
+func currentUser(c *gin.Context) string {
+	return strings.TrimSpace(c.GetHeader(userHeader))
+}
+
+// TransitionQuestion applies an editorial action (submit, assign, approve,
+// reject, retire or reopen) to a question and records it in its history
+func TransitionQuestion(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actor := currentUser(c)
+		if actor == "" {
+			utils.BadRequestResponse(c, "The "+userHeader+" header is required")
+			return
+		}
+
+		question, ok := findQuestion(c, db)
+		if !ok || rejectFileBacked(c, &question) {
+			return
+		}
+
+		var req models.TransitionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ValidationErrorResponse(c, "Invalid request body")
+			return
+		}
+
+		entry, err := question.Transition(actor, req)
+		if err != nil {
+			utils.ValidationErrorResponse(c, err.Error())
+			return
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&question).Select("status", "reviewer").Updates(&question).Error; err != nil {
+				return err
+			}
+			return tx.Create(&entry).Error
+		})
+		if err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to update question status")
+			return
+		}
+
+		response, err := question.ToResponse()
+		if err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to parse question options")
+			return
+		}
+
+		utils.SuccessResponse(c, gin.H{"question": response, "transition": entry}, "Question "+entry.Action+" recorded successfully")
+	}
+}
+
+// GetQuestionTransitions returns a question's editorial history, oldest first
+func GetQuestionTransitions(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		question, ok := findQuestion(c, db)
+		if !ok {
+			return
+		}
+
+		transitions := []models.QuestionTransition{}
+		if err := db.Where("question_id = ?", question.ID).Order("id").Find(&transitions).Error; err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to fetch question history")
+			return
+		}
+
+		utils.SuccessResponse(c, transitions, "Question history retrieved successfully")
+	}
+}
+
+// GetAuthorQueue returns an author's drafts and questions in review
+func GetAuthorQueue(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := db.Where("author = ? AND status IN ?", c.Param("user"), []string{models.StatusDraft, models.StatusInReview})
+		respondWithQueue(c, db, query)
+	}
+}
+
+// GetReviewerQueue returns the questions in review assigned to a reviewer,
+// plus unassigned ones when unassigned=true
+func GetReviewerQueue(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := db.Where("status = ?", models.StatusInReview)
+		if c.Query("unassigned") == "true" {
+			query = query.Where("reviewer = ? OR reviewer = ''", c.Param("user"))
+		} else {
+			query = query.Where("reviewer = ?", c.Param("user"))
+		}
+		respondWithQueue(c, db, query)
+	}
+}
+
+// respondWithQueue writes the questions selected by query, least recently
+// updated first, with their latest editorial action
+func respondWithQueue(c *gin.Context, db *gorm.DB, query *gorm.DB) {
+	var questions []models.Question
+	if err := query.Scopes(models.WithTaxonomy).Order("updated_at").Find(&questions).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch queue")
+		return
+	}
+
+	ids := make([]uint, len(questions))
+	for i, q := range questions {
+		ids[i] = q.ID
+	}
+	var transitions []models.QuestionTransition
+	if len(ids) > 0 {
+		if err := db.Where("question_id IN ?", ids).Order("id").Find(&transitions).Error; err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to fetch question history")
+			return
+		}
+	}
+	latest := make(map[uint]*models.QuestionTransition, len(transitions))
+	for i := range transitions {
+		latest[transitions[i].QuestionID] = &transitions[i]
+	}
+
+	items := []queueItem{}
+	for _, q := range questions {
+		response, err := q.ToResponse()
+		if err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to parse question options")
+			return
+		}
+		items = append(items, queueItem{QuestionResponse: response, LastTransition: latest[q.ID]})
+	}
+
+	utils.SuccessResponse(c, items, "Queue retrieved successfully")
+}
@@ -0,0 +1,120 @@
+// Package i18n negotiates the locale questions are served in. Locales are
+// BCP 47 language tags in canonical form, such as "ja" or "es-419".
+package i18n
+
+import (
+	"fmt"
+
+	"aws-rds-quiz-backend/config"
+
+	"golang.org/x/text/language"
+)
+
+// Locales holds the source locale and the locales translations are kept for
+type Locales struct {
+	source  string
+	targets []string
+}
+
+// NewLocales canonicalizes the configured locales
+func NewLocales(cfg config.TranslationsConfig) (*Locales, error) {
+	source, err := Canonical(cfg.SourceLocale)
+	if err != nil {
+		return nil, err
+	}
+	l := &Locales{source: source}
+	for _, locale := range cfg.Locales {
+		target, err := Canonical(locale)
+		if err != nil {
+			return nil, err
+		}
+		if target == source {
+			return nil, fmt.Errorf("translation locale %s is the source locale", target)
+		}
+		l.targets = append(l.targets, target)
+	}
+	return l, nil
+}
+
+// Source returns the locale questions are written in
+func (l *Locales) Source() string {
+	return l.source
+}
+
+// Targets returns the configured translation locales
+func (l *Locales) Targets() []string {
+	return l.targets
+}
+
+// Allows reports whether translations may be added for a canonical locale
+func (l *Locales) Allows(locale string) bool {
+	if locale == l.source {
+		return false
+	}
+	if len(l.targets) == 0 {
+		return true
+	}
+	for _, target := range l.targets {
+		if target == locale {
+			return true
+		}
+	}
+	return false
+}
+
+// Chain returns the fallback chain for a request; see Chain
+func (l *Locales) Chain(lang, acceptLanguage string) ([]string, error) {
+	return Chain(lang, acceptLanguage, l.source)
+}
+
+// wildcard is what ParseAcceptLanguage makes of the "*" wildcard
+var wildcard = language.MustParse("mul")
+
+// Canonical returns the canonical form of a locale, e.g. "pt-br" -> "pt-BR"
+func Canonical(locale string) (string, error) {
+	tag, err := language.Parse(locale)
+	if err != nil || tag == language.Und {
+		return "", fmt.Errorf("invalid locale %q", locale)
+	}
+	return tag.String(), nil
+}
+
+// Chain returns the locales to try, best first, for a ?lang= value or, when
+// that is empty, an Accept-Language header. Each requested locale is
+// followed by the locales it falls back to (es-MX, es-419, es). The chain
+// ends where it reaches the source locale, since the question itself is
+// written in it. An invalid lang is an error; an invalid header is ignored.
+func Chain(lang, acceptLanguage, source string) ([]string, error) {
+	var (
+		tags []language.Tag
+		q    []float32
+		err  error
+	)
+	if lang != "" {
+		if tags, q, err = language.ParseAcceptLanguage(lang); err != nil {
+			return nil, fmt.Errorf("invalid lang %q", lang)
+		}
+	} else if acceptLanguage != "" {
+		tags, q, _ = language.ParseAcceptLanguage(acceptLanguage)
+	}
+
+	seen := map[string]bool{}
+	var chain []string
+	// ParseAcceptLanguage sorts the tags by descending weight
+	for i := range tags {
+		if q[i] <= 0 || tags[i] == wildcard {
+			continue
+		}
+		for tag := tags[i]; tag != language.Und; tag = tag.Parent() {
+			locale := tag.String()
+			if locale == source {
+				return chain, nil
+			}
+			if !seen[locale] {
+				seen[locale] = true
+				chain = append(chain, locale)
+			}
+		}
+	}
+	return chain, nil
+}
@@ -0,0 +1,236 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"aws-rds-quiz-backend/attachments"
+	"aws-rds-quiz-backend/formats"
+	"aws-rds-quiz-backend/models"
+	"aws-rds-quiz-backend/similarity"
+
+	"gorm.io/gorm"
+)
+
+// Row statuses reported by Import
+const (
+	StatusCreated  = "created"
+	StatusValid    = "valid" // passed validation but was not written
+	StatusSkipped  = "skipped"
+	StatusRejected = "rejected"
+)
+
+// Options controls how an import is applied
+type Options struct {
+	// DryRun validates every row without writing anything
+	DryRun bool
+	// Partial inserts the valid rows even when other rows were rejected.
+	// By default a single rejected row aborts the whole import.
+	Partial bool
+	// Publish makes the imported questions live immediately instead of
+	// creating drafts that go through review
+	Publish bool
+	// Author is recorded as the author of the imported questions
+	Author string
+	// Bank receives the imported questions; duplicates are only looked for
+	// among its questions
+	Bank *models.Bank
+	// Store receives the attachments embedded in the file. Rows carrying
+	// attachments are rejected without one.
+	Store *attachments.Store
+}
+
+// RowResult describes what happened to a single imported row
+type RowResult struct {
+	Line     int      `json:"line"`
+	Source   string   `json:"source,omitempty"`
+	Status   string   `json:"status"`
+	ID       uint     `json:"id,omitempty"`
+	Question string   `json:"question"`
+	Reason   string   `json:"reason,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// Report summarizes an import
+type Report struct {
+	Committed bool        `json:"committed"`
+	Created   int         `json:"created"`
+	Skipped   int         `json:"skipped"`
+	Rejected  int         `json:"rejected"`
+	Rows      []RowResult `json:"rows"`
+}
+
+// Import validates rows and inserts the valid ones in a single transaction.
+// Rows whose question text already exists in the bank, or earlier in the
+// same file, are skipped; rows that closely resemble one get a warning.
+func Import(db *gorm.DB, rows []formats.Row, opts Options) (*Report, error) {
+	existing, err := existingQuestions(db.Scopes(models.InBank(opts.Bank.ID)))
+	if err != nil {
+		return nil, err
+	}
+	index, err := similarity.Bank(db.Scopes(models.InBank(opts.Bank.ID)))
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{Rows: make([]RowResult, len(rows))}
+	pending := make([]int, 0, len(rows))
+
+	for i := range rows {
+		row := &rows[i]
+		result := &report.Rows[i]
+		result.Line = row.Line
+		result.Source = row.Source
+		result.Warnings = row.Warnings
+
+		if row.Err != nil {
+			result.Question = row.Question.Question
+			reject(report, result, row.Err.Error())
+			continue
+		}
+
+		row.Question.Normalize()
+		result.Question = row.Question.Question
+		if err := opts.Bank.Check(&row.Question); err != nil {
+			reject(report, result, err.Error())
+			continue
+		}
+		reason, err := attachmentProblem(db, row, opts.Store)
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			reject(report, result, reason)
+			continue
+		}
+
+		key := questionKey(row.Question.Question)
+		if line, ok := existing[key]; ok {
+			result.Status = StatusSkipped
+			if line > 0 {
+				result.Reason = fmt.Sprintf("duplicate of line %d", line)
+			} else {
+				result.Reason = "question already exists"
+			}
+			report.Skipped++
+			continue
+		}
+		existing[key] = row.Line
+
+		doc := similarity.FromRequest(0, row.Line, &row.Question)
+		for _, match := range index.Similar(doc, similarity.DefaultThreshold) {
+			result.Warnings = append(result.Warnings, duplicateWarning(match))
+		}
+		index.Add(doc)
+
+		result.Status = StatusValid
+		pending = append(pending, i)
+	}
+
+	if opts.DryRun || len(pending) == 0 || (report.Rejected > 0 && !opts.Partial) {
+		return report, nil
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, i := range pending {
+			question := models.Question{BankID: opts.Bank.ID, Source: models.SourceImport, Status: models.StatusDraft, Author: opts.Author}
+			if opts.Publish {
+				question.Status = models.StatusPublished
+			}
+			for _, f := range rows[i].Files {
+				if _, err := opts.Store.Save(tx, bytes.NewReader(f.Data), f.Filename); err != nil {
+					return fmt.Errorf("line %d: %v", rows[i].Line, err)
+				}
+			}
+			if err := rows[i].Question.ApplyTo(&question); err != nil {
+				return fmt.Errorf("line %d: %v", rows[i].Line, err)
+			}
+			if err := tx.Create(&question).Error; err != nil {
+				return fmt.Errorf("line %d: %v", rows[i].Line, err)
+			}
+			report.Rows[i].ID = question.ID
+		}
+		return nil
+	})
+	if err != nil {
+		for _, i := range pending {
+			report.Rows[i].ID = 0
+		}
+		return report, err
+	}
+
+	for _, i := range pending {
+		report.Rows[i].Status = StatusCreated
+	}
+	report.Created = len(pending)
+	report.Committed = true
+	return report, nil
+}
+
+// attachmentProblem returns why a row's attachment references cannot be
+// imported: each must name a file carried by the row that the store accepts,
+// or an attachment that is already stored
+func attachmentProblem(db *gorm.DB, row *formats.Row, store *attachments.Store) (string, error) {
+	carried := make(map[string]formats.File, len(row.Files))
+	for _, f := range row.Files {
+		carried[f.SHA256] = f
+	}
+
+	var stored []string
+	for _, sha := range row.Question.AttachmentHashes() {
+		f, ok := carried[sha]
+		if !ok {
+			stored = append(stored, sha)
+			continue
+		}
+		if store == nil {
+			return "attachments cannot be imported here", nil
+		}
+		if _, err := store.Check(f.Data); err != nil {
+			return fmt.Sprintf("attachment %s: %v", sha, err), nil
+		}
+	}
+
+	missing, err := models.MissingAttachments(db, stored)
+	if err != nil {
+		return "", err
+	}
+	if len(missing) > 0 {
+		return "unknown attachment " + missing[0], nil
+	}
+	return "", nil
+}
+
+func reject(report *Report, result *RowResult, reason string) {
+	result.Status = StatusRejected
+	result.Reason = reason
+	report.Rejected++
+}
+
+// duplicateWarning describes a likely duplicate of a row
+func duplicateWarning(match similarity.Match) string {
+	if match.ID == 0 {
+		return fmt.Sprintf("possible duplicate of line %d (similarity %.2f)", match.Line, match.Similarity)
+	}
+	return fmt.Sprintf("possible duplicate of question %d (similarity %.2f)", match.ID, match.Similarity)
+}
+
+// existingQuestions indexes the current bank by normalized question text
+func existingQuestions(db *gorm.DB) (map[string]int, error) {
+	var texts []string
+	if err := db.Model(&models.Question{}).Pluck("question", &texts).Error; err != nil {
+		return nil, fmt.Errorf("failed to load existing questions: %v", err)
+	}
+
+	existing := make(map[string]int, len(texts))
+	for _, text := range texts {
+		existing[questionKey(text)] = 0
+	}
+	return existing, nil
+}
+
+// questionKey normalizes case and whitespace for duplicate detection
+func questionKey(text string) string {
+	return strings.Join(strings.Fields(strings.ToLower(text)), " ")
+}
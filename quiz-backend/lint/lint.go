@@ -0,0 +1,301 @@
+// Package lint checks the question bank for structural problems that make
+// questions easier to guess or harder to learn from.
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"aws-rds-quiz-backend/config"
+	"aws-rds-quiz-backend/models"
+)
+
+// Severities of findings. Errors are questions that cannot be answered
+// correctly as stored; warnings are questions that could be better.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Rule IDs
+const (
+	RuleAnswerOutOfRange    = "answer-out-of-range"
+	RuleDuplicateOptions    = "duplicate-options"
+	RuleAllNoneOfAbove      = "all-none-of-the-above"
+	RuleLongOption          = "long-option"
+	RuleMissingExplanation  = "missing-explanation"
+	RuleAnswerPositionSkew  = "answer-position-skew"
+	RuleUnknownSuppressions = "unknown-suppression"
+)
+
+// Rule describes a check
+type Rule struct {
+	ID          string `json:"id"`
+	Severity    string `json:"severity"`
+	Description string `json:"description"`
+}
+
+// Rules lists every check in the order they run
+var Rules = []Rule{
+	{RuleAnswerOutOfRange, SeverityError, "The correct answer points past the end of the options"},
+	{RuleDuplicateOptions, SeverityError, "Two options have the same text"},
+	{RuleAllNoneOfAbove, SeverityWarning, "An option reads \"all of the above\" or \"none of the above\""},
+	{RuleLongOption, SeverityWarning, "One option is much longer than the others, which gives the answer away"},
+	{RuleMissingExplanation, SeverityWarning, "The question has no explanation"},
+	{RuleAnswerPositionSkew, SeverityWarning, "Across the bank, too many single choice answers sit at the same position"},
+	{RuleUnknownSuppressions, SeverityWarning, "lintIgnore names a rule that does not exist"},
+}
+
+// Finding is one problem found. QuestionID is 0 for findings about the bank
+// as a whole.
+type Finding struct {
+	Rule       string `json:"rule"`
+	Severity   string `json:"severity"`
+	QuestionID uint   `json:"questionId,omitempty"`
+	Message    string `json:"message"`
+}
+
+// Report is the outcome of linting a set of questions
+type Report struct {
+	Questions  int            `json:"questions"`
+	Rules      []string       `json:"rules"`
+	Errors     int            `json:"errors"`
+	Warnings   int            `json:"warnings"`
+	Suppressed int            `json:"suppressed"`
+	Counts     map[string]int `json:"counts"`
+	Findings   []Finding      `json:"findings"`
+}
+
+// aboveOption matches "all of the above", "none of the above" and
+// variations such as "Both A and B"
+var aboveOption = regexp.MustCompile(`(?i)^\s*(all|none|both)\b.*\b(above|a and b|of these)\W*$`)
+
+// questionCheck inspects a single question
+type questionCheck func(q *models.Question, options []string, cfg config.LintConfig) []string
+
+var questionChecks = map[string]questionCheck{
+	RuleAnswerOutOfRange:   checkAnswerRange,
+	RuleDuplicateOptions:   checkDuplicateOptions,
+	RuleAllNoneOfAbove:     checkAboveOptions,
+	RuleLongOption:         checkLongOption,
+	RuleMissingExplanation: checkExplanation,
+}
+
+// Run lints questions with the rules enabled by cfg. only, when not empty,
+// restricts the run to those rules.
+func Run(questions []models.Question, cfg config.LintConfig, only []string) (*Report, error) {
+	enabled, err := enabledRules(cfg.Disabled, only)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{Questions: len(questions), Counts: map[string]int{}, Findings: []Finding{}}
+	for _, rule := range Rules {
+		if enabled[rule.ID] {
+			report.Rules = append(report.Rules, rule.ID)
+		}
+	}
+
+	var positioned []*models.Question
+	for i := range questions {
+		q := &questions[i]
+		ignored := map[string]bool{}
+		for _, id := range q.DecodeLintIgnore() {
+			ignored[id] = true
+			if ruleByID(id) == nil && enabled[RuleUnknownSuppressions] {
+				report.add(Finding{Rule: RuleUnknownSuppressions, QuestionID: q.ID,
+					Message: fmt.Sprintf("lintIgnore names unknown rule %q", id)})
+			}
+		}
+
+		options, err := q.DecodeOptions()
+		if err != nil {
+			options = nil
+		}
+		for _, rule := range Rules {
+			check, ok := questionChecks[rule.ID]
+			if !ok || !enabled[rule.ID] {
+				continue
+			}
+			for _, message := range check(q, options, cfg) {
+				if ignored[rule.ID] {
+					report.Suppressed++
+					continue
+				}
+				report.add(Finding{Rule: rule.ID, QuestionID: q.ID, Message: message})
+			}
+		}
+
+		inRange := q.CorrectAnswer >= 0 && q.CorrectAnswer < len(options)
+		if q.QuestionType() == models.QuestionTypeSingle && len(options) > 1 && inRange && !ignored[RuleAnswerPositionSkew] {
+			positioned = append(positioned, q)
+		}
+	}
+
+	if enabled[RuleAnswerPositionSkew] {
+		for _, message := range checkAnswerPositions(positioned, cfg) {
+			report.add(Finding{Rule: RuleAnswerPositionSkew, Message: message})
+		}
+	}
+	return report, nil
+}
+
+func (r *Report) add(finding Finding) {
+	finding.Severity = ruleByID(finding.Rule).Severity
+	if finding.Severity == SeverityError {
+		r.Errors++
+	} else {
+		r.Warnings++
+	}
+	r.Counts[finding.Rule]++
+	r.Findings = append(r.Findings, finding)
+}
+
+// enabledRules resolves the configured and requested rules, rejecting
+// unknown IDs
+func enabledRules(disabled, only []string) (map[string]bool, error) {
+	enabled := map[string]bool{}
+	for _, rule := range Rules {
+		enabled[rule.ID] = len(only) == 0
+	}
+	for _, id := range only {
+		if ruleByID(id) == nil {
+			return nil, fmt.Errorf("unknown lint rule %q", id)
+		}
+		enabled[id] = true
+	}
+	for _, id := range disabled {
+		if ruleByID(id) == nil {
+			return nil, fmt.Errorf("unknown lint rule %q", id)
+		}
+		enabled[id] = false
+	}
+	return enabled, nil
+}
+
+func ruleByID(id string) *Rule {
+	for i := range Rules {
+		if Rules[i].ID == id {
+			return &Rules[i]
+		}
+	}
+	return nil
+}
+
+// checkAnswerRange checks the correct answers of choice questions
+func checkAnswerRange(q *models.Question, options []string, cfg config.LintConfig) []string {
+	switch q.QuestionType() {
+	case models.QuestionTypeSingle:
+		if q.CorrectAnswer < 0 || q.CorrectAnswer >= len(options) {
+			return []string{fmt.Sprintf("correctAnswer %d is out of range for %d options", q.CorrectAnswer, len(options))}
+		}
+	case models.QuestionTypeMultiple:
+		correct, err := q.DecodeCorrectAnswers()
+		if err != nil {
+			return []string{fmt.Sprintf("correctAnswers cannot be parsed: %v", err)}
+		}
+		var messages []string
+		for _, index := range correct {
+			if index < 0 || index >= len(options) {
+				messages = append(messages, fmt.Sprintf("correctAnswers entry %d is out of range for %d options", index, len(options)))
+			}
+		}
+		return messages
+	}
+	return nil
+}
+
+// checkDuplicateOptions compares options ignoring case and spacing
+func checkDuplicateOptions(q *models.Question, options []string, cfg config.LintConfig) []string {
+	var messages []string
+	seen := map[string]int{}
+	for i, option := range options {
+		key := strings.Join(strings.Fields(strings.ToLower(option)), " ")
+		if first, ok := seen[key]; ok {
+			messages = append(messages, fmt.Sprintf("options %d and %d are both %q", first, i, option))
+			continue
+		}
+		seen[key] = i
+	}
+	return messages
+}
+
+// checkAboveOptions flags options that refer to the other options
+func checkAboveOptions(q *models.Question, options []string, cfg config.LintConfig) []string {
+	if !isChoice(q) {
+		return nil
+	}
+	var messages []string
+	for i, option := range options {
+		if aboveOption.MatchString(option) {
+			messages = append(messages, fmt.Sprintf("option %d %q depends on the other options", i, option))
+		}
+	}
+	return messages
+}
+
+// checkLongOption flags an option more than cfg.LongOptionRatio times as
+// long as the average of the others
+func checkLongOption(q *models.Question, options []string, cfg config.LintConfig) []string {
+	if !isChoice(q) || len(options) < 3 {
+		return nil
+	}
+	longest, total := 0, 0
+	for i, option := range options {
+		total += len(option)
+		if len(option) > len(options[longest]) {
+			longest = i
+		}
+	}
+	others := float64(total-len(options[longest])) / float64(len(options)-1)
+	if others > 0 && float64(len(options[longest])) > cfg.LongOptionRatio*others {
+		return []string{fmt.Sprintf("option %d is %.1f times as long as the average of the others", longest, float64(len(options[longest]))/others)}
+	}
+	return nil
+}
+
+func checkExplanation(q *models.Question, options []string, cfg config.LintConfig) []string {
+	if strings.TrimSpace(q.Explanation) == "" {
+		return []string{"question has no explanation"}
+	}
+	return nil
+}
+
+// checkAnswerPositions reports answer positions used by more than
+// cfg.MaxAnswerPositionShare of the single choice questions
+func checkAnswerPositions(questions []*models.Question, cfg config.LintConfig) []string {
+	if len(questions) < cfg.MinAnswerPositionCount {
+		return nil
+	}
+	counts := map[int]int{}
+	optionTotal := 0
+	for _, q := range questions {
+		counts[q.CorrectAnswer]++
+		options, _ := q.DecodeOptions()
+		optionTotal += len(options)
+	}
+	expected := float64(len(questions)) / float64(optionTotal)
+
+	positions := make([]int, 0, len(counts))
+	for position := range counts {
+		positions = append(positions, position)
+	}
+	sort.Ints(positions)
+
+	var messages []string
+	for _, position := range positions {
+		share := float64(counts[position]) / float64(len(questions))
+		if share > cfg.MaxAnswerPositionShare {
+			messages = append(messages, fmt.Sprintf("%.0f%% of %d single choice questions (%d) have the correct answer at position %c (index %d); expected about %.0f%%",
+				share*100, len(questions), counts[position], 'A'+rune(position), position, expected*100))
+		}
+	}
+	return messages
+}
+
+func isChoice(q *models.Question) bool {
+	t := q.QuestionType()
+	return t == models.QuestionTypeSingle || t == models.QuestionTypeMultiple
+}
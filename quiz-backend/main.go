@@ -1,27 +1,80 @@
 package main
 
 import (
+	"crypto/rand"
 	"log"
 	"net/http"
+	"os"
+	"time"
 
+	"aws-rds-quiz-backend/attachments"
 	"aws-rds-quiz-backend/config"
 	"aws-rds-quiz-backend/database"
+	"aws-rds-quiz-backend/filebank"
 	"aws-rds-quiz-backend/handlers"
+	"aws-rds-quiz-backend/i18n"
 	"aws-rds-quiz-backend/middleware"
+	"aws-rds-quiz-backend/models"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 func main() {
 	// Load configuration
 	cfg := config.LoadConfig()
 
+	// Run a subcommand instead of the server when one is given
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(cfg, os.Args[1], os.Args[2:]))
+	}
+
+	serve(cfg)
+}
+
+// serve runs the HTTP API server
+func serve(cfg *config.Config) {
 	// Initialize database
 	db, err := database.InitDB(cfg.Database)
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
 
+	// Sync and watch the Markdown question directory, if configured
+	if cfg.QuestionFiles.Dir != "" {
+		bank := filebank.New(db, cfg.QuestionFiles.Dir)
+		interval := time.Duration(cfg.QuestionFiles.PollInterval) * time.Second
+		if err := bank.SyncAndWatch(interval); err != nil {
+			log.Fatal("Failed to load question files:", err)
+		}
+	}
+
+	store := attachments.NewStore(cfg.Attachments)
+	locales, err := i18n.NewLocales(cfg.Translations)
+	if err != nil {
+		log.Fatal("Invalid translation locales:", err)
+	}
+	if !models.IsReviewPolicy(cfg.Exam.DefaultReview) {
+		log.Fatal("Invalid EXAM_REVIEW_POLICY: ", cfg.Exam.DefaultReview)
+	}
+	if cfg.Exam.TimeLimit < 0 || cfg.Exam.TimeLimit > models.MaxTimeLimit {
+		log.Fatal("Invalid EXAM_TIME_LIMIT: ", cfg.Exam.TimeLimit)
+	}
+	if cfg.Exam.WithholdAnswers && cfg.Exam.DefaultReview == models.ReviewAfterClose && cfg.Exam.TimeLimit == 0 {
+		log.Fatal("EXAM_REVIEW_POLICY=after_close needs an EXAM_TIME_LIMIT while answers are withheld")
+	}
+	if cfg.Exam.WithholdAnswers && cfg.Exam.EditorToken == "" {
+		log.Println("WARNING: EXAM_EDITOR_TOKEN is not set; the authoring routes are closed while answers are withheld")
+	}
+	if cfg.Templates.VariantSecret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			log.Fatal("Failed to generate a variant secret:", err)
+		}
+		cfg.Templates.VariantSecret = string(secret)
+		log.Println("TEMPLATE_VARIANT_SECRET is not set; servedVariant tokens will not survive a restart")
+	}
+
 	// Set Gin mode
 	gin.SetMode(gin.ReleaseMode)
 
@@ -38,14 +91,52 @@ func main() {
 	// API v1 routes
 	v1 := r.Group("/api/v1")
 	{
-		// Questions endpoints
-		v1.GET("/questions", handlers.GetAllQuestions(db))
-		v1.GET("/questions/random", handlers.GetRandomQuestions(db))
-		v1.GET("/questions/:id", handlers.GetQuestionByID(db))
+		// In exam mode the authoring and administration routes need the
+		// editor token
+		editor := handlers.EditorsOnly(cfg.Exam)
 
-		// Quiz endpoints
-		v1.POST("/quiz/submit", handlers.SubmitQuiz(db))
-		v1.GET("/quiz/results/:id", handlers.GetQuizResult(db))
+		// Bank endpoints
+		v1.GET("/banks", handlers.GetBanks(db))
+		v1.POST("/banks", editor, handlers.CreateBank(db))
+		v1.GET("/banks/:bank", handlers.GetBank(db))
+		v1.PUT("/banks/:bank", editor, handlers.UpdateBank(db))
+		v1.DELETE("/banks/:bank", editor, handlers.DeleteBank(db))
+
+		// Question and quiz endpoints of each bank; the unscoped routes
+		// serve the default bank
+		bankRoutes(v1.Group("/banks/:bank", handlers.ResolveBank(db)), cfg, db, store, locales)
+		bankRoutes(v1.Group("", handlers.ResolveBank(db)), cfg, db, store, locales)
+
+		// Attachment endpoints
+		v1.POST("/attachments", handlers.UploadAttachment(db, store))
+		v1.GET("/attachments/:sha256", handlers.GetAttachment(db, store))
+
+		// Editorial queues
+		v1.GET("/authors/:user/queue", editor, handlers.GetAuthorQueue(db))
+		v1.GET("/reviewers/:user/queue", editor, handlers.GetReviewerQueue(db))
+
+		// Taxonomy endpoints
+		v1.GET("/tags", handlers.GetTags(db))
+		v1.POST("/tags", handlers.CreateTag(db))
+		v1.PUT("/tags/:id", handlers.UpdateTag(db))
+		v1.DELETE("/tags/:id", handlers.DeleteTag(db))
+
+		v1.GET("/topics", handlers.GetTopics(db))
+		v1.GET("/topics/:id", handlers.GetTopic(db))
+		v1.POST("/topics", handlers.CreateTopic(db))
+		v1.PUT("/topics/:id", handlers.UpdateTopic(db))
+		v1.DELETE("/topics/:id", handlers.DeleteTopic(db))
+
+		v1.GET("/exam-domains", handlers.GetExamDomains(db))
+		v1.POST("/exam-domains", handlers.CreateExamDomain(db))
+		v1.PUT("/exam-domains/:id", handlers.UpdateExamDomain(db))
+		v1.DELETE("/exam-domains/:id", handlers.DeleteExamDomain(db))
+
+		// Trash of soft-deleted questions and submissions
+		v1.GET("/admin/trash", editor, handlers.GetTrash(db, cfg.Trash))
+		v1.DELETE("/admin/trash", editor, handlers.PurgeTrash(db, cfg.Trash))
+		v1.POST("/admin/trash/:kind/:id/restore", editor, handlers.RestoreTrashItem(db))
+		v1.DELETE("/admin/trash/:kind/:id", editor, handlers.PurgeTrashItem(db, cfg.Trash))
 	}
 
 	// Start server
@@ -56,3 +147,49 @@ func main() {
 		log.Fatal("Failed to start server:", err)
 	}
 }
+
+// bankRoutes registers the question, translation report and quiz endpoints
+// of a bank on g, whose middleware resolves the bank
+func bankRoutes(g *gin.RouterGroup, cfg *config.Config, db *gorm.DB, store *attachments.Store, locales *i18n.Locales) {
+	// Questions endpoints. In exam mode the learner routes withhold the
+	// answers and the authoring routes need the editor token.
+	exam := handlers.ExamMode(cfg.Exam)
+	editor := handlers.EditorsOnly(cfg.Exam)
+
+	g.GET("/questions", exam, handlers.GetAllQuestions(db, locales))
+	g.GET("/questions/random", exam, handlers.GetRandomQuestions(db, locales, cfg.Templates))
+	g.GET("/questions/search", exam, handlers.SearchQuestions(db, locales))
+	g.GET("/questions/duplicates", editor, handlers.GetDuplicateClusters(db))
+	g.GET("/questions/lint", editor, handlers.LintQuestions(db, cfg.Lint))
+	g.GET("/questions/export", editor, handlers.ExportQuestions(db, store))
+	g.GET("/questions/:id", exam, handlers.GetQuestionByID(db, locales, cfg.Templates))
+	g.GET("/questions/:id/revisions", editor, handlers.GetQuestionRevisions(db))
+	g.POST("/questions", editor, handlers.CreateQuestion(db))
+	g.POST("/questions/import", editor, handlers.ImportQuestions(db, store))
+	g.PUT("/questions/:id", editor, handlers.UpdateQuestion(db))
+	g.PATCH("/questions/:id", editor, handlers.PatchQuestion(db))
+	g.DELETE("/questions/:id", editor, handlers.DeleteQuestion(db))
+	g.GET("/questions/:id/transitions", editor, handlers.GetQuestionTransitions(db))
+	g.POST("/questions/:id/transitions", editor, handlers.TransitionQuestion(db))
+	g.GET("/questions/:id/translations", editor, handlers.GetQuestionTranslations(db))
+	g.PUT("/questions/:id/translations/:locale", editor, handlers.PutQuestionTranslation(db, locales))
+	g.DELETE("/questions/:id/translations/:locale", editor, handlers.DeleteQuestionTranslation(db))
+	g.GET("/questions/:id/reports", handlers.GetQuestionReports(db))
+	g.POST("/questions/:id/reports", handlers.ReportQuestion(db))
+	g.GET("/questions/:id/votes", handlers.GetExplanationVotes(db))
+	g.PUT("/questions/:id/votes", handlers.VoteExplanation(db))
+
+	g.GET("/reports", handlers.GetReports(db))
+	g.PATCH("/reports/:id", handlers.ModerateReport(db))
+
+	// Translation reports
+	g.GET("/translations/coverage", handlers.GetTranslationCoverage(db, locales))
+	g.GET("/translations/pending", handlers.GetPendingTranslations(db))
+
+	// Quiz endpoints
+	g.POST("/quiz/sessions", exam, handlers.CreateQuizSession(db, locales, cfg.Exam))
+	g.POST("/quiz/sessions/:id/submit", handlers.SubmitQuizSession(db))
+	g.POST("/quiz/submit", exam, handlers.SubmitQuiz(db, cfg.Templates))
+	g.GET("/quiz/results/:id", handlers.GetQuizResult(db))
+	g.DELETE("/quiz/results/:id", handlers.DeleteQuizResult(db))
+}
@@ -0,0 +1,270 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"aws-rds-quiz-backend/sqlsandbox"
+)
+
+// Fill-in-the-blank match modes
+const (
+	MatchNormalized = "normalized" // case, whitespace and surrounding punctuation are ignored
+	MatchExact      = "exact"      // the answer must equal an accepted answer exactly
+	MatchRegex      = "regex"      // the answer must fully match an accepted pattern
+)
+
+// AllowedMatchModes lists the fill-in-the-blank match modes
+var AllowedMatchModes = []string{MatchNormalized, MatchExact, MatchRegex}
+
+// TrueFalseKey is the answer key of a true_false question
+type TrueFalseKey struct {
+	Answer bool `json:"answer"`
+}
+
+// OrderingKey is the answer key of an ordering question. The question's
+// options are the items as displayed; Order lists their indices in the
+// correct sequence.
+type OrderingKey struct {
+	Order []int `json:"order"`
+}
+
+// MatchingKey is the answer key of a matching question. The question's
+// options are the prompts; Targets are the choices they are matched to (extra
+// targets act as distractors) and Pairs[i] is the target index for prompt i.
+type MatchingKey struct {
+	Targets []string `json:"targets"`
+	Pairs   []int    `json:"pairs"`
+}
+
+// FillBlankKey is the answer key of a fill_blank question
+type FillBlankKey struct {
+	Accepted      []string `json:"accepted"`
+	Match         string   `json:"match"`
+	CaseSensitive bool     `json:"caseSensitive,omitempty"`
+}
+
+// SQLKey is the answer key of a sql question. Fixture is the SQL that builds
+// and fills the tables; the learner's query must return the same result set
+// as Reference.
+type SQLKey struct {
+	Fixture     string `json:"fixture"`
+	Reference   string `json:"reference"`
+	IgnoreOrder bool   `json:"ignoreOrder,omitempty"`
+}
+
+// DecodeAnswerKey parses the question's answer key into v
+func (q *Question) DecodeAnswerKey(v interface{}) error {
+	if q.AnswerKey == "" {
+		return fmt.Errorf("question has no answer key")
+	}
+	return json.Unmarshal([]byte(q.AnswerKey), v)
+}
+
+// MatchingTargets returns the targets of a matching question, which clients
+// need to render it
+func (q *Question) MatchingTargets() []string {
+	if q.QuestionType() != QuestionTypeMatching {
+		return nil
+	}
+	var key MatchingKey
+	if err := q.DecodeAnswerKey(&key); err != nil {
+		return nil
+	}
+	return key.Targets
+}
+
+// decodeKey strictly decodes the request's answer key into v
+func (r *QuestionRequest) decodeKey(v interface{}) error {
+	if len(r.AnswerKey) == 0 || string(r.AnswerKey) == "null" {
+		return fmt.Errorf("%s questions need an answerKey", r.Type)
+	}
+	decoder := json.NewDecoder(strings.NewReader(string(r.AnswerKey)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("invalid answerKey: %v", err)
+	}
+	return nil
+}
+
+// setKey stores v as the request's canonical answer key
+func (r *QuestionRequest) setKey(v interface{}) {
+	data, err := json.Marshal(v)
+	if err == nil {
+		r.AnswerKey = data
+	}
+}
+
+// normalizeKey rewrites the answer key in canonical form and derives the
+// fields the key implies. Keys that fail to decode are left for validateKey
+// to report.
+func (r *QuestionRequest) normalizeKey() {
+	switch r.Type {
+	case QuestionTypeTrueFalse:
+		var key TrueFalseKey
+		if r.decodeKey(&key) != nil {
+			return
+		}
+		r.Options = []string{"True", "False"}
+		r.CorrectAnswer = 1
+		if key.Answer {
+			r.CorrectAnswer = 0
+		}
+		r.setKey(key)
+	case QuestionTypeOrdering:
+		var key OrderingKey
+		if r.decodeKey(&key) == nil {
+			r.setKey(key)
+		}
+	case QuestionTypeMatching:
+		var key MatchingKey
+		if r.decodeKey(&key) == nil {
+			for i := range key.Targets {
+				key.Targets[i] = strings.TrimSpace(key.Targets[i])
+			}
+			r.setKey(key)
+		}
+	case QuestionTypeFillBlank:
+		r.clearOptions()
+		var key FillBlankKey
+		if r.decodeKey(&key) == nil {
+			for i := range key.Accepted {
+				key.Accepted[i] = strings.TrimSpace(key.Accepted[i])
+			}
+			key.Match = strings.ToLower(strings.TrimSpace(key.Match))
+			if key.Match == "" {
+				key.Match = MatchNormalized
+			}
+			r.setKey(key)
+		}
+	case QuestionTypeNumeric:
+		r.clearOptions()
+		var key NumericKey
+		if r.decodeKey(&key) == nil {
+			key.ToleranceMode = strings.ToLower(strings.TrimSpace(key.ToleranceMode))
+			if key.ToleranceMode == "" {
+				key.ToleranceMode = ToleranceAbsolute
+			}
+			key.Unit = strings.TrimSpace(key.Unit)
+			r.setKey(key)
+		}
+	case QuestionTypeSQL:
+		r.clearOptions()
+		var key SQLKey
+		if r.decodeKey(&key) == nil {
+			key.Fixture = strings.TrimSpace(key.Fixture)
+			key.Reference = strings.TrimSpace(key.Reference)
+			r.setKey(key)
+		}
+	default:
+		r.AnswerKey = nil
+	}
+}
+
+// clearOptions stores an empty option list for types without options, so
+// that options given by mistake still fail validation
+func (r *QuestionRequest) clearOptions() {
+	if r.Options == nil {
+		r.Options = []string{}
+	}
+}
+
+// validateKey checks the answer key against the question's options
+func (r *QuestionRequest) validateKey() error {
+	switch r.Type {
+	case QuestionTypeTrueFalse:
+		var key TrueFalseKey
+		return r.decodeKey(&key)
+	case QuestionTypeOrdering:
+		var key OrderingKey
+		if err := r.decodeKey(&key); err != nil {
+			return err
+		}
+		if !isPermutation(key.Order, len(r.Options)) {
+			return fmt.Errorf("answerKey.order must list each option index from 0 to %d exactly once", len(r.Options)-1)
+		}
+	case QuestionTypeMatching:
+		var key MatchingKey
+		if err := r.decodeKey(&key); err != nil {
+			return err
+		}
+		if len(key.Targets) < 2 || len(key.Targets) > maxListOptions {
+			return fmt.Errorf("answerKey.targets must have between 2 and %d entries", maxListOptions)
+		}
+		for i, target := range key.Targets {
+			if target == "" {
+				return fmt.Errorf("answerKey target %d is empty", i)
+			}
+		}
+		if len(key.Pairs) != len(r.Options) {
+			return fmt.Errorf("answerKey.pairs must have one entry per option")
+		}
+		for i, target := range key.Pairs {
+			if target < 0 || target >= len(key.Targets) {
+				return fmt.Errorf("answerKey.pairs[%d] must be between 0 and %d", i, len(key.Targets)-1)
+			}
+		}
+	case QuestionTypeFillBlank:
+		var key FillBlankKey
+		if err := r.decodeKey(&key); err != nil {
+			return err
+		}
+		if len(key.Accepted) == 0 {
+			return fmt.Errorf("answerKey.accepted needs at least one answer")
+		}
+		if !contains(AllowedMatchModes, key.Match) {
+			return fmt.Errorf("answerKey.match must be one of: %s", strings.Join(AllowedMatchModes, ", "))
+		}
+		for i, accepted := range key.Accepted {
+			if accepted == "" {
+				return fmt.Errorf("answerKey.accepted[%d] is empty", i)
+			}
+			if key.Match == MatchRegex {
+				if _, err := regexp.Compile(accepted); err != nil {
+					return fmt.Errorf("answerKey.accepted[%d] is not a valid regular expression: %v", i, err)
+				}
+			}
+		}
+	case QuestionTypeNumeric:
+		var key NumericKey
+		if err := r.decodeKey(&key); err != nil {
+			return err
+		}
+		if math.IsNaN(key.Tolerance) || key.Tolerance < 0 {
+			return fmt.Errorf("answerKey.tolerance must not be negative")
+		}
+		if !contains(AllowedToleranceModes, key.ToleranceMode) {
+			return fmt.Errorf("answerKey.toleranceMode must be one of: %s", strings.Join(AllowedToleranceModes, ", "))
+		}
+	case QuestionTypeSQL:
+		var key SQLKey
+		if err := r.decodeKey(&key); err != nil {
+			return err
+		}
+		if key.Reference == "" {
+			return fmt.Errorf("answerKey.reference is required")
+		}
+		if _, err := sqlsandbox.Run(key.Fixture, key.Reference, sqlsandbox.DefaultLimits); err != nil {
+			return fmt.Errorf("invalid answerKey: %v", err)
+		}
+	}
+	return nil
+}
+
+// isPermutation reports whether values holds each of 0..n-1 exactly once
+func isPermutation(values []int, n int) bool {
+	if len(values) != n {
+		return false
+	}
+	seen := make([]bool, n)
+	for _, v := range values {
+		if v < 0 || v >= n || seen[v] {
+			return false
+		}
+		seen[v] = true
+	}
+	return true
+}
@@ -0,0 +1,156 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AttachmentURLPrefix is the API path attachments are downloaded from
+const AttachmentURLPrefix = "/api/v1/attachments/"
+
+// attachmentRef matches a Markdown image that references a stored
+// attachment by its SHA-256 hash: ![alt text](attachment:<sha256>)
+var attachmentRef = regexp.MustCompile(`!\[([^\]\n]*)\]\(attachment:([0-9a-f]{64})\)`)
+
+// Attachment is an uploaded image or diagram. The file itself lives in the
+// content-addressed attachment store under its SHA-256 hash.
+type Attachment struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	SHA256    string    `json:"sha256" gorm:"size:64;not null;uniqueIndex"`
+	MIMEType  string    `json:"mimeType" gorm:"not null"`
+	Size      int64     `json:"size" gorm:"not null"`
+	Filename  string    `json:"filename"` // as first uploaded
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// AttachmentResponse represents the API response format for an attachment
+type AttachmentResponse struct {
+	SHA256    string    `json:"sha256"`
+	URL       string    `json:"url"`
+	MIMEType  string    `json:"mimeType"`
+	Size      int64     `json:"size"`
+	Filename  string    `json:"filename,omitempty"`
+	Reference string    `json:"reference"` // Markdown to paste into a question or option
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// AttachmentRef is a reference to an attachment from a question's text or
+// one of its options
+type AttachmentRef struct {
+	SHA256 string `json:"sha256"`
+	URL    string `json:"url"`
+	Alt    string `json:"alt,omitempty"`
+	Option *int   `json:"option,omitempty"` // index of the referencing option; nil for the question text
+}
+
+// TableName specifies the table name for the Attachment model
+func (Attachment) TableName() string {
+	return "attachments"
+}
+
+// ToResponse converts an attachment into the API response format
+func (a *Attachment) ToResponse() AttachmentResponse {
+	return AttachmentResponse{
+		SHA256:    a.SHA256,
+		URL:       AttachmentURL(a.SHA256),
+		MIMEType:  a.MIMEType,
+		Size:      a.Size,
+		Filename:  a.Filename,
+		Reference: AttachmentReference("", a.SHA256),
+		CreatedAt: a.CreatedAt,
+	}
+}
+
+// AttachmentURL returns the download URL of an attachment
+func AttachmentURL(sha256 string) string {
+	return AttachmentURLPrefix + sha256
+}
+
+// AttachmentReference returns the Markdown that embeds an attachment in a
+// question or option
+func AttachmentReference(alt, sha256 string) string {
+	return fmt.Sprintf("![%s](attachment:%s)", alt, sha256)
+}
+
+// FindAttachmentRefs returns the attachment references in text in order
+func FindAttachmentRefs(text string) []AttachmentRef {
+	var refs []AttachmentRef
+	for _, m := range attachmentRef.FindAllStringSubmatch(text, -1) {
+		refs = append(refs, AttachmentRef{SHA256: m[2], URL: AttachmentURL(m[2]), Alt: m[1]})
+	}
+	return refs
+}
+
+// ReplaceAttachmentRefs replaces each attachment reference in text with the
+// result of replace. Text around the references is passed through literal,
+// which lets callers escape it for the target format.
+func ReplaceAttachmentRefs(text string, literal func(string) string, replace func(AttachmentRef) string) string {
+	var out []byte
+	last := 0
+	for _, m := range attachmentRef.FindAllStringSubmatchIndex(text, -1) {
+		out = append(out, literal(text[last:m[0]])...)
+		sha := text[m[4]:m[5]]
+		out = append(out, replace(AttachmentRef{SHA256: sha, URL: AttachmentURL(sha), Alt: text[m[2]:m[3]]})...)
+		last = m[1]
+	}
+	out = append(out, literal(text[last:])...)
+	return string(out)
+}
+
+// AttachmentRefs lists the attachments referenced by the question text and
+// options
+func (r *QuestionRequest) AttachmentRefs() []AttachmentRef {
+	refs := FindAttachmentRefs(r.Question)
+	for i, option := range r.Options {
+		for _, ref := range FindAttachmentRefs(option) {
+			index := i
+			ref.Option = &index
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// AttachmentHashes returns the distinct hashes referenced by the question
+func (r *QuestionRequest) AttachmentHashes() []string {
+	seen := map[string]bool{}
+	var hashes []string
+	for _, ref := range r.AttachmentRefs() {
+		if !seen[ref.SHA256] {
+			seen[ref.SHA256] = true
+			hashes = append(hashes, ref.SHA256)
+		}
+	}
+	return hashes
+}
+
+// MissingAttachments returns the hashes that name no stored attachment
+func MissingAttachments(db *gorm.DB, hashes []string) ([]string, error) {
+	if len(hashes) == 0 {
+		return nil, nil
+	}
+	var found []string
+	if err := db.Model(&Attachment{}).Where("sha256 IN ?", hashes).Pluck("sha256", &found).Error; err != nil {
+		return nil, fmt.Errorf("failed to look up attachments: %v", err)
+	}
+	stored := make(map[string]bool, len(found))
+	for _, sha := range found {
+		stored[sha] = true
+	}
+	var missing []string
+	for _, sha := range hashes {
+		if !stored[sha] {
+			missing = append(missing, sha)
+		}
+	}
+	return missing, nil
+}
+
+// attachmentRefs lists the attachments referenced by a stored question
+func (q *Question) attachmentRefs(options []string) []AttachmentRef {
+	req := QuestionRequest{Question: q.Question, Options: options}
+	return req.AttachmentRefs()
+}
@@ -0,0 +1,231 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultBankSlug names the bank created for the original RDS and Aurora
+// questions, which the unscoped routes serve
+const DefaultBankSlug = "rds"
+
+// bankSlug matches the URL-safe names of banks
+var bankSlug = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// Bank is a question bank for one AWS service. Categories lists the
+// categories its questions may use, the first being the default; an empty
+// list accepts any category. Owners may edit and delete the bank.
+type Bank struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Slug        string    `json:"slug" gorm:"size:64;not null;uniqueIndex"`
+	Name        string    `json:"name" gorm:"not null"`
+	Description string    `json:"description" gorm:"type:text"`
+	Categories  string    `json:"categories" gorm:"type:text"` // JSON array as string
+	Owners      string    `json:"owners" gorm:"type:text"`     // JSON array of user IDs
+	IsDefault   bool      `json:"isDefault" gorm:"not null;default:false"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// BankRequest represents the API request format for creating/updating banks
+type BankRequest struct {
+	Slug        string   `json:"slug" binding:"required"`
+	Name        string   `json:"name" binding:"required"`
+	Description string   `json:"description"`
+	Categories  []string `json:"categories"`
+	Owners      []string `json:"owners"`
+}
+
+// BankResponse represents the API response format for a bank
+type BankResponse struct {
+	ID            uint      `json:"id"`
+	Slug          string    `json:"slug"`
+	Name          string    `json:"name"`
+	Description   string    `json:"description,omitempty"`
+	Categories    []string  `json:"categories"`
+	Owners        []string  `json:"owners"`
+	IsDefault     bool      `json:"isDefault"`
+	QuestionCount int64     `json:"questionCount"` // published questions
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+// TableName specifies the table name for the Bank model
+func (Bank) TableName() string {
+	return "banks"
+}
+
+// InBank scopes a question query to one bank
+func InBank(bankID uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("questions.bank_id = ?", bankID)
+	}
+}
+
+// DefaultBank loads the bank served by the unscoped routes
+func DefaultBank(db *gorm.DB) (Bank, error) {
+	var bank Bank
+	err := db.Where("is_default = ?", true).First(&bank).Error
+	return bank, err
+}
+
+// FindBank loads the bank with the given slug, or the default bank when slug
+// is empty
+func FindBank(db *gorm.DB, slug string) (Bank, error) {
+	if slug == "" {
+		return DefaultBank(db)
+	}
+	var bank Bank
+	err := db.Where("slug = ?", slug).First(&bank).Error
+	return bank, err
+}
+
+// EnsureDefaultBank creates the default bank if there is none and moves the
+// questions and submissions that predate banks into it
+func EnsureDefaultBank(db *gorm.DB) (Bank, error) {
+	bank, err := DefaultBank(db)
+	if err == gorm.ErrRecordNotFound {
+		categories, _ := json.Marshal(AllowedCategories)
+		bank = Bank{
+			Slug:        DefaultBankSlug,
+			Name:        "Amazon RDS and Aurora",
+			Description: "Managed relational databases on AWS: Amazon RDS and Amazon Aurora.",
+			Categories:  string(categories),
+			Owners:      "[]",
+			IsDefault:   true,
+		}
+		err = db.Create(&bank).Error
+	}
+	if err != nil {
+		return bank, fmt.Errorf("failed to load default bank: %v", err)
+	}
+
+	if err := db.Unscoped().Model(&Question{}).Where("bank_id = 0").UpdateColumn("bank_id", bank.ID).Error; err != nil {
+		return bank, fmt.Errorf("failed to assign questions to the default bank: %v", err)
+	}
+	if err := db.Unscoped().Model(&QuizSubmission{}).Where("bank_id = 0").UpdateColumn("bank_id", bank.ID).Error; err != nil {
+		return bank, fmt.Errorf("failed to assign submissions to the default bank: %v", err)
+	}
+	return bank, nil
+}
+
+// Normalize trims the request and drops blank and repeated entries
+func (r *BankRequest) Normalize() {
+	r.Slug = strings.ToLower(strings.TrimSpace(r.Slug))
+	r.Name = strings.TrimSpace(r.Name)
+	r.Description = strings.TrimSpace(r.Description)
+	r.Categories = uniqueStrings(r.Categories)
+	r.Owners = uniqueStrings(r.Owners)
+}
+
+// Validate checks the bank's slug and name
+func (r *BankRequest) Validate() error {
+	if !bankSlug.MatchString(r.Slug) || len(r.Slug) > 64 {
+		return fmt.Errorf("slug must be lowercase letters and digits separated by single dashes, at most 64 characters")
+	}
+	if r.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	return nil
+}
+
+// ApplyTo copies the request fields onto a bank
+func (r *BankRequest) ApplyTo(b *Bank) error {
+	categories, err := json.Marshal(r.Categories)
+	if err != nil {
+		return fmt.Errorf("failed to marshal categories: %v", err)
+	}
+	owners, err := json.Marshal(r.Owners)
+	if err != nil {
+		return fmt.Errorf("failed to marshal owners: %v", err)
+	}
+	b.Slug = r.Slug
+	b.Name = r.Name
+	b.Description = r.Description
+	b.Categories = string(categories)
+	b.Owners = string(owners)
+	return nil
+}
+
+// DecodeCategories parses the categories column, ignoring a malformed column
+func (b *Bank) DecodeCategories() []string {
+	categories := []string{}
+	if b.Categories != "" {
+		json.Unmarshal([]byte(b.Categories), &categories)
+	}
+	return categories
+}
+
+// DecodeOwners parses the owners column, ignoring a malformed column
+func (b *Bank) DecodeOwners() []string {
+	owners := []string{}
+	if b.Owners != "" {
+		json.Unmarshal([]byte(b.Owners), &owners)
+	}
+	return owners
+}
+
+// IsOwner reports whether user may edit the bank. A bank without owners may
+// be edited by anyone.
+func (b *Bank) IsOwner(user string) bool {
+	owners := b.DecodeOwners()
+	return len(owners) == 0 || contains(owners, user)
+}
+
+// IsListedOwner reports whether user is one of the bank's owners; unlike
+// IsOwner, nobody qualifies for a bank without owners
+func (b *Bank) IsListedOwner(user string) bool {
+	return user != "" && contains(b.DecodeOwners(), user)
+}
+
+// Check fills in the bank's default category when r has none, then checks r
+// against the authoring rules and the categories the bank accepts
+func (b *Bank) Check(r *QuestionRequest) error {
+	categories := b.DecodeCategories()
+	if r.Category == "" && len(categories) > 0 {
+		r.Category = categories[0]
+	}
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	if r.Category == "" {
+		return fmt.Errorf("category is required")
+	}
+	if len(categories) > 0 && !contains(categories, r.Category) {
+		return fmt.Errorf("category must be one of: %s", strings.Join(categories, ", "))
+	}
+	return nil
+}
+
+// ToResponse converts a bank into the API response format
+func (b *Bank) ToResponse(questionCount int64) BankResponse {
+	return BankResponse{
+		ID:            b.ID,
+		Slug:          b.Slug,
+		Name:          b.Name,
+		Description:   b.Description,
+		Categories:    b.DecodeCategories(),
+		Owners:        b.DecodeOwners(),
+		IsDefault:     b.IsDefault,
+		QuestionCount: questionCount,
+		CreatedAt:     b.CreatedAt,
+		UpdatedAt:     b.UpdatedAt,
+	}
+}
+
+// uniqueStrings trims values and drops blank and repeated ones
+func uniqueStrings(values []string) []string {
+	unique := []string{}
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value != "" && !contains(unique, value) {
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,134 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Serving modes of quiz sessions
+const (
+	ModePractice = "practice" // questions are served with their answers and explanations
+	ModeExam     = "exam"     // answers and explanations are withheld until review
+)
+
+// Review policies of exam sessions: when the result of a submitted exam
+// reveals the answers and explanations
+const (
+	ReviewImmediate  = "immediate"   // as soon as the answers are submitted
+	ReviewAfterClose = "after_close" // once the session's time limit has run out
+	ReviewNever      = "never"       // only the score is ever reported
+)
+
+// MaxTimeLimit is the longest time limit a quiz session may have, in seconds
+const MaxTimeLimit = 24 * 60 * 60
+
+// AllowedModes lists the serving modes of quiz sessions
+var AllowedModes = []string{ModePractice, ModeExam}
+
+// AllowedReviewPolicies lists the review policies of exam sessions
+var AllowedReviewPolicies = []string{ReviewImmediate, ReviewAfterClose, ReviewNever}
+
+// QuizSessionRequest represents the API request format for starting a quiz
+// session. TimeLimit is in seconds; answers are not accepted once it has run
+// out. An exam with no Review or TimeLimit takes the configured default.
+type QuizSessionRequest struct {
+	Mode      string `json:"mode"`
+	Review    string `json:"review"`
+	TimeLimit int    `json:"timeLimit"`
+}
+
+// IsReviewPolicy reports whether review is a known review policy
+func IsReviewPolicy(review string) bool {
+	return contains(AllowedReviewPolicies, review)
+}
+
+// Normalize trims the request and fills in the practice mode, and for exams
+// the given default review policy and time limit
+func (r *QuizSessionRequest) Normalize(defaultReview string, defaultTimeLimit int) {
+	r.Mode = strings.ToLower(strings.TrimSpace(r.Mode))
+	r.Review = strings.ToLower(strings.TrimSpace(r.Review))
+	if r.Mode == "" {
+		r.Mode = ModePractice
+	}
+	if r.Review == "" {
+		r.Review = ReviewImmediate
+		if r.Mode == ModeExam {
+			r.Review = defaultReview
+		}
+	}
+	if r.Mode == ModeExam && r.TimeLimit == 0 {
+		r.TimeLimit = defaultTimeLimit
+	}
+}
+
+// Validate checks the mode, review policy and time limit
+func (r *QuizSessionRequest) Validate() error {
+	if !contains(AllowedModes, r.Mode) {
+		return fmt.Errorf("mode must be one of: %s", strings.Join(AllowedModes, ", "))
+	}
+	if !IsReviewPolicy(r.Review) {
+		return fmt.Errorf("review must be one of: %s", strings.Join(AllowedReviewPolicies, ", "))
+	}
+	if r.Mode == ModePractice && r.Review != ReviewImmediate {
+		return fmt.Errorf("review applies to exam sessions only")
+	}
+	if r.TimeLimit < 0 || r.TimeLimit > MaxTimeLimit {
+		return fmt.Errorf("timeLimit must be between 0 and %d seconds", MaxTimeLimit)
+	}
+	if r.Review == ReviewAfterClose && r.TimeLimit == 0 {
+		return fmt.Errorf("the %s review policy needs a timeLimit", ReviewAfterClose)
+	}
+	return nil
+}
+
+// WithholdAnswers strips everything that gives the answer away from a
+// question served in exam mode: the correct answers, answer key, explanation,
+// rationales and references. A sql question keeps the fixture learners write
+// their query against. Templates must be instantiated first.
+func (r *QuestionResponse) WithholdAnswers() {
+	r.CorrectAnswer = nil
+	r.CorrectAnswers = nil
+	r.Explanation = ""
+	r.Rationales = nil
+	r.References = nil
+	r.Variants = nil
+
+	var key SQLKey
+	if r.Type == QuestionTypeSQL && json.Unmarshal(r.AnswerKey, &key) == nil {
+		r.AnswerKey, _ = json.Marshal(struct {
+			Fixture     string `json:"fixture"`
+			IgnoreOrder bool   `json:"ignoreOrder,omitempty"`
+		}{key.Fixture, key.IgnoreOrder})
+	} else {
+		r.AnswerKey = nil
+	}
+	r.AnswersWithheld = true
+}
+
+// IsExam reports whether the session withholds answers until review
+func (s *QuizSession) IsExam() bool {
+	return s.Mode == ModeExam
+}
+
+// Closed reports whether the session's time limit has run out at now
+func (s *QuizSession) Closed(now time.Time) bool {
+	return s.ClosesAt != nil && !now.Before(*s.ClosesAt)
+}
+
+// Reveals reports whether a result of the session may show the answers at
+// now, and if not, when it will; the time is nil when it never will
+func (s *QuizSession) Reveals(now time.Time) (bool, *time.Time) {
+	if !s.IsExam() {
+		return true, nil
+	}
+	switch s.Review {
+	case ReviewImmediate:
+		return true, nil
+	case ReviewAfterClose:
+		return s.Closed(now), s.ClosesAt
+	default:
+		return false, nil
+	}
+}
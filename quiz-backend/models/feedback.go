@@ -0,0 +1,110 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Reference is a titled link to documentation backing a question
+type Reference struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// normalizeFeedback trims the rationales and references, dropping the
+// rationales entirely when none of them has text
+func (r *QuestionRequest) normalizeFeedback() {
+	empty := true
+	for i := range r.Rationales {
+		r.Rationales[i] = strings.TrimSpace(r.Rationales[i])
+		if r.Rationales[i] != "" {
+			empty = false
+		}
+	}
+	if empty {
+		r.Rationales = nil
+	}
+	for i := range r.References {
+		r.References[i].Title = strings.TrimSpace(r.References[i].Title)
+		r.References[i].URL = strings.TrimSpace(r.References[i].URL)
+	}
+}
+
+// validateFeedback checks that rationales line up with the options and that
+// every reference is a titled http(s) link
+func (r *QuestionRequest) validateFeedback() error {
+	if len(r.Rationales) > 0 {
+		if r.Type != QuestionTypeSingle && r.Type != QuestionTypeMultiple {
+			return fmt.Errorf("rationales are only supported for %s and %s questions", QuestionTypeSingle, QuestionTypeMultiple)
+		}
+		if len(r.Rationales) != len(r.Options) {
+			return fmt.Errorf("rationales must have one entry per option (%d), got %d", len(r.Options), len(r.Rationales))
+		}
+	}
+
+	seen := make(map[string]bool, len(r.References))
+	for i, ref := range r.References {
+		if ref.Title == "" {
+			return fmt.Errorf("reference %d needs a title", i+1)
+		}
+		u, err := url.Parse(ref.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("reference %d must have an http or https URL", i+1)
+		}
+		if seen[ref.URL] {
+			return fmt.Errorf("reference %s is listed more than once", ref.URL)
+		}
+		seen[ref.URL] = true
+	}
+	return nil
+}
+
+// applyFeedback stores the rationales and references as JSON columns
+func (r *QuestionRequest) applyFeedback(q *Question) error {
+	q.Rationales, q.References = "", ""
+	if len(r.Rationales) > 0 {
+		data, err := json.Marshal(r.Rationales)
+		if err != nil {
+			return fmt.Errorf("failed to marshal rationales: %v", err)
+		}
+		q.Rationales = string(data)
+	}
+	if len(r.References) > 0 {
+		data, err := json.Marshal(r.References)
+		if err != nil {
+			return fmt.Errorf("failed to marshal references: %v", err)
+		}
+		q.References = string(data)
+	}
+	return nil
+}
+
+// DecodeRationales parses the per-option rationales, ignoring a malformed
+// column
+func (q *Question) DecodeRationales() []string {
+	var rationales []string
+	if q.Rationales != "" {
+		json.Unmarshal([]byte(q.Rationales), &rationales)
+	}
+	return rationales
+}
+
+// DecodeReferences parses the documentation references, ignoring a
+// malformed column
+func (q *Question) DecodeReferences() []Reference {
+	var references []Reference
+	if q.References != "" {
+		json.Unmarshal([]byte(q.References), &references)
+	}
+	return references
+}
+
+// RationaleAt returns the rationale for an option, or "" when it has none
+func RationaleAt(rationales []string, index int) string {
+	if index < 0 || index >= len(rationales) {
+		return ""
+	}
+	return rationales[index]
+}
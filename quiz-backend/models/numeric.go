@@ -0,0 +1,130 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Numeric tolerance modes
+const (
+	ToleranceAbsolute = "absolute" // |answer - value| <= tolerance
+	ToleranceRelative = "relative" // |answer - value| <= tolerance * |value|
+)
+
+// AllowedToleranceModes lists the numeric tolerance modes
+var AllowedToleranceModes = []string{ToleranceAbsolute, ToleranceRelative}
+
+// NumericKey is the answer key of a numeric question. Unit is optional; when
+// it is a known time or storage unit, answers given in another unit of the
+// same kind ("5 weeks", "64 TiB") are converted before comparison. Any other
+// unit is a label the answer may repeat ("15 replicas").
+type NumericKey struct {
+	Value         float64 `json:"value"`
+	Tolerance     float64 `json:"tolerance,omitempty"`
+	ToleranceMode string  `json:"toleranceMode"`
+	Unit          string  `json:"unit,omitempty"`
+}
+
+// unit is a known unit of measure, expressed as a multiple of its kind's base
+// unit (seconds or bytes)
+type unit struct {
+	kind   string
+	factor float64
+}
+
+const (
+	kib = 1024.0
+	kb  = 1000.0
+)
+
+// units maps lower-case unit names and abbreviations to their definition
+var units = knownUnits()
+
+func knownUnits() map[string]unit {
+	units := make(map[string]unit)
+	define := func(kind string, factor float64, names ...string) {
+		for _, name := range names {
+			units[name] = unit{kind: kind, factor: factor}
+		}
+	}
+
+	define("time", 1, "s", "sec", "secs", "second", "seconds")
+	define("time", 60, "min", "mins", "minute", "minutes")
+	define("time", 3600, "h", "hr", "hrs", "hour", "hours")
+	define("time", 86400, "d", "day", "days")
+	define("time", 7*86400, "w", "wk", "wks", "week", "weeks")
+
+	define("storage", 1, "b", "byte", "bytes")
+	define("storage", kb, "kb")
+	define("storage", kb*kb, "mb")
+	define("storage", kb*kb*kb, "gb")
+	define("storage", kb*kb*kb*kb, "tb")
+	define("storage", kb*kb*kb*kb*kb, "pb")
+	define("storage", kib, "kib")
+	define("storage", kib*kib, "mib")
+	define("storage", kib*kib*kib, "gib")
+	define("storage", kib*kib*kib*kib, "tib")
+	define("storage", kib*kib*kib*kib*kib, "pib")
+	return units
+}
+
+// ParseQuantity splits an answer such as "35 days" or "1,024" into its value
+// and unit
+func ParseQuantity(s string) (float64, string, error) {
+	s = strings.TrimSpace(s)
+	end := strings.IndexFunc(s, func(r rune) bool {
+		return !(unicode.IsDigit(r) || strings.ContainsRune("+-.,eE", r))
+	})
+	number, unitName := s, ""
+	if end >= 0 {
+		number, unitName = s[:end], strings.TrimSpace(s[end:])
+	}
+	// A trailing "e" belongs to a unit rather than an exponent
+	for strings.HasSuffix(number, "e") || strings.HasSuffix(number, "E") {
+		unitName = number[len(number)-1:] + unitName
+		number = number[:len(number)-1]
+	}
+
+	value, err := strconv.ParseFloat(strings.ReplaceAll(number, ",", ""), 64)
+	if err != nil || math.IsInf(value, 0) || math.IsNaN(value) {
+		return 0, "", fmt.Errorf("%q is not a number", s)
+	}
+	return value, unitName, nil
+}
+
+// ConvertUnit converts value from one unit to another. It reports false when
+// the units cannot be converted: different kinds of known units, or a label
+// unit that is not the target unit.
+func ConvertUnit(value float64, from, to string) (float64, bool) {
+	if from == "" || strings.EqualFold(from, to) {
+		return value, true
+	}
+	source, ok := units[strings.ToLower(from)]
+	if !ok {
+		return 0, false
+	}
+	target, ok := units[strings.ToLower(to)]
+	if !ok || target.kind != source.kind {
+		return 0, false
+	}
+	return value * source.factor / target.factor, true
+}
+
+// Allowance returns the absolute difference from Value the key accepts
+func (k NumericKey) Allowance() float64 {
+	if k.ToleranceMode == ToleranceRelative {
+		return k.Tolerance * math.Abs(k.Value)
+	}
+	return k.Tolerance
+}
+
+// Accepts reports whether value, already in the key's unit, is within the
+// key's tolerance
+func (k NumericKey) Accepts(value float64) bool {
+	// Allow for binary floating point error in unit conversion
+	allowed := k.Allowance() + 1e-9*math.Max(1, math.Abs(k.Value))
+	return math.Abs(value-k.Value) <= allowed
+}
@@ -1,46 +1,411 @@
 package models
 
 import (
+	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+// Allowed difficulty levels
+const (
+	DifficultyEasy   = "easy"
+	DifficultyMedium = "medium"
+	DifficultyHard   = "hard"
+)
+
+// Categories of the default bank
+const (
+	CategoryRDS    = "RDS"
+	CategoryAurora = "Aurora"
+)
+
+// Question sources record how a question entered the bank
+const (
+	SourceSeed     = "seed"
+	SourceAPI      = "api"
+	SourceImport   = "import"
+	SourceMarkdown = "markdown"
+)
+
+// AllowedDifficulties lists the difficulty values accepted by the API
+var AllowedDifficulties = []string{DifficultyEasy, DifficultyMedium, DifficultyHard}
+
+// AllowedCategories lists the categories of the default bank; other banks
+// choose their own
+var AllowedCategories = []string{CategoryRDS, CategoryAurora}
+
 type Question struct {
-	ID            uint           `json:"id" gorm:"primaryKey"`
-	Question      string         `json:"question" gorm:"not null"`
-	Options       string         `json:"options" gorm:"type:text;not null"` // JSON array as string
-	CorrectAnswer int            `json:"correctAnswer" gorm:"not null"`
-	Explanation   string         `json:"explanation" gorm:"type:text"`
-	Category      string         `json:"category" gorm:"default:'RDS'"`
-	Difficulty    string         `json:"difficulty" gorm:"default:'medium'"`
-	CreatedAt     time.Time      `json:"createdAt"`
-	UpdatedAt     time.Time      `json:"updatedAt"`
-	DeletedAt     gorm.DeletedAt `json:"deletedAt,omitempty" gorm:"index"`
+	ID             uint           `json:"id" gorm:"primaryKey"`
+	BankID         uint           `json:"bankId" gorm:"not null;default:0;index"`
+	Question       string         `json:"question" gorm:"not null"`
+	Options        string         `json:"options" gorm:"type:text;not null"` // JSON array as string
+	CorrectAnswer  int            `json:"correctAnswer" gorm:"not null"`
+	Explanation    string         `json:"explanation" gorm:"type:text"`
+	Rationales     string         `json:"rationales" gorm:"type:text"` // JSON array, one entry per option
+	References     string         `json:"references" gorm:"type:text"` // JSON array of Reference
+	Variants       string         `json:"variants" gorm:"type:text"`   // JSON array of placeholder values; set for templates
+	Category       string         `json:"category" gorm:"default:'RDS'"`
+	Difficulty     string         `json:"difficulty" gorm:"default:'medium'"`
+	Type           string         `json:"type" gorm:"default:'single_choice'"`
+	CorrectAnswers string         `json:"correctAnswers" gorm:"type:text"` // JSON array as string, multiple_choice only
+	Scoring        string         `json:"scoring"`
+	AnswerKey      string         `json:"answerKey" gorm:"type:text"`         // JSON object as string, per-type schema
+	Revision       int            `json:"revision" gorm:"not null;default:0"` // latest entry in question_revisions
+	TopicID        *uint          `json:"topicId" gorm:"index"`
+	Topic          *Topic         `json:"topic,omitempty" gorm:"<-:false"`                        // saved through TopicID
+	Tags           []Tag          `json:"tags,omitempty" gorm:"many2many:question_tags;<-:false"` // saved by the AfterSave hook
+	Source         string         `json:"source"`
+	Status         string         `json:"status" gorm:"not null;default:'published';index"`
+	Author         string         `json:"author" gorm:"index"`
+	Reviewer       string         `json:"reviewer" gorm:"index"`
+	LintIgnore     string         `json:"lintIgnore" gorm:"type:text"`             // JSON array of suppressed lint rule IDs
+	ExternalID     *string        `json:"externalId,omitempty" gorm:"uniqueIndex"` // stable key for file-backed and seeded questions
+	SeedHash       string         `json:"-"`                                       // fingerprint of the seed entry last applied
+	CreatedAt      time.Time      `json:"createdAt"`
+	UpdatedAt      time.Time      `json:"updatedAt"`
+	DeletedAt      gorm.DeletedAt `json:"deletedAt,omitempty" gorm:"index"`
+
+	// Set by QuestionRequest.ApplyTo and resolved by the save hooks; nil
+	// leaves the stored tags or topic unchanged
+	tagNames  []string
+	topicPath *string
 }
 
 // QuestionResponse represents the API response format
 type QuestionResponse struct {
-	ID            uint     `json:"id"`
-	Question      string   `json:"question"`
-	Options       []string `json:"options"`
-	CorrectAnswer int      `json:"correctAnswer"`
-	Explanation   string   `json:"explanation,omitempty"`
-	Category      string   `json:"category"`
-	Difficulty    string   `json:"difficulty"`
+	ID             uint                `json:"id"`
+	BankID         uint                `json:"bankId"`
+	Revision       int                 `json:"revision"`
+	Question       string              `json:"question"`
+	Options        []string            `json:"options"`
+	CorrectAnswer  *int                `json:"correctAnswer,omitempty"`
+	Explanation    string              `json:"explanation,omitempty"`
+	Rationales     []string            `json:"rationales,omitempty"`
+	References     []Reference         `json:"references,omitempty"`
+	Variants       []map[string]string `json:"variants,omitempty"`      // value table of a template
+	Variant        *int                `json:"variant,omitempty"`       // row of the value table this question was made from
+	ServedVariant  string              `json:"servedVariant,omitempty"` // signed token of the variant served, named on submission
+	Category       string              `json:"category"`
+	Difficulty     string              `json:"difficulty"`
+	Type           string              `json:"type"`
+	SelectCount    int                 `json:"selectCount"`
+	CorrectAnswers []int               `json:"correctAnswers,omitempty"`
+	Scoring        string              `json:"scoring,omitempty"`
+	Targets        []string            `json:"targets,omitempty"`
+	AnswerKey      json.RawMessage     `json:"answerKey,omitempty"`
+	Tags           []string            `json:"tags"`
+	Topic          string              `json:"topic,omitempty"`
+	TopicID        *uint               `json:"topicId,omitempty"`
+	Status         string              `json:"status"`
+	Author         string              `json:"author,omitempty"`
+	Reviewer       string              `json:"reviewer,omitempty"`
+	LintIgnore     []string            `json:"lintIgnore,omitempty"`
+	Attachments    []AttachmentRef     `json:"attachments,omitempty"` // images referenced by the question text and options
+	Locale         string              `json:"locale,omitempty"`      // set by endpoints that negotiate the language
+
+	AnswersWithheld bool `json:"answersWithheld,omitempty"` // served in exam mode, see WithholdAnswers
 }
 
 // QuestionRequest represents the API request format for creating/updating questions
 type QuestionRequest struct {
-	Question      string   `json:"question" binding:"required"`
-	Options       []string `json:"options" binding:"required,min=2,max=6"`
-	CorrectAnswer int      `json:"correctAnswer" binding:"required,min=0"`
-	Explanation   string   `json:"explanation"`
-	Category      string   `json:"category"`
-	Difficulty    string   `json:"difficulty"`
+	Question       string              `json:"question" binding:"required"`
+	Options        []string            `json:"options"`
+	CorrectAnswer  int                 `json:"correctAnswer" binding:"min=0"`
+	Explanation    string              `json:"explanation"`
+	Rationales     []string            `json:"rationales,omitempty"` // why each option is right or wrong, in option order
+	References     []Reference         `json:"references,omitempty"`
+	Variants       []map[string]string `json:"variants,omitempty"` // makes the question a template: one row of {placeholder} values per variant
+	Category       string              `json:"category"`
+	Difficulty     string              `json:"difficulty"`
+	Type           string              `json:"type,omitempty"`
+	CorrectAnswers []int               `json:"correctAnswers,omitempty"`
+	Scoring        string              `json:"scoring,omitempty"`
+	AnswerKey      json.RawMessage     `json:"answerKey,omitempty"`
+	Tags           []string            `json:"tags,omitempty"`
+	Topic          string              `json:"topic,omitempty"`
+	LintIgnore     []string            `json:"lintIgnore,omitempty"` // lint rule IDs suppressed for this question
+}
+
+// QuestionPatchRequest represents a partial update; nil fields are left unchanged
+type QuestionPatchRequest struct {
+	Question       *string              `json:"question"`
+	Options        *[]string            `json:"options"`
+	CorrectAnswer  *int                 `json:"correctAnswer"`
+	Explanation    *string              `json:"explanation"`
+	Rationales     *[]string            `json:"rationales"`
+	References     *[]Reference         `json:"references"`
+	Variants       *[]map[string]string `json:"variants"`
+	Category       *string              `json:"category"`
+	Difficulty     *string              `json:"difficulty"`
+	Type           *string              `json:"type"`
+	CorrectAnswers *[]int               `json:"correctAnswers"`
+	Scoring        *string              `json:"scoring"`
+	AnswerKey      *json.RawMessage     `json:"answerKey"`
+	Tags           *[]string            `json:"tags"`
+	Topic          *string              `json:"topic"`
+	LintIgnore     *[]string            `json:"lintIgnore"`
 }
 
 // TableName specifies the table name for the Question model
 func (Question) TableName() string {
 	return "questions"
 }
+
+// Normalize trims whitespace and fills in the default difficulty. The default
+// category depends on the bank and is filled in by Bank.Check.
+func (r *QuestionRequest) Normalize() {
+	r.Question = strings.TrimSpace(r.Question)
+	r.Explanation = strings.TrimSpace(r.Explanation)
+	r.Category = strings.TrimSpace(r.Category)
+	r.Difficulty = strings.ToLower(strings.TrimSpace(r.Difficulty))
+	for i := range r.Options {
+		r.Options[i] = strings.TrimSpace(r.Options[i])
+	}
+
+	if r.Difficulty == "" {
+		r.Difficulty = DifficultyMedium
+	}
+	r.Tags = normalizeTags(r.Tags)
+	r.Topic = NormalizeTopicPath(r.Topic)
+	r.LintIgnore = normalizeTags(r.LintIgnore)
+	r.normalizeType()
+	r.normalizeFeedback()
+	r.normalizeTemplate()
+}
+
+// Validate checks the request against the question authoring rules. It is
+// shared by the authoring API and every import path, through Bank.Check,
+// so they agree on what a valid question looks like.
+func (r *QuestionRequest) Validate() error {
+	if r.Question == "" {
+		return fmt.Errorf("question text is required")
+	}
+	if len(r.Variants) > 0 {
+		// Each variant is validated as a question of its own
+		return r.validateTemplate()
+	}
+	if err := r.validateType(); err != nil {
+		return err
+	}
+	if !contains(AllowedDifficulties, r.Difficulty) {
+		return fmt.Errorf("difficulty must be one of: %s", strings.Join(AllowedDifficulties, ", "))
+	}
+	if err := r.validateFeedback(); err != nil {
+		return err
+	}
+	return r.validateTaxonomy()
+}
+
+// ApplyTo copies the request fields onto an existing question
+func (r *QuestionRequest) ApplyTo(q *Question) error {
+	optionsJSON, err := json.Marshal(r.Options)
+	if err != nil {
+		return fmt.Errorf("failed to marshal options: %v", err)
+	}
+
+	q.Question = r.Question
+	q.Options = string(optionsJSON)
+	q.CorrectAnswer = r.CorrectAnswer
+	q.Explanation = r.Explanation
+	q.Category = r.Category
+	q.Difficulty = r.Difficulty
+	q.LintIgnore = ""
+	if len(r.LintIgnore) > 0 {
+		lintIgnore, err := json.Marshal(r.LintIgnore)
+		if err != nil {
+			return fmt.Errorf("failed to marshal lintIgnore: %v", err)
+		}
+		q.LintIgnore = string(lintIgnore)
+	}
+	q.tagNames = append([]string{}, r.Tags...)
+	topic := r.Topic
+	q.topicPath = &topic
+	if err := r.applyFeedback(q); err != nil {
+		return err
+	}
+	if err := r.applyTemplate(q); err != nil {
+		return err
+	}
+	return r.applyType(q)
+}
+
+// ApplyTo merges the non-nil patch fields into a full request
+func (p *QuestionPatchRequest) ApplyTo(r *QuestionRequest) {
+	if p.Question != nil {
+		r.Question = *p.Question
+	}
+	if p.Options != nil {
+		r.Options = *p.Options
+	}
+	if p.CorrectAnswer != nil {
+		r.CorrectAnswer = *p.CorrectAnswer
+	}
+	if p.Explanation != nil {
+		r.Explanation = *p.Explanation
+	}
+	if p.Rationales != nil {
+		r.Rationales = *p.Rationales
+	}
+	if p.References != nil {
+		r.References = *p.References
+	}
+	if p.Variants != nil {
+		r.Variants = *p.Variants
+	}
+	if p.Category != nil {
+		r.Category = *p.Category
+	}
+	if p.Difficulty != nil {
+		r.Difficulty = *p.Difficulty
+	}
+	if p.Type != nil {
+		r.Type = *p.Type
+	}
+	if p.CorrectAnswers != nil {
+		r.CorrectAnswers = *p.CorrectAnswers
+	}
+	if p.Scoring != nil {
+		r.Scoring = *p.Scoring
+	}
+	if p.AnswerKey != nil {
+		r.AnswerKey = *p.AnswerKey
+	}
+	if p.Tags != nil {
+		r.Tags = *p.Tags
+	}
+	if p.Topic != nil {
+		r.Topic = *p.Topic
+	}
+	if p.LintIgnore != nil {
+		r.LintIgnore = *p.LintIgnore
+	}
+}
+
+// DecodeOptions parses the JSON encoded options column
+func (q *Question) DecodeOptions() ([]string, error) {
+	var options []string
+	if err := json.Unmarshal([]byte(q.Options), &options); err != nil {
+		return nil, err
+	}
+	return options, nil
+}
+
+// ToRequest converts a stored question back into its authoring representation
+func (q *Question) ToRequest() (QuestionRequest, error) {
+	options, err := q.DecodeOptions()
+	if err != nil {
+		return QuestionRequest{}, err
+	}
+	correctAnswers, err := q.DecodeCorrectAnswers()
+	if err != nil {
+		return QuestionRequest{}, err
+	}
+
+	return QuestionRequest{
+		Question:       q.Question,
+		Options:        options,
+		CorrectAnswer:  q.CorrectAnswer,
+		Explanation:    q.Explanation,
+		Rationales:     q.DecodeRationales(),
+		References:     q.DecodeReferences(),
+		Variants:       q.DecodeVariants(),
+		Category:       q.Category,
+		Difficulty:     q.Difficulty,
+		Type:           q.QuestionType(),
+		CorrectAnswers: correctAnswers,
+		Scoring:        q.Scoring,
+		AnswerKey:      answerKey(q.AnswerKey),
+		Tags:           q.tagList(),
+		Topic:          q.topicName(),
+		LintIgnore:     q.DecodeLintIgnore(),
+	}, nil
+}
+
+// ToResponse converts a stored question into the API response format
+func (q *Question) ToResponse() (QuestionResponse, error) {
+	options, err := q.DecodeOptions()
+	if err != nil {
+		return QuestionResponse{}, err
+	}
+	correctAnswers, err := q.DecodeCorrectAnswers()
+	if err != nil {
+		return QuestionResponse{}, err
+	}
+	correctAnswer := q.CorrectAnswer
+
+	return QuestionResponse{
+		ID:             q.ID,
+		BankID:         q.BankID,
+		Revision:       q.Revision,
+		Question:       q.Question,
+		Options:        options,
+		CorrectAnswer:  &correctAnswer,
+		Explanation:    q.Explanation,
+		Rationales:     q.DecodeRationales(),
+		References:     q.DecodeReferences(),
+		Variants:       q.DecodeVariants(),
+		Category:       q.Category,
+		Difficulty:     q.Difficulty,
+		Type:           q.QuestionType(),
+		SelectCount:    q.SelectCount(),
+		CorrectAnswers: correctAnswers,
+		Scoring:        q.Scoring,
+		Targets:        q.MatchingTargets(),
+		AnswerKey:      answerKey(q.AnswerKey),
+		Tags:           append([]string{}, q.tagList()...),
+		Topic:          q.topicName(),
+		TopicID:        q.TopicID,
+		Status:         q.Status,
+		Author:         q.Author,
+		Reviewer:       q.Reviewer,
+		LintIgnore:     q.DecodeLintIgnore(),
+		Attachments:    q.attachmentRefs(options),
+	}, nil
+}
+
+// DecodeLintIgnore parses the suppressed lint rule IDs, ignoring a malformed
+// column
+func (q *Question) DecodeLintIgnore() []string {
+	var rules []string
+	if q.LintIgnore != "" {
+		json.Unmarshal([]byte(q.LintIgnore), &rules)
+	}
+	return rules
+}
+
+// tagList returns the names of the question's preloaded tags
+func (q *Question) tagList() []string {
+	var names []string
+	for _, tag := range q.Tags {
+		names = append(names, tag.Name)
+	}
+	return names
+}
+
+// topicName returns the full name of the question's preloaded topic
+func (q *Question) topicName() string {
+	if q.Topic == nil {
+		return ""
+	}
+	return q.Topic.FullName
+}
+
+// answerKey returns the stored answer key as raw JSON, or nil when unset
+func answerKey(stored string) json.RawMessage {
+	if stored == "" {
+		return nil
+	}
+	return json.RawMessage(stored)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
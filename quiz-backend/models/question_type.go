@@ -0,0 +1,204 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Question types
+const (
+	QuestionTypeSingle    = "single_choice"
+	QuestionTypeMultiple  = "multiple_choice" // "choose N" with a set of correct options
+	QuestionTypeTrueFalse = "true_false"
+	QuestionTypeOrdering  = "ordering"   // put the options in the right sequence
+	QuestionTypeMatching  = "matching"   // match each option to a target
+	QuestionTypeFillBlank = "fill_blank" // free text checked against accepted answers
+	QuestionTypeNumeric   = "numeric"    // a number, optionally with a unit, within a tolerance
+	QuestionTypeSQL       = "sql"        // a query graded against a fixture database
+)
+
+// Option count limits. Ordering and matching lists may be longer than the
+// options of a choice question.
+const (
+	maxChoiceOptions = 6
+	maxListOptions   = 10
+)
+
+// Scoring modes for multiple choice, ordering and matching questions
+const (
+	ScoringExact   = "exact"   // full credit only for an entirely correct answer
+	ScoringPartial = "partial" // credit per correct selection, position or pair
+)
+
+// AllowedQuestionTypes lists the question types accepted by the API
+var AllowedQuestionTypes = []string{
+	QuestionTypeSingle,
+	QuestionTypeMultiple,
+	QuestionTypeTrueFalse,
+	QuestionTypeOrdering,
+	QuestionTypeMatching,
+	QuestionTypeFillBlank,
+	QuestionTypeNumeric,
+	QuestionTypeSQL,
+}
+
+// AllowedScorings lists the scoring modes accepted by the API
+var AllowedScorings = []string{ScoringExact, ScoringPartial}
+
+// QuestionType returns the question's type, treating rows created before
+// question types existed as single choice
+func (q *Question) QuestionType() string {
+	if q.Type == "" {
+		return QuestionTypeSingle
+	}
+	return q.Type
+}
+
+// DecodeCorrectAnswers parses the JSON encoded correct answer set of a
+// multiple choice question
+func (q *Question) DecodeCorrectAnswers() ([]int, error) {
+	if q.CorrectAnswers == "" {
+		return nil, nil
+	}
+	var answers []int
+	if err := json.Unmarshal([]byte(q.CorrectAnswers), &answers); err != nil {
+		return nil, err
+	}
+	return answers, nil
+}
+
+// SelectCount returns how many options a learner must pick
+func (q *Question) SelectCount() int {
+	if q.QuestionType() != QuestionTypeMultiple {
+		return 1
+	}
+	answers, _ := q.DecodeCorrectAnswers()
+	return len(answers)
+}
+
+// normalizeType fills in the default type and the type-specific fields
+func (r *QuestionRequest) normalizeType() {
+	r.Type = strings.ToLower(strings.TrimSpace(r.Type))
+	if r.Type == "" {
+		r.Type = QuestionTypeSingle
+	}
+	r.Scoring = strings.ToLower(strings.TrimSpace(r.Scoring))
+
+	switch r.Type {
+	case QuestionTypeMultiple:
+		r.CorrectAnswers = uniqueSorted(r.CorrectAnswers)
+		if len(r.CorrectAnswers) > 0 {
+			r.CorrectAnswer = r.CorrectAnswers[0]
+		}
+	case QuestionTypeSingle:
+		r.CorrectAnswers = nil
+		r.Scoring = ""
+	default:
+		r.CorrectAnswers = nil
+		r.CorrectAnswer = 0
+	}
+
+	switch r.Type {
+	case QuestionTypeMultiple, QuestionTypeOrdering, QuestionTypeMatching:
+		if r.Scoring == "" {
+			r.Scoring = ScoringExact
+		}
+	default:
+		r.Scoring = ""
+	}
+
+	r.normalizeKey()
+}
+
+// validateType applies the rules specific to the question type
+func (r *QuestionRequest) validateType() error {
+	if !contains(AllowedQuestionTypes, r.Type) {
+		return fmt.Errorf("type must be one of: %s", strings.Join(AllowedQuestionTypes, ", "))
+	}
+
+	switch r.Type {
+	case QuestionTypeSingle:
+		if err := r.validateOptions(2, maxChoiceOptions); err != nil {
+			return err
+		}
+		if r.CorrectAnswer < 0 || r.CorrectAnswer >= len(r.Options) {
+			return fmt.Errorf("correctAnswer must be between 0 and %d", len(r.Options)-1)
+		}
+	case QuestionTypeMultiple:
+		if err := r.validateOptions(2, maxChoiceOptions); err != nil {
+			return err
+		}
+		if len(r.CorrectAnswers) < 2 {
+			return fmt.Errorf("multiple_choice questions need at least 2 correctAnswers")
+		}
+		if len(r.CorrectAnswers) >= len(r.Options) {
+			return fmt.Errorf("multiple_choice questions need at least one incorrect option")
+		}
+		for _, answer := range r.CorrectAnswers {
+			if answer < 0 || answer >= len(r.Options) {
+				return fmt.Errorf("correctAnswers must be between 0 and %d", len(r.Options)-1)
+			}
+		}
+	case QuestionTypeOrdering, QuestionTypeMatching:
+		if err := r.validateOptions(2, maxListOptions); err != nil {
+			return err
+		}
+	case QuestionTypeFillBlank, QuestionTypeNumeric, QuestionTypeSQL:
+		if len(r.Options) > 0 {
+			return fmt.Errorf("%s questions have no options", r.Type)
+		}
+	}
+
+	if r.Scoring != "" && !contains(AllowedScorings, r.Scoring) {
+		return fmt.Errorf("scoring must be one of: %s", strings.Join(AllowedScorings, ", "))
+	}
+	return r.validateKey()
+}
+
+// validateOptions checks the number of options and that none is empty
+func (r *QuestionRequest) validateOptions(min, max int) error {
+	if len(r.Options) < min || len(r.Options) > max {
+		return fmt.Errorf("question must have between %d and %d options, got %d", min, max, len(r.Options))
+	}
+	for i, option := range r.Options {
+		if option == "" {
+			return fmt.Errorf("option %d is empty", i)
+		}
+	}
+	return nil
+}
+
+// applyType copies the type-specific fields onto a question
+func (r *QuestionRequest) applyType(q *Question) error {
+	q.Type = r.Type
+	q.Scoring = r.Scoring
+	q.AnswerKey = string(r.AnswerKey)
+	q.CorrectAnswers = ""
+	if len(r.CorrectAnswers) > 0 {
+		answersJSON, err := json.Marshal(r.CorrectAnswers)
+		if err != nil {
+			return fmt.Errorf("failed to marshal correct answers: %v", err)
+		}
+		q.CorrectAnswers = string(answersJSON)
+	}
+	return nil
+}
+
+// uniqueSorted returns the distinct values in ascending order
+func uniqueSorted(values []int) []int {
+	if len(values) == 0 {
+		return nil
+	}
+	seen := make(map[int]bool, len(values))
+	var result []int
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	sort.Ints(result)
+	return result
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// validate normalizes and validates a question request
+func validate(t *testing.T, body string) (QuestionRequest, error) {
+	t.Helper()
+	var req QuestionRequest
+	if err := json.Unmarshal([]byte(body), &req); err != nil {
+		t.Fatalf("invalid request: %v", err)
+	}
+	req.Normalize()
+	return req, req.Validate()
+}
+
+func TestValidateMultipleChoice(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr string
+	}{
+		{"choose two", `{"question": "q", "type": "multiple_choice", "options": ["a", "b", "c"], "correctAnswers": [2, 0]}`, ""},
+		{"duplicates collapse", `{"question": "q", "type": "multiple_choice", "options": ["a", "b", "c"], "correctAnswers": [0, 0, 1]}`, ""},
+		{"one correct answer", `{"question": "q", "type": "multiple_choice", "options": ["a", "b", "c"], "correctAnswers": [1]}`, "at least 2 correctAnswers"},
+		{"every option correct", `{"question": "q", "type": "multiple_choice", "options": ["a", "b"], "correctAnswers": [0, 1]}`, "at least one incorrect option"},
+		{"out of range", `{"question": "q", "type": "multiple_choice", "options": ["a", "b", "c"], "correctAnswers": [0, 3]}`, "correctAnswers must be between 0 and 2"},
+		{"unknown scoring", `{"question": "q", "type": "multiple_choice", "options": ["a", "b", "c"], "correctAnswers": [0, 1], "scoring": "generous"}`, "scoring must be one of"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := validate(t, tt.body)
+			checkError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestSelectCount(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"single choice", `{"question": "q", "options": ["a", "b"], "correctAnswer": 1}`, 1},
+		{"choose three", `{"question": "q", "type": "multiple_choice", "options": ["a", "b", "c", "d"], "correctAnswers": [0, 1, 3]}`, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := validate(t, tt.body)
+			if err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			var q Question
+			if err := req.ApplyTo(&q); err != nil {
+				t.Fatalf("ApplyTo() error = %v", err)
+			}
+			if got := q.SelectCount(); got != tt.want {
+				t.Errorf("SelectCount() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// checkError fails unless err contains want, or is nil when want is empty
+func checkError(t *testing.T, err error, want string) {
+	t.Helper()
+	switch {
+	case want == "" && err != nil:
+		t.Errorf("Validate() error = %v, want none", err)
+	case want != "" && (err == nil || !strings.Contains(err.Error(), want)):
+		t.Errorf("Validate() error = %v, want one containing %q", err, want)
+	}
+}
+
+func TestValidateAnswerKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr string
+	}{
+		{"true false", `{"question": "q", "type": "true_false", "answerKey": {"answer": true}}`, ""},
+		{"true false without key", `{"question": "q", "type": "true_false"}`, "answerKey"},
+		{"ordering", `{"question": "q", "type": "ordering", "options": ["a", "b", "c"], "answerKey": {"order": [1, 2, 0]}}`, ""},
+		{"ordering repeats", `{"question": "q", "type": "ordering", "options": ["a", "b", "c"], "answerKey": {"order": [1, 1, 0]}}`, "exactly once"},
+		{"matching", `{"question": "q", "type": "matching", "options": ["a", "b"], "answerKey": {"targets": ["x", "y", "z"], "pairs": [2, 0]}}`, ""},
+		{"matching pair per option", `{"question": "q", "type": "matching", "options": ["a", "b"], "answerKey": {"targets": ["x", "y"], "pairs": [0]}}`, "one entry per option"},
+		{"matching target out of range", `{"question": "q", "type": "matching", "options": ["a", "b"], "answerKey": {"targets": ["x", "y"], "pairs": [0, 2]}}`, "pairs[1] must be between 0 and 1"},
+		{"fill blank", `{"question": "q", "type": "fill_blank", "answerKey": {"accepted": ["3306"]}}`, ""},
+		{"fill blank with options", `{"question": "q", "type": "fill_blank", "options": ["a"], "answerKey": {"accepted": ["3306"]}}`, "have no options"},
+		{"fill blank empty", `{"question": "q", "type": "fill_blank", "answerKey": {"accepted": []}}`, "at least one answer"},
+		{"fill blank bad regex", `{"question": "q", "type": "fill_blank", "answerKey": {"accepted": ["("], "match": "regex"}}`, "not a valid regular expression"},
+		{"unknown type", `{"question": "q", "type": "essay"}`, "type must be one of"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := validate(t, tt.body)
+			checkError(t, err, tt.wantErr)
+		})
+	}
+}
@@ -1,17 +1,25 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
+	"aws-rds-quiz-backend/sqlsandbox"
+
 	"gorm.io/gorm"
 )
 
 type QuizSubmission struct {
 	ID         uint           `json:"id" gorm:"primaryKey"`
+	BankID     uint           `json:"bankId" gorm:"not null;default:0;index"`
 	UserID     string         `json:"userId" gorm:"index"`
+	SessionID  *uint          `json:"sessionId,omitempty" gorm:"index"`  // set for answers submitted against a session
 	Answers    string         `json:"answers" gorm:"type:text;not null"` // JSON object as string
+	Revisions  string         `json:"revisions" gorm:"type:text"`        // JSON object of question ID to revision graded
+	Variants   string         `json:"variants" gorm:"type:text"`         // JSON object of template question ID to variant graded
+	Details    string         `json:"-" gorm:"type:text"`                // JSON array of the answer details as graded
 	TimeSpent  int64          `json:"timeSpent" gorm:"not null"`         // Time in milliseconds
-	Score      int            `json:"score" gorm:"not null"`
+	Score      float64        `json:"score" gorm:"not null"`
 	Total      int            `json:"total" gorm:"not null"`
 	Percentage float64        `json:"percentage" gorm:"not null"`
 	CreatedAt  time.Time      `json:"createdAt"`
@@ -19,34 +27,86 @@ type QuizSubmission struct {
 	DeletedAt  gorm.DeletedAt `json:"deletedAt,omitempty" gorm:"index"`
 }
 
-// QuizSubmissionRequest represents the API request format
+// QuizSubmissionRequest represents the API request format. Each answer is
+// keyed by question ID and its shape depends on the question type: an option
+// index for single choice, a list of option indices for multiple choice, a
+// boolean for true/false, the option indices in the chosen sequence for
+// ordering, one target index per option for matching, a string for fill in
+// the blank, a number or a string such as "35 days" for numeric and a query
+// string for sql. Revisions optionally names the revision of each question
+// that was served; answers are graded against the current revision otherwise.
+// ServedVariants names the servedVariant each template question was served
+// with and is required for them.
 type QuizSubmissionRequest struct {
-	UserID    string         `json:"userId"`
-	Answers   map[string]int `json:"answers" binding:"required"`
-	TimeSpent int64          `json:"timeSpent" binding:"required,min=0"`
+	UserID         string                     `json:"userId"`
+	Answers        map[string]json.RawMessage `json:"answers" binding:"required"`
+	Revisions      map[string]int             `json:"revisions"`
+	ServedVariants map[string]string          `json:"servedVariants"`
+	TimeSpent      int64                      `json:"timeSpent" binding:"required,min=0"`
 }
 
-// QuizSubmissionResponse represents the API response format
+// QuizSubmissionResponse represents the API response format. Unanswered
+// lists the questions of the session left unanswered, which count toward
+// Total. The result of an exam session leaves out Answers until its review
+// policy allows, at ReviewAfter when that time is known.
 type QuizSubmissionResponse struct {
 	ID         uint               `json:"id"`
 	UserID     string             `json:"userId"`
-	Score      int                `json:"score"`
+	SessionID  *uint              `json:"sessionId,omitempty"`
+	Score      float64            `json:"score"`
 	Total      int                `json:"total"`
 	Percentage float64            `json:"percentage"`
 	TimeSpent  int64              `json:"timeSpent"`
 	Answers    []QuizAnswerDetail `json:"answers"`
+	Unanswered []uint             `json:"unanswered,omitempty"`
 	CreatedAt  time.Time          `json:"createdAt"`
+
+	AnswersWithheld bool       `json:"answersWithheld,omitempty"`
+	ReviewAfter     *time.Time `json:"reviewAfter,omitempty"`
 }
 
-// QuizAnswerDetail represents individual answer details
+// QuizAnswerDetail represents individual answer details. Credit is the
+// fraction of the question's point earned, which is below 1 only for
+// partially correct answers to questions with partial scoring. For ordering
+// and matching questions SelectedAnswers and CorrectAnswers hold the
+// submitted and expected sequences. Numeric questions report the submitted
+// value, converted to the expected unit, instead of option text, and wrong
+// sql answers carry a diff against the reference result set. Choice questions
+// with rationales explain the picked and the correct options; for multiple
+// choice the rationale lists line up with SelectedAnswers and CorrectAnswers.
+// An answer that could not be graded at all, such as one to a question that
+// no longer exists, earns no credit and says why in Ungraded.
 type QuizAnswerDetail struct {
-	QuestionID     uint   `json:"questionId"`
-	UserAnswer     int    `json:"userAnswer"`
-	CorrectAnswer  int    `json:"correctAnswer"`
-	IsCorrect      bool   `json:"isCorrect"`
-	Question       string `json:"question"`
-	SelectedOption string `json:"selectedOption"`
-	CorrectOption  string `json:"correctOption"`
+	QuestionID      uint             `json:"questionId"`
+	Revision        int              `json:"revision,omitempty"`
+	Variant         *int             `json:"variant,omitempty"` // template variant graded against
+	Type            string           `json:"type"`
+	UserAnswer      int              `json:"userAnswer"`
+	CorrectAnswer   int              `json:"correctAnswer"`
+	IsCorrect       bool             `json:"isCorrect"`
+	Credit          float64          `json:"credit"`
+	Question        string           `json:"question"`
+	SelectedOption  string           `json:"selectedOption"`
+	CorrectOption   string           `json:"correctOption"`
+	SelectedAnswers []int            `json:"selectedAnswers,omitempty"`
+	CorrectAnswers  []int            `json:"correctAnswers,omitempty"`
+	SelectedOptions []string         `json:"selectedOptions,omitempty"`
+	CorrectOptions  []string         `json:"correctOptions,omitempty"`
+	TextAnswer      string           `json:"textAnswer,omitempty"`
+	AcceptedAnswers []string         `json:"acceptedAnswers,omitempty"`
+	SubmittedValue  *float64         `json:"submittedValue,omitempty"`
+	ExpectedValue   *float64         `json:"expectedValue,omitempty"`
+	Tolerance       *float64         `json:"tolerance,omitempty"`
+	Unit            string           `json:"unit,omitempty"`
+	SQLDiff         *sqlsandbox.Diff `json:"sqlDiff,omitempty"`
+
+	SelectedRationale  string      `json:"selectedRationale,omitempty"`
+	CorrectRationale   string      `json:"correctRationale,omitempty"`
+	SelectedRationales []string    `json:"selectedRationales,omitempty"`
+	CorrectRationales  []string    `json:"correctRationales,omitempty"`
+	References         []Reference `json:"references,omitempty"`
+
+	Ungraded string `json:"ungraded,omitempty"`
 }
 
 // TableName specifies the table name for the QuizSubmission model
@@ -0,0 +1,142 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Reasons a learner reports a question for
+const (
+	ReportWrong     = "wrong"     // the keyed answer is incorrect
+	ReportOutdated  = "outdated"  // AWS changed a limit or feature since it was written
+	ReportAmbiguous = "ambiguous" // more than one option could be right
+)
+
+// Moderation states of a question report
+const (
+	ReportOpen      = "open"
+	ReportTriaged   = "triaged"   // confirmed for follow-up
+	ReportResolved  = "resolved"  // the question was fixed or retired
+	ReportDismissed = "dismissed" // the question stands as written
+)
+
+// AllowedReportReasons lists the reasons a question may be reported for
+var AllowedReportReasons = []string{ReportWrong, ReportOutdated, ReportAmbiguous}
+
+// AllowedReportStatuses lists the moderation states of a report
+var AllowedReportStatuses = []string{ReportOpen, ReportTriaged, ReportResolved, ReportDismissed}
+
+// reportMoves maps each moderation state to the states a report may move to
+// from it; closed reports may only be reopened
+var reportMoves = map[string][]string{
+	ReportOpen:      {ReportTriaged, ReportResolved, ReportDismissed},
+	ReportTriaged:   {ReportOpen, ReportResolved, ReportDismissed},
+	ReportResolved:  {ReportOpen},
+	ReportDismissed: {ReportOpen},
+}
+
+// QuestionReport is a learner's report that a question is wrong, outdated or
+// ambiguous. Revision is the revision the learner saw: the one graded in
+// SubmissionID when the report is tied to a submission, the current one
+// otherwise.
+type QuestionReport struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	BankID       uint       `json:"bankId" gorm:"not null;index"`
+	QuestionID   uint       `json:"questionId" gorm:"not null;index"`
+	Revision     int        `json:"revision"`
+	SubmissionID *uint      `json:"submissionId,omitempty" gorm:"index"`
+	Reason       string     `json:"reason" gorm:"not null"`
+	Comment      string     `json:"comment" gorm:"type:text"`
+	Reporter     string     `json:"reporter" gorm:"index"`
+	Status       string     `json:"status" gorm:"not null;default:'open';index"`
+	Resolution   string     `json:"resolution,omitempty" gorm:"type:text"`
+	Moderator    string     `json:"moderator,omitempty"`
+	ResolvedAt   *time.Time `json:"resolvedAt,omitempty"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	UpdatedAt    time.Time  `json:"updatedAt"`
+}
+
+// QuestionReportRequest represents the API request format for reporting a
+// question
+type QuestionReportRequest struct {
+	Reason       string `json:"reason" binding:"required"`
+	Comment      string `json:"comment"`
+	SubmissionID *uint  `json:"submissionId"`
+	UserID       string `json:"userId"` // used when there is no X-User-ID header
+}
+
+// ModerationRequest represents the API request format for moderating a report
+type ModerationRequest struct {
+	Status     string `json:"status" binding:"required"`
+	Resolution string `json:"resolution"`
+}
+
+// ReportedQuestion is an entry of the moderation queue: a report together
+// with the text of the question it is about
+type ReportedQuestion struct {
+	QuestionReport
+	Question        string `json:"question"`
+	CurrentRevision int    `json:"currentRevision"`
+}
+
+// TableName specifies the table name for the QuestionReport model
+func (QuestionReport) TableName() string {
+	return "question_reports"
+}
+
+// IsReportReason reports whether reason is a known report reason
+func IsReportReason(reason string) bool {
+	return contains(AllowedReportReasons, reason)
+}
+
+// IsReportStatus reports whether status is a known moderation state
+func IsReportStatus(status string) bool {
+	return contains(AllowedReportStatuses, status)
+}
+
+// Normalize trims the request
+func (r *QuestionReportRequest) Normalize() {
+	r.Reason = strings.ToLower(strings.TrimSpace(r.Reason))
+	r.Comment = strings.TrimSpace(r.Comment)
+	r.UserID = strings.TrimSpace(r.UserID)
+}
+
+// Validate checks the report's reason
+func (r *QuestionReportRequest) Validate() error {
+	if !IsReportReason(r.Reason) {
+		return fmt.Errorf("reason must be one of: %s", strings.Join(AllowedReportReasons, ", "))
+	}
+	return nil
+}
+
+// Moderate moves the report to the requested state on behalf of moderator.
+// Closing a report takes a resolution note, and reopening one clears it.
+func (r *QuestionReport) Moderate(moderator string, req ModerationRequest) error {
+	status := strings.ToLower(strings.TrimSpace(req.Status))
+	resolution := strings.TrimSpace(req.Resolution)
+
+	if _, ok := reportMoves[status]; !ok {
+		return fmt.Errorf("status must be one of: %s", strings.Join(AllowedReportStatuses, ", "))
+	}
+	if !contains(reportMoves[r.Status], status) {
+		return fmt.Errorf("cannot move a %s report to %s", r.Status, status)
+	}
+	closing := status == ReportResolved || status == ReportDismissed
+	if closing && resolution == "" {
+		return fmt.Errorf("a resolution is required when closing a report")
+	}
+
+	r.Status = status
+	r.Moderator = moderator
+	switch {
+	case closing:
+		now := time.Now()
+		r.Resolution, r.ResolvedAt = resolution, &now
+	case status == ReportOpen:
+		r.Resolution, r.ResolvedAt = "", nil
+	case resolution != "":
+		r.Resolution = resolution
+	}
+	return nil
+}
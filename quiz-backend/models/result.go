@@ -8,7 +8,7 @@ import (
 type QuizResult struct {
 	ID             uint               `json:"id"`
 	UserID         string             `json:"userId"`
-	Score          int                `json:"score"`
+	Score          float64            `json:"score"`
 	Total          int                `json:"total"`
 	Percentage     float64            `json:"percentage"`
 	CorrectAnswers int                `json:"correctAnswers"`
@@ -0,0 +1,171 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// QuestionRevision is an immutable snapshot of a question's content. A new
+// revision is recorded whenever a save changes the content, and quiz
+// submissions reference the revision each answer was graded against.
+type QuestionRevision struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	QuestionID     uint      `json:"questionId" gorm:"not null;uniqueIndex:idx_question_revision"`
+	Revision       int       `json:"revision" gorm:"not null;uniqueIndex:idx_question_revision"`
+	Question       string    `json:"question" gorm:"not null"`
+	Options        string    `json:"options" gorm:"type:text;not null"`
+	CorrectAnswer  int       `json:"correctAnswer" gorm:"not null"`
+	Explanation    string    `json:"explanation" gorm:"type:text"`
+	Rationales     string    `json:"rationales" gorm:"type:text"`
+	References     string    `json:"references" gorm:"type:text"`
+	Variants       string    `json:"variants" gorm:"type:text"`
+	Category       string    `json:"category"`
+	Difficulty     string    `json:"difficulty"`
+	Type           string    `json:"type"`
+	CorrectAnswers string    `json:"correctAnswers" gorm:"type:text"`
+	Scoring        string    `json:"scoring"`
+	AnswerKey      string    `json:"answerKey" gorm:"type:text"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+// QuestionRevisionResponse is one entry of a question's revision history.
+// Changes lists the fields that differ from the previous revision.
+type QuestionRevisionResponse struct {
+	Revision  int             `json:"revision"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Question  QuestionRequest `json:"question"`
+	Changes   []FieldChange   `json:"changes,omitempty"`
+}
+
+// FieldChange is a single field's old and new value
+type FieldChange struct {
+	Field string          `json:"field"`
+	From  json.RawMessage `json:"from"`
+	To    json.RawMessage `json:"to"`
+}
+
+// TableName specifies the table name for the QuestionRevision model
+func (QuestionRevision) TableName() string {
+	return "question_revisions"
+}
+
+// AfterSave records a revision when a create or update changed the
+// question's content, and saves the question's tags
+func (q *Question) AfterSave(tx *gorm.DB) error {
+	revision, err := RecordRevision(tx, q.ID)
+	if err != nil {
+		return err
+	}
+	q.Revision = revision
+	return q.saveTags(tx)
+}
+
+// RecordRevision snapshots the stored question if its content differs from
+// its latest revision and returns the question's current revision number
+func RecordRevision(db *gorm.DB, questionID uint) (int, error) {
+	db = db.Session(&gorm.Session{NewDB: true})
+
+	var question Question
+	if err := db.Unscoped().First(&question, questionID).Error; err != nil {
+		return 0, fmt.Errorf("failed to load question %d: %v", questionID, err)
+	}
+
+	var latest QuestionRevision
+	err := db.Where("question_id = ?", questionID).Order("revision DESC").Limit(1).Find(&latest).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to load revisions of question %d: %v", questionID, err)
+	}
+
+	revision := latest.Revision
+	if latest.ID == 0 || !latest.sameContent(&question) {
+		snapshot := question.snapshot(latest.Revision + 1)
+		if err := db.Create(&snapshot).Error; err != nil {
+			return 0, fmt.Errorf("failed to record revision of question %d: %v", questionID, err)
+		}
+		revision = snapshot.Revision
+	}
+
+	if question.Revision != revision {
+		err := db.Model(&Question{}).Where("id = ?", questionID).UpdateColumn("revision", revision).Error
+		if err != nil {
+			return 0, err
+		}
+	}
+	return revision, nil
+}
+
+// snapshot copies the question's content into a revision
+func (q *Question) snapshot(revision int) QuestionRevision {
+	return QuestionRevision{
+		QuestionID:     q.ID,
+		Revision:       revision,
+		Question:       q.Question,
+		Options:        q.Options,
+		CorrectAnswer:  q.CorrectAnswer,
+		Explanation:    q.Explanation,
+		Rationales:     q.Rationales,
+		References:     q.References,
+		Variants:       q.Variants,
+		Category:       q.Category,
+		Difficulty:     q.Difficulty,
+		Type:           q.Type,
+		CorrectAnswers: q.CorrectAnswers,
+		Scoring:        q.Scoring,
+		AnswerKey:      q.AnswerKey,
+	}
+}
+
+// sameContent reports whether the revision matches the question's content
+func (r *QuestionRevision) sameContent(q *Question) bool {
+	current := q.snapshot(r.Revision)
+	current.ID, current.CreatedAt = r.ID, r.CreatedAt
+	return current == *r
+}
+
+// ToQuestion rebuilds the question as it was at this revision, for grading
+func (r *QuestionRevision) ToQuestion() Question {
+	return Question{
+		ID:             r.QuestionID,
+		Revision:       r.Revision,
+		Question:       r.Question,
+		Options:        r.Options,
+		CorrectAnswer:  r.CorrectAnswer,
+		Explanation:    r.Explanation,
+		Rationales:     r.Rationales,
+		References:     r.References,
+		Variants:       r.Variants,
+		Category:       r.Category,
+		Difficulty:     r.Difficulty,
+		Type:           r.Type,
+		CorrectAnswers: r.CorrectAnswers,
+		Scoring:        r.Scoring,
+		AnswerKey:      r.AnswerKey,
+	}
+}
+
+// DiffRequests lists the fields that differ between two versions of a
+// question, in the order they appear in QuestionRequest
+func DiffRequests(from, to QuestionRequest) ([]FieldChange, error) {
+	var changes []FieldChange
+	fromValue, toValue := reflect.ValueOf(from), reflect.ValueOf(to)
+	for i := 0; i < fromValue.NumField(); i++ {
+		name := strings.Split(fromValue.Type().Field(i).Tag.Get("json"), ",")[0]
+		before, err := json.Marshal(fromValue.Field(i).Interface())
+		if err != nil {
+			return nil, err
+		}
+		after, err := json.Marshal(toValue.Field(i).Interface())
+		if err != nil {
+			return nil, err
+		}
+		if string(before) != string(after) {
+			changes = append(changes, FieldChange{Field: name, From: before, To: after})
+		}
+	}
+	return changes, nil
+}
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// QuizSession is a quiz served by the server: the exact questions, in the
+// order served, together with the revision and template variant of each.
+// Answers are submitted against the session, once, and before ClosesAt when
+// the session has a time limit. Exam sessions withhold the answers until the
+// result is reviewed, as their Review policy allows.
+type QuizSession struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	BankID       uint       `json:"bankId" gorm:"not null;index"`
+	UserID       string     `json:"userId" gorm:"index"`
+	Questions    string     `json:"questions" gorm:"type:text;not null"` // JSON array of SessionQuestion
+	Mode         string     `json:"mode" gorm:"not null;default:'practice'"`
+	Review       string     `json:"review" gorm:"not null;default:'immediate'"`
+	StartedAt    time.Time  `json:"startedAt" gorm:"not null"`
+	ClosesAt     *time.Time `json:"closesAt,omitempty"`
+	SubmissionID *uint      `json:"submissionId,omitempty"` // set once answers are submitted
+	CreatedAt    time.Time  `json:"createdAt"`
+	UpdatedAt    time.Time  `json:"updatedAt"`
+}
+
+// SessionQuestion is a question served in a session
+type SessionQuestion struct {
+	QuestionID uint `json:"questionId"`
+	Revision   int  `json:"revision"`
+	Variant    *int `json:"variant,omitempty"` // set for template questions
+}
+
+// QuizSessionResponse represents the API response format for a new session
+type QuizSessionResponse struct {
+	ID        uint               `json:"id"`
+	UserID    string             `json:"userId,omitempty"`
+	Mode      string             `json:"mode"`
+	Review    string             `json:"review"`
+	StartedAt time.Time          `json:"startedAt"`
+	ClosesAt  *time.Time         `json:"closesAt,omitempty"`
+	Questions []QuestionResponse `json:"questions"`
+}
+
+// SessionSubmissionRequest represents the API request format for submitting
+// a session's answers, keyed by question ID as in QuizSubmissionRequest.
+// Questions left out are unanswered and scored as wrong.
+type SessionSubmissionRequest struct {
+	Answers map[string]json.RawMessage `json:"answers" binding:"required"`
+}
+
+// TableName specifies the table name for the QuizSession model
+func (QuizSession) TableName() string {
+	return "quiz_sessions"
+}
+
+// DecodeQuestions parses the questions column, ignoring a malformed column
+func (s *QuizSession) DecodeQuestions() []SessionQuestion {
+	var questions []SessionQuestion
+	if s.Questions != "" {
+		json.Unmarshal([]byte(s.Questions), &questions)
+	}
+	return questions
+}
+
+// Served reports whether the session served the question keyed by qid
+func (s *QuizSession) Served(qid string) bool {
+	for _, q := range s.DecodeQuestions() {
+		if strconv.FormatUint(uint64(q.QuestionID), 10) == qid {
+			return true
+		}
+	}
+	return false
+}
+
+// Grading returns the revisions and variants served, keyed by question ID as
+// submissions store them
+func (s *QuizSession) Grading() (revisions, variants map[string]int) {
+	revisions = make(map[string]int)
+	variants = make(map[string]int)
+	for _, q := range s.DecodeQuestions() {
+		key := strconv.FormatUint(uint64(q.QuestionID), 10)
+		revisions[key] = q.Revision
+		if q.Variant != nil {
+			variants[key] = *q.Variant
+		}
+	}
+	return revisions, variants
+}
+
+// Unanswered lists the served questions, in the order served, that answers
+// has no answer for
+func (s *QuizSession) Unanswered(answers map[string]json.RawMessage) []uint {
+	unanswered := []uint{}
+	for _, q := range s.DecodeQuestions() {
+		if _, ok := answers[strconv.FormatUint(uint64(q.QuestionID), 10)]; !ok {
+			unanswered = append(unanswered, q.QuestionID)
+		}
+	}
+	return unanswered
+}
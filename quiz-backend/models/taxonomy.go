@@ -0,0 +1,271 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TopicSeparator joins topic names into a path such as
+// "RDS > High Availability > Multi-AZ"
+const TopicSeparator = " > "
+
+// Tag is a free-form label; questions and tags are many-to-many
+type Tag struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"not null;uniqueIndex"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Topic is a node in the topic tree. Path holds the IDs from the root down to
+// the topic ("/1/4/9/") so a subtree is a prefix match, and FullName holds the
+// names joined by TopicSeparator. Exam domains mapped to a topic apply to its
+// whole subtree.
+type Topic struct {
+	ID          uint         `json:"id" gorm:"primaryKey"`
+	Name        string       `json:"name" gorm:"not null"`
+	ParentID    *uint        `json:"parentId" gorm:"index"`
+	Path        string       `json:"path" gorm:"index"`
+	FullName    string       `json:"fullName"`
+	ExamDomains []ExamDomain `json:"examDomains" gorm:"many2many:topic_exam_domains"`
+	CreatedAt   time.Time    `json:"createdAt"`
+	UpdatedAt   time.Time    `json:"updatedAt"`
+}
+
+// ExamDomain is a domain of an AWS certification exam, e.g. domain "2" of
+// DBS-C01, "Workload-Specific Database Design"
+type ExamDomain struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Exam      string    `json:"exam" gorm:"not null;uniqueIndex:idx_exam_domain"`
+	Code      string    `json:"code" gorm:"not null;uniqueIndex:idx_exam_domain"`
+	Name      string    `json:"name" gorm:"not null"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// TagRequest represents the API request format for tags
+type TagRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// TopicRequest represents the API request format for topics
+type TopicRequest struct {
+	Name          string `json:"name" binding:"required"`
+	ParentID      *uint  `json:"parentId"`
+	ExamDomainIDs []uint `json:"examDomainIds"`
+}
+
+// ExamDomainRequest represents the API request format for exam domains
+type ExamDomainRequest struct {
+	Exam string `json:"exam" binding:"required"`
+	Code string `json:"code" binding:"required"`
+	Name string `json:"name" binding:"required"`
+}
+
+// TableName specifies the table name for the Tag model
+func (Tag) TableName() string {
+	return "tags"
+}
+
+// TableName specifies the table name for the Topic model
+func (Topic) TableName() string {
+	return "topics"
+}
+
+// TableName specifies the table name for the ExamDomain model
+func (ExamDomain) TableName() string {
+	return "exam_domains"
+}
+
+// WithTaxonomy preloads a question's tags and topic, which ToRequest and
+// ToResponse include
+func WithTaxonomy(db *gorm.DB) *gorm.DB {
+	return db.Preload("Tags", func(db *gorm.DB) *gorm.DB {
+		return db.Order("tags.name")
+	}).Preload("Topic")
+}
+
+// NormalizeTag trims and lower-cases a tag name
+func NormalizeTag(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// NormalizeTopicPath trims each name of a topic path and rejoins them with
+// TopicSeparator
+func NormalizeTopicPath(path string) string {
+	if strings.TrimSpace(path) == "" {
+		return ""
+	}
+	names := strings.Split(path, ">")
+	for i := range names {
+		names[i] = strings.TrimSpace(names[i])
+	}
+	return strings.Join(names, TopicSeparator)
+}
+
+// normalizeTags trims, lower-cases, de-duplicates and sorts tag names
+func normalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	var result []string
+	for _, tag := range tags {
+		tag = NormalizeTag(tag)
+		if !seen[tag] {
+			seen[tag] = true
+			result = append(result, tag)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// validateTaxonomy checks tag names and the topic path
+func (r *QuestionRequest) validateTaxonomy() error {
+	for _, tag := range r.Tags {
+		if tag == "" {
+			return fmt.Errorf("tags must not be empty")
+		}
+		if len(tag) > 50 {
+			return fmt.Errorf("tag %q is longer than 50 characters", tag)
+		}
+	}
+	if r.Topic != "" {
+		for _, name := range strings.Split(r.Topic, TopicSeparator) {
+			if name == "" {
+				return fmt.Errorf("topic %q has an empty name", r.Topic)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateTopicName checks a single topic name
+func ValidateTopicName(name string) error {
+	if name == "" {
+		return fmt.Errorf("topic name is required")
+	}
+	if strings.Contains(name, ">") {
+		return fmt.Errorf("topic names must not contain \">\"")
+	}
+	return nil
+}
+
+// BeforeSave resolves the topic path set by QuestionRequest.ApplyTo,
+// creating any topics that do not exist yet
+func (q *Question) BeforeSave(tx *gorm.DB) error {
+	if q.topicPath == nil {
+		return nil
+	}
+	if *q.topicPath == "" {
+		q.TopicID, q.Topic = nil, nil
+		return nil
+	}
+	topic, err := ResolveTopic(tx, *q.topicPath)
+	if err != nil {
+		return err
+	}
+	q.TopicID, q.Topic = &topic.ID, topic
+	return nil
+}
+
+// saveTags replaces the question's tags with those set by
+// QuestionRequest.ApplyTo, creating any tags that do not exist yet
+func (q *Question) saveTags(tx *gorm.DB) error {
+	if q.tagNames == nil {
+		return nil
+	}
+	db := tx.Session(&gorm.Session{NewDB: true})
+
+	tags := make([]Tag, 0, len(q.tagNames))
+	for _, name := range q.tagNames {
+		tag := Tag{Name: name}
+		if err := db.Where(Tag{Name: name}).FirstOrCreate(&tag).Error; err != nil {
+			return fmt.Errorf("failed to save tag %q: %v", name, err)
+		}
+		tags = append(tags, tag)
+	}
+	// The join rows are written directly: Association.Replace saves q
+	// again, which would re-enter this hook
+	if err := db.Exec("DELETE FROM question_tags WHERE question_id = ?", q.ID).Error; err != nil {
+		return fmt.Errorf("failed to save tags: %v", err)
+	}
+	for _, tag := range tags {
+		if err := db.Exec("INSERT INTO question_tags (question_id, tag_id) VALUES (?, ?)", q.ID, tag.ID).Error; err != nil {
+			return fmt.Errorf("failed to save tags: %v", err)
+		}
+	}
+	q.Tags, q.tagNames = tags, nil
+	return nil
+}
+
+// ResolveTopic finds the topic with the given path, creating missing topics
+// along the way
+func ResolveTopic(tx *gorm.DB, path string) (*Topic, error) {
+	db := tx.Session(&gorm.Session{NewDB: true})
+
+	var parent *Topic
+	for _, name := range strings.Split(NormalizeTopicPath(path), TopicSeparator) {
+		if err := ValidateTopicName(name); err != nil {
+			return nil, err
+		}
+
+		query := db.Where("name = ?", name)
+		if parent == nil {
+			query = query.Where("parent_id IS NULL")
+		} else {
+			query = query.Where("parent_id = ?", parent.ID)
+		}
+		var topic Topic
+		if err := query.Limit(1).Find(&topic).Error; err != nil {
+			return nil, err
+		}
+		if topic.ID == 0 {
+			topic = Topic{Name: name}
+			if parent != nil {
+				topic.ParentID = &parent.ID
+			}
+			if err := db.Create(&topic).Error; err != nil {
+				return nil, fmt.Errorf("failed to create topic %q: %v", name, err)
+			}
+			topic.SetLineage(parent)
+			if err := db.Model(&topic).Updates(map[string]interface{}{"path": topic.Path, "full_name": topic.FullName}).Error; err != nil {
+				return nil, err
+			}
+		}
+		parent = &topic
+	}
+	return parent, nil
+}
+
+// SetLineage computes Path and FullName from the topic's parent
+func (t *Topic) SetLineage(parent *Topic) {
+	id := strconv.FormatUint(uint64(t.ID), 10)
+	if parent == nil {
+		t.Path, t.FullName = "/"+id+"/", t.Name
+		return
+	}
+	t.Path, t.FullName = parent.Path+id+"/", parent.FullName+TopicSeparator+t.Name
+}
+
+// MoveSubtree rewrites the Path and FullName of the topic's descendants after
+// the topic itself was renamed or moved from oldPath/oldFullName
+func (t *Topic) MoveSubtree(tx *gorm.DB, oldPath, oldFullName string) error {
+	var descendants []Topic
+	if err := tx.Where("path LIKE ? AND id <> ?", oldPath+"%", t.ID).Find(&descendants).Error; err != nil {
+		return err
+	}
+	for _, d := range descendants {
+		updates := map[string]interface{}{
+			"path":      t.Path + strings.TrimPrefix(d.Path, oldPath),
+			"full_name": t.FullName + strings.TrimPrefix(d.FullName, oldFullName),
+		}
+		if err := tx.Model(&Topic{}).Where("id = ?", d.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
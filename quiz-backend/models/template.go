@@ -0,0 +1,356 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MaxVariants is the largest value table a template question may have
+const MaxVariants = 50
+
+// placeholder matches a {name} placeholder in a template question
+var placeholder = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// templateTypes lists the question types whose answer key can vary between
+// variants through placeholders in its text
+var templateTypes = []string{QuestionTypeSingle, QuestionTypeMultiple, QuestionTypeOrdering, QuestionTypeMatching, QuestionTypeFillBlank, QuestionTypeSQL}
+
+// IsTemplate reports whether the question is a template with variants
+func (q *Question) IsTemplate() bool {
+	return q.Variants != ""
+}
+
+// DecodeVariants parses the template value table, ignoring a malformed column
+func (q *Question) DecodeVariants() []map[string]string {
+	var variants []map[string]string
+	if q.Variants != "" {
+		json.Unmarshal([]byte(q.Variants), &variants)
+	}
+	return variants
+}
+
+// Variant returns the concrete question for one row of a template's value
+// table, for grading
+func (q *Question) Variant(index int) (Question, error) {
+	req, err := q.ToRequest()
+	if err != nil {
+		return Question{}, err
+	}
+	instance, err := req.Instantiate(index)
+	if err != nil {
+		return Question{}, err
+	}
+	variant := *q
+	if err := instance.ApplyTo(&variant); err != nil {
+		return Question{}, err
+	}
+	return variant, nil
+}
+
+// Instantiate turns a template response, possibly localized, into one of its
+// variants for serving
+func (r *QuestionResponse) Instantiate(index int) error {
+	template := QuestionRequest{
+		Question:       r.Question,
+		Options:        r.Options,
+		CorrectAnswer:  *r.CorrectAnswer,
+		Explanation:    r.Explanation,
+		Rationales:     r.Rationales,
+		Variants:       r.Variants,
+		Type:           r.Type,
+		CorrectAnswers: r.CorrectAnswers,
+		Scoring:        r.Scoring,
+		AnswerKey:      r.AnswerKey,
+	}
+	instance, err := template.Instantiate(index)
+	if err != nil {
+		return err
+	}
+
+	key := Question{Type: instance.Type, AnswerKey: string(instance.AnswerKey)}
+	r.Question = instance.Question
+	r.Options = instance.Options
+	r.CorrectAnswer = &instance.CorrectAnswer
+	r.Explanation = instance.Explanation
+	r.Rationales = instance.Rationales
+	r.CorrectAnswers = instance.CorrectAnswers
+	r.AnswerKey = instance.AnswerKey
+	r.Targets = key.MatchingTargets()
+	r.Attachments = instance.AttachmentRefs()
+	r.Variants = nil
+	r.Variant = &index
+	return nil
+}
+
+// normalizeTemplate trims the names and values of the value table
+func (r *QuestionRequest) normalizeTemplate() {
+	for i, row := range r.Variants {
+		trimmed := make(map[string]string, len(row))
+		for name, value := range row {
+			trimmed[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		}
+		r.Variants[i] = trimmed
+	}
+}
+
+// validateTemplate checks that every variant fills in exactly the
+// placeholders the question uses and yields a valid, distinct question. The
+// template itself is not validated, since placeholders may stand in for
+// parts of the answer key such as a SQL fixture.
+func (r *QuestionRequest) validateTemplate() error {
+	if !contains(templateTypes, r.Type) {
+		return fmt.Errorf("templates are only supported for %s questions", strings.Join(templateTypes, ", "))
+	}
+	if len(r.Variants) > MaxVariants {
+		return fmt.Errorf("a template may have at most %d variants, got %d", MaxVariants, len(r.Variants))
+	}
+
+	used, err := r.placeholders()
+	if err != nil {
+		return err
+	}
+	if len(used) == 0 {
+		return fmt.Errorf("template has variants but no {placeholders}")
+	}
+
+	seen := make(map[string]int, len(r.Variants))
+	for i, row := range r.Variants {
+		for _, name := range used {
+			if row[name] == "" {
+				return fmt.Errorf("variant %d has no value for {%s}", i+1, name)
+			}
+		}
+		for name := range row {
+			if !contains(used, name) {
+				return fmt.Errorf("variant %d sets {%s}, which the question does not use", i+1, name)
+			}
+		}
+
+		instance, err := r.Instantiate(i)
+		if err != nil {
+			return err
+		}
+		if err := instance.Validate(); err != nil {
+			return fmt.Errorf("variant %d: %v", i+1, err)
+		}
+		if j, ok := seen[instance.Question]; ok {
+			return fmt.Errorf("variants %d and %d produce the same question", j+1, i+1)
+		}
+		seen[instance.Question] = i
+	}
+	return nil
+}
+
+// applyTemplate stores the value table as a JSON column
+func (r *QuestionRequest) applyTemplate(q *Question) error {
+	q.Variants = ""
+	if len(r.Variants) > 0 {
+		data, err := json.Marshal(r.Variants)
+		if err != nil {
+			return fmt.Errorf("failed to marshal variants: %v", err)
+		}
+		q.Variants = string(data)
+	}
+	return nil
+}
+
+// placeholders lists the placeholder names used by the question text,
+// options, explanation, rationales and answer key, sorted
+func (r *QuestionRequest) placeholders() ([]string, error) {
+	found := map[string]bool{}
+	collect := func(s string) string {
+		for _, m := range placeholder.FindAllStringSubmatch(s, -1) {
+			found[m[1]] = true
+		}
+		return s
+	}
+	collect(r.Question)
+	collect(r.Explanation)
+	for _, s := range append(append([]string{}, r.Options...), r.Rationales...) {
+		collect(s)
+	}
+	if _, err := substituteKey(r.AnswerKey, collect); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(found))
+	for name := range found {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Instantiate returns the concrete question for one row of the value table.
+// Placeholders are replaced in the question text, options, explanation,
+// rationales and the strings of the answer key. The options of choice
+// questions are shuffled in an order fixed by the variant's values, so the
+// correct option does not sit in the same place in every variant while a
+// variant, translated or not, always comes out the same for grading.
+func (r QuestionRequest) Instantiate(index int) (QuestionRequest, error) {
+	if index < 0 || index >= len(r.Variants) {
+		return r, fmt.Errorf("variant %d does not exist", index)
+	}
+	values := r.Variants[index]
+	fill := func(s string) string {
+		return placeholder.ReplaceAllStringFunc(s, func(m string) string {
+			if value, ok := values[m[1:len(m)-1]]; ok {
+				return value
+			}
+			return m
+		})
+	}
+
+	instance := r
+	instance.Variants = nil
+	instance.Question = fill(r.Question)
+	instance.Explanation = fill(r.Explanation)
+	instance.Options = fillAll(r.Options, fill)
+	instance.Rationales = fillAll(r.Rationales, fill)
+	key, err := substituteKey(r.AnswerKey, fill)
+	if err != nil {
+		return r, err
+	}
+	instance.AnswerKey = key
+
+	if instance.Type == QuestionTypeSingle || instance.Type == QuestionTypeMultiple {
+		instance.shuffleOptions(variantSeed(values, index))
+	}
+	return instance, nil
+}
+
+// shuffleOptions reorders the options and rationales, moving the correct
+// answers along with them
+func (r *QuestionRequest) shuffleOptions(seed int64) {
+	order := rand.New(rand.NewSource(seed)).Perm(len(r.Options))
+	position := make([]int, len(order)) // original index -> new index
+	options := make([]string, len(order))
+	for i, from := range order {
+		options[i] = r.Options[from]
+		position[from] = i
+	}
+	r.Options = options
+
+	if len(r.Rationales) == len(order) {
+		rationales := make([]string, len(order))
+		for i, from := range order {
+			rationales[i] = r.Rationales[from]
+		}
+		r.Rationales = rationales
+	}
+	if r.CorrectAnswer >= 0 && r.CorrectAnswer < len(position) {
+		r.CorrectAnswer = position[r.CorrectAnswer]
+	}
+	if len(r.CorrectAnswers) > 0 {
+		correct := make([]int, 0, len(r.CorrectAnswers))
+		for _, index := range r.CorrectAnswers {
+			if index >= 0 && index < len(position) {
+				correct = append(correct, position[index])
+			}
+		}
+		sort.Ints(correct)
+		r.CorrectAnswers = correct
+		if len(correct) > 0 {
+			r.CorrectAnswer = correct[0]
+		}
+	}
+}
+
+// variantSeed derives the option order of a variant from its values
+func variantSeed(values map[string]string, index int) int64 {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%d", index)
+	for _, name := range names {
+		fmt.Fprintf(h, "\x00%s=%s", name, values[name])
+	}
+	return int64(h.Sum64())
+}
+
+// fillAll applies fill to each string, keeping nil as nil
+func fillAll(values []string, fill func(string) string) []string {
+	if values == nil {
+		return nil
+	}
+	filled := make([]string, len(values))
+	for i, value := range values {
+		filled[i] = fill(value)
+	}
+	return filled
+}
+
+// substituteKey applies fill to every string in an answer key
+func substituteKey(key json.RawMessage, fill func(string) string) (json.RawMessage, error) {
+	if len(key) == 0 {
+		return key, nil
+	}
+	var value interface{}
+	if err := json.Unmarshal(key, &value); err != nil {
+		return nil, fmt.Errorf("invalid answerKey: %v", err)
+	}
+	data, err := json.Marshal(substituteValue(value, fill))
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func substituteValue(value interface{}, fill func(string) string) interface{} {
+	switch v := value.(type) {
+	case string:
+		return fill(v)
+	case []interface{}:
+		for i := range v {
+			v[i] = substituteValue(v[i], fill)
+		}
+	case map[string]interface{}:
+		for k := range v {
+			v[k] = substituteValue(v[k], fill)
+		}
+	}
+	return value
+}
+
+// SignVariant returns the servedVariant token of a variant of a template
+// question served outside a quiz session. Submissions name the token instead
+// of a variant index, and as it is signed with secret the server need not
+// remember what it served to grade each answer against the variant that was
+// actually served.
+func SignVariant(secret []byte, questionID uint, variant int) string {
+	return strconv.Itoa(variant) + "." + variantMAC(secret, questionID, variant)
+}
+
+// VerifyVariant returns the variant a servedVariant token names, or false
+// when the token was not signed with secret for the question
+func VerifyVariant(secret []byte, questionID uint, token string) (int, bool) {
+	variantStr, mac, ok := strings.Cut(token, ".")
+	if !ok {
+		return 0, false
+	}
+	variant, err := strconv.Atoi(variantStr)
+	if err != nil || variant < 0 {
+		return 0, false
+	}
+	return variant, hmac.Equal([]byte(mac), []byte(variantMAC(secret, questionID, variant)))
+}
+
+// variantMAC authenticates a question ID and variant index
+func variantMAC(secret []byte, questionID uint, variant int) string {
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%d:%d", questionID, variant)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
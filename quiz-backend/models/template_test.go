@@ -0,0 +1,130 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const replicaTemplate = `{"question": "How many read replicas can RDS for {engine} have?",
+	"options": ["{max}", "{wrong1}", "{wrong2}"], "correctAnswer": 0,
+	"variants": [{"engine": "MySQL", "max": "15", "wrong1": "5", "wrong2": "10"},
+	             {"engine": "Oracle", "max": "5", "wrong1": "15", "wrong2": "10"}]}`
+
+func TestValidateTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr string
+	}{
+		{"valid", replicaTemplate, ""},
+		{"no placeholders", `{"question": "q", "options": ["a", "b"], "variants": [{"x": "1"}]}`, "no {placeholders}"},
+		{"missing value", `{"question": "{a} {b}", "options": ["x", "y"], "variants": [{"a": "1"}]}`, "variant 1 has no value for {b}"},
+		{"unused value", `{"question": "{a}", "options": ["x", "y"], "variants": [{"a": "1", "b": "2"}]}`, "variant 1 sets {b}"},
+		{"same question", `{"question": "q {a}", "options": ["x {b}", "y"], "variants": [{"a": "1", "b": "2"}, {"a": "1", "b": "3"}]}`, "variants 1 and 2 produce the same question"},
+		{"unsupported type", `{"question": "{a}?", "type": "numeric", "answerKey": {"value": 1}, "variants": [{"a": "1"}]}`, "templates are only supported"},
+		{"answer key placeholder", `{"question": "Port of {engine}?", "type": "fill_blank", "answerKey": {"accepted": ["{port}"]},
+			"variants": [{"engine": "MySQL", "port": "3306"}, {"engine": "PostgreSQL", "port": "5432"}]}`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := validate(t, tt.body)
+			checkError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestInstantiate(t *testing.T) {
+	req, err := validate(t, replicaTemplate)
+	if err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	tests := []struct {
+		index    int
+		question string
+		correct  string
+	}{
+		{0, "How many read replicas can RDS for MySQL have?", "15"},
+		{1, "How many read replicas can RDS for Oracle have?", "5"},
+	}
+
+	for _, tt := range tests {
+		instance, err := req.Instantiate(tt.index)
+		if err != nil {
+			t.Fatalf("Instantiate(%d) error = %v", tt.index, err)
+		}
+		if instance.Question != tt.question {
+			t.Errorf("Instantiate(%d) question = %q, want %q", tt.index, instance.Question, tt.question)
+		}
+		// The options are shuffled, but the correct answer moves with them
+		if got := instance.Options[instance.CorrectAnswer]; got != tt.correct {
+			t.Errorf("Instantiate(%d) correct option = %q, want %q", tt.index, got, tt.correct)
+		}
+		again, _ := req.Instantiate(tt.index)
+		if strings.Join(again.Options, ",") != strings.Join(instance.Options, ",") {
+			t.Errorf("Instantiate(%d) options %v then %v, want a fixed order", tt.index, instance.Options, again.Options)
+		}
+	}
+
+	if _, err := req.Instantiate(2); err == nil {
+		t.Error("Instantiate(2) error = nil, want an error for a missing variant")
+	}
+}
+
+func TestQuestionVariant(t *testing.T) {
+	req, err := validate(t, `{"question": "Port of {engine}?", "type": "fill_blank", "answerKey": {"accepted": ["{port}"]},
+		"variants": [{"engine": "MySQL", "port": "3306"}, {"engine": "PostgreSQL", "port": "5432"}]}`)
+	if err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	var q Question
+	if err := req.ApplyTo(&q); err != nil {
+		t.Fatalf("ApplyTo() error = %v", err)
+	}
+
+	variant, err := q.Variant(1)
+	if err != nil {
+		t.Fatalf("Variant(1) error = %v", err)
+	}
+	var key FillBlankKey
+	if err := json.Unmarshal([]byte(variant.AnswerKey), &key); err != nil {
+		t.Fatalf("invalid answer key: %v", err)
+	}
+	if variant.Question != "Port of PostgreSQL?" || len(key.Accepted) != 1 || key.Accepted[0] != "5432" {
+		t.Errorf("Variant(1) = %q accepting %v, want the PostgreSQL variant accepting 5432", variant.Question, key.Accepted)
+	}
+}
+
+func TestVerifyVariant(t *testing.T) {
+	secret := []byte("secret")
+	token := SignVariant(secret, 7, 2)
+	mac := token[strings.Index(token, ".")+1:]
+
+	tests := []struct {
+		name       string
+		secret     []byte
+		questionID uint
+		token      string
+		want       int
+		ok         bool
+	}{
+		{"signed", secret, 7, token, 2, true},
+		{"other question", secret, 8, token, 0, false},
+		{"other secret", []byte("other"), 7, token, 0, false},
+		{"other variant", secret, 7, "1." + mac, 0, false},
+		{"negative variant", secret, 7, "-1." + mac, 0, false},
+		{"no signature", secret, 7, "2", 0, false},
+		{"empty", secret, 7, "", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			variant, ok := VerifyVariant(tt.secret, tt.questionID, tt.token)
+			if ok != tt.ok || (ok && variant != tt.want) {
+				t.Errorf("VerifyVariant() = %d, %v, want %d, %v", variant, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Translation states reported for a question in a locale
+const (
+	TranslationCurrent = "current"
+	TranslationStale   = "stale"   // the source text changed after translating
+	TranslationMissing = "missing" // never translated
+)
+
+// QuestionTranslation holds a question's text, options and explanation in
+// another locale. SourceHash identifies the source text it was translated
+// from, so edits to the question make the translation stale.
+type QuestionTranslation struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	QuestionID     uint      `json:"questionId" gorm:"not null;uniqueIndex:idx_question_translations_locale"`
+	Locale         string    `json:"locale" gorm:"size:35;not null;uniqueIndex:idx_question_translations_locale;index"`
+	Question       string    `json:"question" gorm:"not null"`
+	Options        string    `json:"options" gorm:"type:text"` // JSON array as string
+	Explanation    string    `json:"explanation" gorm:"type:text"`
+	SourceHash     string    `json:"sourceHash" gorm:"size:64;not null"`
+	SourceRevision int       `json:"sourceRevision"` // question revision when translated
+	Translator     string    `json:"translator"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+// TranslationRequest represents the API request format for a translation
+type TranslationRequest struct {
+	Question    string   `json:"question" binding:"required"`
+	Options     []string `json:"options"`
+	Explanation string   `json:"explanation"`
+}
+
+// TranslationResponse represents the API response format for a translation
+type TranslationResponse struct {
+	Locale         string    `json:"locale"`
+	Question       string    `json:"question"`
+	Options        []string  `json:"options"`
+	Explanation    string    `json:"explanation,omitempty"`
+	Status         string    `json:"status"` // current or stale
+	SourceRevision int       `json:"sourceRevision"`
+	Translator     string    `json:"translator,omitempty"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+// PendingTranslation is a question whose translation into a locale is
+// missing or stale
+type PendingTranslation struct {
+	QuestionID     uint   `json:"questionId"`
+	Revision       int    `json:"revision"`
+	Question       string `json:"question"` // source text
+	Status         string `json:"status"`
+	SourceRevision int    `json:"sourceRevision,omitempty"` // revision the stale translation was made from
+}
+
+// LocaleCoverage counts the questions translated into a locale
+type LocaleCoverage struct {
+	Locale     string  `json:"locale"`
+	Current    int     `json:"current"`
+	Stale      int     `json:"stale"`
+	Missing    int     `json:"missing"`
+	Percentage float64 `json:"percentage"` // share of questions with a current translation
+}
+
+// TranslationCoverage reports translation coverage across locales
+type TranslationCoverage struct {
+	SourceLocale string           `json:"sourceLocale"`
+	Total        int              `json:"total"`
+	Locales      []LocaleCoverage `json:"locales"`
+}
+
+// TableName specifies the table name for the QuestionTranslation model
+func (QuestionTranslation) TableName() string {
+	return "question_translations"
+}
+
+// TranslationSourceHash identifies the translatable text of a question
+func TranslationSourceHash(question string, options []string, explanation string) string {
+	data, _ := json.Marshal([]interface{}{question, options, explanation})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Normalize trims the translated text
+func (r *TranslationRequest) Normalize() {
+	r.Question = strings.TrimSpace(r.Question)
+	r.Explanation = strings.TrimSpace(r.Explanation)
+	for i := range r.Options {
+		r.Options[i] = strings.TrimSpace(r.Options[i])
+	}
+}
+
+// Validate checks the translation against the question it translates
+func (r *TranslationRequest) Validate(source QuestionResponse) error {
+	if r.Question == "" {
+		return fmt.Errorf("question text is required")
+	}
+	if len(r.Options) != len(source.Options) {
+		return fmt.Errorf("translation must have one option per source option (%d), got %d", len(source.Options), len(r.Options))
+	}
+	for i, option := range r.Options {
+		if option == "" {
+			return fmt.Errorf("option %d is empty", i+1)
+		}
+	}
+	return nil
+}
+
+// ApplyTo stores the translation of source in t
+func (r *TranslationRequest) ApplyTo(t *QuestionTranslation, source QuestionResponse) error {
+	options, err := json.Marshal(r.Options)
+	if err != nil {
+		return fmt.Errorf("failed to marshal options: %v", err)
+	}
+	t.Question = r.Question
+	t.Options = string(options)
+	t.Explanation = r.Explanation
+	t.SourceHash = TranslationSourceHash(source.Question, source.Options, source.Explanation)
+	t.SourceRevision = source.Revision
+	return nil
+}
+
+// DecodeOptions parses the translated options, ignoring a malformed column
+func (t *QuestionTranslation) DecodeOptions() []string {
+	options := []string{}
+	if t.Options != "" {
+		json.Unmarshal([]byte(t.Options), &options)
+	}
+	return options
+}
+
+// StatusFor reports whether t still matches the source question
+func (t *QuestionTranslation) StatusFor(source QuestionResponse) string {
+	if t.SourceHash != TranslationSourceHash(source.Question, source.Options, source.Explanation) {
+		return TranslationStale
+	}
+	return TranslationCurrent
+}
+
+// ToResponse converts a translation of source into the API response format
+func (t *QuestionTranslation) ToResponse(source QuestionResponse) TranslationResponse {
+	return TranslationResponse{
+		Locale:         t.Locale,
+		Question:       t.Question,
+		Options:        t.DecodeOptions(),
+		Explanation:    t.Explanation,
+		Status:         t.StatusFor(source),
+		SourceRevision: t.SourceRevision,
+		Translator:     t.Translator,
+		UpdatedAt:      t.UpdatedAt,
+	}
+}
+
+// Localize serves r in the locale of translation t. An explanation left
+// untranslated falls back to the source one.
+func (r *QuestionResponse) Localize(t *QuestionTranslation) {
+	r.Locale = t.Locale
+	r.Question = t.Question
+	r.Options = t.DecodeOptions()
+	if t.Explanation != "" {
+		r.Explanation = t.Explanation
+	}
+	req := QuestionRequest{Question: r.Question, Options: r.Options}
+	r.Attachments = req.AttachmentRefs()
+}
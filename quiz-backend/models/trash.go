@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Kinds of soft-deleted items kept in the trash
+const (
+	TrashQuestions   = "questions"
+	TrashSubmissions = "submissions"
+)
+
+// TrashKinds lists the kinds of items kept in the trash
+var TrashKinds = []string{TrashQuestions, TrashSubmissions}
+
+// TrashItem is a soft-deleted question or submission. PurgeAfter is when the
+// retention window ends and the item may be permanently deleted.
+type TrashItem struct {
+	Kind       string    `json:"kind"`
+	ID         uint      `json:"id"`
+	Summary    string    `json:"summary"` // question text, or the submitting user and score
+	DeletedAt  time.Time `json:"deletedAt"`
+	PurgeAfter time.Time `json:"purgeAfter"`
+}
+
+// PurgeReport counts the items permanently deleted from the trash
+type PurgeReport struct {
+	Questions   int `json:"questions"`
+	Submissions int `json:"submissions"`
+}
+
+// PurgeQuestions permanently deletes questions together with their
+// translations, editorial history, tags, learner reports, votes and served
+// variants. Revisions are kept, since quiz results are graded against them.
+func PurgeQuestions(db *gorm.DB, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("question_id IN ?", ids).Delete(&QuestionTranslation{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("question_id IN ?", ids).Delete(&QuestionTransition{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("question_id IN ?", ids).Delete(&QuestionReport{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("question_id IN ?", ids).Delete(&ExplanationVote{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM question_tags WHERE question_id IN ?", ids).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&Question{}, ids).Error
+	})
+}
@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// ExplanationVote is a learner's verdict on whether a question's explanation
+// helped. Each voter has one vote per question, which they may change.
+type ExplanationVote struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	QuestionID uint      `json:"questionId" gorm:"not null;uniqueIndex:idx_explanation_votes_voter"`
+	Voter      string    `json:"voter" gorm:"not null;uniqueIndex:idx_explanation_votes_voter"`
+	Helpful    bool      `json:"helpful"`
+	Revision   int       `json:"revision"` // question revision voted on
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// ExplanationVoteRequest represents the API request format for a vote
+type ExplanationVoteRequest struct {
+	Helpful *bool `json:"helpful" binding:"required"`
+}
+
+// VoteSummary counts the votes on a question's explanation. Votes cast on an
+// earlier revision are counted separately, since the explanation may have
+// been rewritten since.
+type VoteSummary struct {
+	QuestionID uint  `json:"questionId"`
+	Revision   int   `json:"revision"`
+	Helpful    int   `json:"helpful"`
+	Unhelpful  int   `json:"unhelpful"`
+	Outdated   int   `json:"outdated"` // votes on earlier revisions
+	Yours      *bool `json:"yours,omitempty"`
+}
+
+// TableName specifies the table name for the ExplanationVote model
+func (ExplanationVote) TableName() string {
+	return "explanation_votes"
+}
+
+// SummarizeVotes counts votes for a question at its current revision; yours
+// is set to voter's vote when they have voted
+func SummarizeVotes(q *Question, votes []ExplanationVote, voter string) VoteSummary {
+	summary := VoteSummary{QuestionID: q.ID, Revision: q.Revision}
+	for _, vote := range votes {
+		if voter != "" && vote.Voter == voter {
+			helpful := vote.Helpful
+			summary.Yours = &helpful
+		}
+		switch {
+		case vote.Revision != q.Revision:
+			summary.Outdated++
+		case vote.Helpful:
+			summary.Helpful++
+		default:
+			summary.Unhelpful++
+		}
+	}
+	return summary
+}
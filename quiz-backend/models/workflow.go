@@ -0,0 +1,148 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Editorial states of a question. Only published questions are served to
+// learners.
+const (
+	StatusDraft     = "draft"
+	StatusInReview  = "in_review"
+	StatusPublished = "published"
+	StatusRetired   = "retired"
+)
+
+// Editorial actions that move a question between states
+const (
+	ActionSubmit  = "submit"  // draft -> in_review, optionally assigning a reviewer
+	ActionAssign  = "assign"  // assigns a reviewer without changing the state
+	ActionApprove = "approve" // in_review -> published
+	ActionReject  = "reject"  // in_review -> draft, with a comment
+	ActionRetire  = "retire"  // published -> retired
+	ActionReopen  = "reopen"  // retired -> draft
+	ActionEdit    = "edit"    // published -> in_review; recorded when published content is edited
+)
+
+// AllowedStatuses lists the editorial states
+var AllowedStatuses = []string{StatusDraft, StatusInReview, StatusPublished, StatusRetired}
+
+// transitions maps each action to the states it applies to and the state it
+// leads to; an empty target leaves the state unchanged
+var transitions = map[string]struct {
+	from []string
+	to   string
+}{
+	ActionSubmit:  {from: []string{StatusDraft}, to: StatusInReview},
+	ActionAssign:  {from: []string{StatusDraft, StatusInReview}},
+	ActionApprove: {from: []string{StatusInReview}, to: StatusPublished},
+	ActionReject:  {from: []string{StatusInReview}, to: StatusDraft},
+	ActionRetire:  {from: []string{StatusPublished}, to: StatusRetired},
+	ActionReopen:  {from: []string{StatusRetired}, to: StatusDraft},
+}
+
+// QuestionTransition is one entry of a question's editorial history
+type QuestionTransition struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	QuestionID uint      `json:"questionId" gorm:"not null;index"`
+	Action     string    `json:"action" gorm:"not null"`
+	From       string    `json:"from" gorm:"not null"`
+	To         string    `json:"to" gorm:"not null"`
+	Actor      string    `json:"actor" gorm:"not null"`
+	Reviewer   string    `json:"reviewer,omitempty"`
+	Comment    string    `json:"comment,omitempty" gorm:"type:text"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// TransitionRequest represents the API request format for editorial actions
+type TransitionRequest struct {
+	Action   string `json:"action" binding:"required"`
+	Reviewer string `json:"reviewer"`
+	Comment  string `json:"comment"`
+}
+
+// TableName specifies the table name for the QuestionTransition model
+func (QuestionTransition) TableName() string {
+	return "question_transitions"
+}
+
+// IsPublished reports whether the question is served to learners
+func (q *Question) IsPublished() bool {
+	return q.Status == StatusPublished
+}
+
+// Edited moves a published question whose content actor edited back into
+// review, so the change is not served before it is approved, and returns the
+// history entry to record. Questions in other states are left as they are.
+func (q *Question) Edited(actor string) (QuestionTransition, bool) {
+	if !q.IsPublished() {
+		return QuestionTransition{}, false
+	}
+	q.Status = StatusInReview
+	return QuestionTransition{
+		QuestionID: q.ID,
+		Action:     ActionEdit,
+		From:       StatusPublished,
+		To:         StatusInReview,
+		Actor:      actor,
+		Reviewer:   q.Reviewer,
+		Comment:    "Edited while published",
+	}, true
+}
+
+// Transition applies an editorial action by actor to the question and
+// returns the history entry to record. Reviewers approve or reject only the
+// questions assigned to them, and nobody approves their own question.
+func (q *Question) Transition(actor string, req TransitionRequest) (QuestionTransition, error) {
+	action := strings.ToLower(strings.TrimSpace(req.Action))
+	reviewer := strings.TrimSpace(req.Reviewer)
+	comment := strings.TrimSpace(req.Comment)
+
+	rule, ok := transitions[action]
+	if !ok {
+		return QuestionTransition{}, fmt.Errorf("action must be one of: %s, %s, %s, %s, %s, %s",
+			ActionSubmit, ActionAssign, ActionApprove, ActionReject, ActionRetire, ActionReopen)
+	}
+	if !contains(rule.from, q.Status) {
+		return QuestionTransition{}, fmt.Errorf("cannot %s a question that is %s", action, q.Status)
+	}
+
+	switch action {
+	case ActionAssign:
+		if reviewer == "" {
+			return QuestionTransition{}, fmt.Errorf("reviewer is required")
+		}
+	case ActionApprove, ActionReject:
+		if q.Reviewer != "" && q.Reviewer != actor {
+			return QuestionTransition{}, fmt.Errorf("question is assigned to reviewer %s", q.Reviewer)
+		}
+		if action == ActionApprove && q.Author == actor {
+			return QuestionTransition{}, fmt.Errorf("authors cannot approve their own questions")
+		}
+		if action == ActionReject && comment == "" {
+			return QuestionTransition{}, fmt.Errorf("a comment is required when rejecting a question")
+		}
+	}
+	if reviewer != "" && reviewer == q.Author {
+		return QuestionTransition{}, fmt.Errorf("authors cannot review their own questions")
+	}
+
+	entry := QuestionTransition{
+		QuestionID: q.ID,
+		Action:     action,
+		From:       q.Status,
+		To:         q.Status,
+		Actor:      actor,
+		Reviewer:   reviewer,
+		Comment:    comment,
+	}
+	if rule.to != "" {
+		q.Status, entry.To = rule.to, rule.to
+	}
+	if reviewer != "" {
+		q.Reviewer = reviewer
+	}
+	return entry, nil
+}
@@ -0,0 +1,342 @@
+// Package search provides full-text search over the question bank. It uses
+// an SQLite FTS5 index when the driver was built with FTS5 support (the
+// sqlite_fts5 build tag) and falls back to substring matching otherwise.
+package search
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"strings"
+	"unicode"
+
+	"aws-rds-quiz-backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Engines report how a search was answered
+const (
+	EngineFTS5 = "fts5"
+	EngineLike = "like"
+)
+
+// Snippet markers: control characters that cannot occur in question text, so
+// the snippet can be HTML-escaped before they become <mark> tags
+const (
+	markStart = "\x02"
+	markEnd   = "\x03"
+)
+
+// snippetRunes is roughly how much text a fallback snippet shows
+const snippetRunes = 120
+
+// ErrNoTerms is returned for search text without any searchable word
+var ErrNoTerms = errors.New("search text must contain at least one word")
+
+// ftsEnabled records whether Setup created the FTS5 index
+var ftsEnabled bool
+
+// schema creates the index as an external content table over questions and
+// keeps it in sync with triggers, so every write path is covered. Options
+// are indexed as their JSON text.
+var schema = []string{
+	`CREATE VIRTUAL TABLE IF NOT EXISTS questions_fts USING fts5(
+		question, options, explanation,
+		content='questions', content_rowid='id', tokenize='porter unicode61'
+	)`,
+	`CREATE TRIGGER IF NOT EXISTS questions_fts_insert AFTER INSERT ON questions BEGIN
+		INSERT INTO questions_fts(rowid, question, options, explanation)
+		VALUES (new.id, new.question, new.options, new.explanation);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS questions_fts_delete AFTER DELETE ON questions BEGIN
+		INSERT INTO questions_fts(questions_fts, rowid, question, options, explanation)
+		VALUES ('delete', old.id, old.question, old.options, old.explanation);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS questions_fts_update AFTER UPDATE ON questions BEGIN
+		INSERT INTO questions_fts(questions_fts, rowid, question, options, explanation)
+		VALUES ('delete', old.id, old.question, old.options, old.explanation);
+		INSERT INTO questions_fts(rowid, question, options, explanation)
+		VALUES (new.id, new.question, new.options, new.explanation);
+	END`,
+}
+
+// Hit is one search result
+type Hit struct {
+	Question   models.QuestionResponse `json:"question"`
+	Score      float64                 `json:"score"` // higher is more relevant; always 0 without FTS5
+	Highlights Highlights              `json:"highlights"`
+}
+
+// Highlights are HTML-escaped excerpts of the matched fields with the
+// matching terms wrapped in <mark> tags. A field without a match is empty.
+type Highlights struct {
+	Question    string `json:"question,omitempty"`
+	Explanation string `json:"explanation,omitempty"`
+}
+
+// Result is the outcome of a search
+type Result struct {
+	Engine string `json:"engine"`
+	Hits   []Hit  `json:"hits"`
+}
+
+// term is one search word; prefix terms match any word starting with it
+type term struct {
+	text   string
+	prefix bool
+}
+
+// Setup creates the FTS5 index if the SQLite driver supports it, building it
+// from the existing questions whenever its triggers were missing. Without
+// FTS5 support searches use substring matching, and triggers left by a build
+// with FTS5 are dropped so writes keep working; the index is rebuilt when
+// FTS5 is available again.
+func Setup(db *gorm.DB) error {
+	probe := db.Session(&gorm.Session{Logger: db.Logger.LogMode(logger.Silent)})
+	if err := probe.Exec("CREATE VIRTUAL TABLE temp.fts5_probe USING fts5(x)").Error; err != nil {
+		log.Printf("WARNING: SQLite FTS5 is not available (%v). Question search falls back to unranked substring matching; build with -tags sqlite_fts5 to enable it", err)
+		for _, trigger := range []string{"questions_fts_insert", "questions_fts_delete", "questions_fts_update"} {
+			if err := db.Exec("DROP TRIGGER IF EXISTS " + trigger).Error; err != nil {
+				return fmt.Errorf("failed to drop search trigger: %v", err)
+			}
+		}
+		return nil
+	}
+	probe.Exec("DROP TABLE temp.fts5_probe")
+
+	var existing int64
+	if err := db.Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name LIKE 'questions_fts_%'").Scan(&existing).Error; err != nil {
+		return err
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range schema {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		if existing < 3 {
+			return tx.Exec("INSERT INTO questions_fts(questions_fts) VALUES ('rebuild')").Error
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create search index: %v", err)
+	}
+
+	ftsEnabled = true
+	return nil
+}
+
+// Questions searches the question text, options and explanation of the
+// questions selected by query for text, best matches first. Words must all
+// match; a word ending in "*" matches as a prefix.
+func Questions(query *gorm.DB, text string, limit int) (*Result, error) {
+	terms := parseTerms(text)
+	if len(terms) == 0 {
+		return nil, ErrNoTerms
+	}
+	if !ftsEnabled {
+		return likeSearch(query, terms, limit)
+	}
+	return ftsSearch(query, terms, limit)
+}
+
+// ftsSearch ranks matches with bm25, weighting the question text above the
+// options and the options above the explanation
+func ftsSearch(query *gorm.DB, terms []term, limit int) (*Result, error) {
+	var rows []struct {
+		ID          uint
+		Score       float64
+		Question    string
+		Explanation string
+	}
+	err := query.Model(&models.Question{}).
+		Select("questions.id AS id, -bm25(questions_fts, 10.0, 4.0, 2.0) AS score, "+
+			"snippet(questions_fts, 0, ?, ?, '…', 24) AS question, "+
+			"snippet(questions_fts, 2, ?, ?, '…', 24) AS explanation",
+			markStart, markEnd, markStart, markEnd).
+		Joins("JOIN questions_fts ON questions_fts.rowid = questions.id").
+		Where("questions_fts MATCH ?", matchExpression(terms)).
+		Order("score DESC").
+		Limit(limit).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("search failed: %v", err)
+	}
+
+	ids := make([]uint, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ID
+	}
+	questions, err := load(query, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Engine: EngineFTS5, Hits: []Hit{}}
+	for _, row := range rows {
+		q, ok := questions[row.ID]
+		if !ok {
+			continue
+		}
+		hit, err := newHit(q, row.Score, markedSnippet(row.Question), markedSnippet(row.Explanation))
+		if err != nil {
+			return nil, err
+		}
+		result.Hits = append(result.Hits, hit)
+	}
+	return result, nil
+}
+
+// likeSearch matches each term as a substring of any indexed field and
+// returns matches in ID order
+func likeSearch(query *gorm.DB, terms []term, limit int) (*Result, error) {
+	query = query.Model(&models.Question{})
+	for _, t := range terms {
+		pattern := "%" + escapeLike(t.text) + "%"
+		query = query.Where("(questions.question LIKE ? ESCAPE '\\' OR questions.options LIKE ? ESCAPE '\\' OR questions.explanation LIKE ? ESCAPE '\\')", pattern, pattern, pattern)
+	}
+
+	var questions []models.Question
+	if err := query.Scopes(models.WithTaxonomy).Order("questions.id").Limit(limit).Find(&questions).Error; err != nil {
+		return nil, fmt.Errorf("search failed: %v", err)
+	}
+
+	result := &Result{Engine: EngineLike, Hits: []Hit{}}
+	for i := range questions {
+		q := &questions[i]
+		hit, err := newHit(q, 0, highlight(q.Question, terms), highlight(q.Explanation, terms))
+		if err != nil {
+			return nil, err
+		}
+		result.Hits = append(result.Hits, hit)
+	}
+	return result, nil
+}
+
+// load fetches the matched questions with their tags and topic
+func load(query *gorm.DB, ids []uint) (map[uint]*models.Question, error) {
+	var questions []models.Question
+	if len(ids) > 0 {
+		db := query.Session(&gorm.Session{NewDB: true})
+		if err := db.Scopes(models.WithTaxonomy).Find(&questions, ids).Error; err != nil {
+			return nil, fmt.Errorf("failed to load search results: %v", err)
+		}
+	}
+	byID := make(map[uint]*models.Question, len(questions))
+	for i := range questions {
+		byID[questions[i].ID] = &questions[i]
+	}
+	return byID, nil
+}
+
+func newHit(q *models.Question, score float64, question, explanation string) (Hit, error) {
+	response, err := q.ToResponse()
+	if err != nil {
+		return Hit{}, fmt.Errorf("failed to parse question %d: %v", q.ID, err)
+	}
+	return Hit{
+		Question:   response,
+		Score:      score,
+		Highlights: Highlights{Question: question, Explanation: explanation},
+	}, nil
+}
+
+// parseTerms splits search text into words. Punctuation separates words,
+// except for a trailing "*" marking a prefix.
+func parseTerms(text string) []term {
+	var terms []term
+	for _, field := range strings.Fields(text) {
+		prefix := strings.HasSuffix(field, "*")
+		words := strings.FieldsFunc(field, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		for i, word := range words {
+			terms = append(terms, term{text: word, prefix: prefix && i == len(words)-1})
+		}
+	}
+	return terms
+}
+
+// matchExpression builds an FTS5 query requiring every term. Terms are
+// quoted so no user input is read as FTS5 syntax.
+func matchExpression(terms []term) string {
+	parts := make([]string, len(terms))
+	for i, t := range terms {
+		parts[i] = `"` + strings.ReplaceAll(t.text, `"`, `""`) + `"`
+		if t.prefix {
+			parts[i] += "*"
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// markedSnippet escapes an FTS5 snippet and turns its markers into <mark>
+// tags. Snippets without a match are dropped.
+func markedSnippet(snippet string) string {
+	if !strings.Contains(snippet, markStart) {
+		return ""
+	}
+	escaped := html.EscapeString(snippet)
+	return strings.NewReplacer(markStart, "<mark>", markEnd, "</mark>").Replace(escaped)
+}
+
+// highlight builds a snippet around the first term found in text, marking
+// every occurrence of every term, for searches without FTS5
+func highlight(text string, terms []term) string {
+	lower := []rune(strings.ToLower(text))
+	runes := []rune(text)
+	marked := make([]bool, len(runes))
+	first := -1
+	for _, t := range terms {
+		needle := []rune(strings.ToLower(t.text))
+		for i := 0; i+len(needle) <= len(lower); i++ {
+			if string(lower[i:i+len(needle)]) != string(needle) {
+				continue
+			}
+			for j := i; j < i+len(needle); j++ {
+				marked[j] = true
+			}
+			if first < 0 || i < first {
+				first = i
+			}
+		}
+	}
+	if first < 0 || len(lower) != len(runes) {
+		return ""
+	}
+
+	start := first - snippetRunes/4
+	if start < 0 {
+		start = 0
+	}
+	end := start + snippetRunes
+	if end > len(runes) {
+		end = len(runes)
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	for i := start; i < end; i++ {
+		if marked[i] && (i == start || !marked[i-1]) {
+			b.WriteString("<mark>")
+		}
+		b.WriteString(html.EscapeString(string(runes[i])))
+		if marked[i] && (i == end-1 || !marked[i+1]) {
+			b.WriteString("</mark>")
+		}
+	}
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
@@ -0,0 +1,310 @@
+// Package similarity finds near-duplicate questions by comparing TF-IDF
+// weighted terms of their text and options with cosine similarity.
+package similarity
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"aws-rds-quiz-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// DefaultThreshold is the similarity at which two questions are reported as
+// likely duplicates
+const DefaultThreshold = 0.45
+
+// Term weights: a question's wording and its correct answer say the most
+// about what it asks, the distractors less
+const (
+	weightQuestion = 1.0
+	weightAnswer   = 1.0
+	weightOption   = 0.4
+)
+
+// stopwords are dropped before comparing; they include the question words
+// that rephrasings swap freely ("which", "how", "what")
+var stopwords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "can": true, "do": true, "does": true, "for": true,
+	"from": true, "how": true, "i": true, "in": true, "is": true, "it": true,
+	"its": true, "of": true, "on": true, "or": true, "should": true, "that": true,
+	"the": true, "this": true, "to": true, "use": true, "what": true, "when": true,
+	"which": true, "who": true, "why": true, "will": true, "with": true, "you": true,
+	"your": true, "following": true, "best": true, "true": true, "false": true,
+}
+
+// Document is a question reduced to what similarity compares. ID names a
+// stored question; Line names a row of an import file that is not stored yet.
+type Document struct {
+	ID       uint
+	Line     int
+	Question string
+	Answers  []string // correct options or accepted answers
+	Options  []string // the remaining options
+}
+
+// Match is a document found similar to another one
+type Match struct {
+	ID         uint    `json:"id,omitempty"`
+	Line       int     `json:"line,omitempty"`
+	Question   string  `json:"question"`
+	Similarity float64 `json:"similarity"`
+}
+
+// Cluster is a group of questions that are all linked by similar pairs
+type Cluster struct {
+	Questions []Match `json:"questions"` // Similarity is each question's best match within the cluster
+	Max       float64 `json:"maxSimilarity"`
+}
+
+// Index holds documents and the document frequency of their terms
+type Index struct {
+	docs  []Document
+	terms []map[string]float64
+	df    map[string]int
+}
+
+// NewIndex indexes docs
+func NewIndex(docs []Document) *Index {
+	index := &Index{df: make(map[string]int)}
+	for _, doc := range docs {
+		index.Add(doc)
+	}
+	return index
+}
+
+// Add indexes another document
+func (ix *Index) Add(doc Document) {
+	terms := termWeights(doc)
+	ix.docs = append(ix.docs, doc)
+	ix.terms = append(ix.terms, terms)
+	for term := range terms {
+		ix.df[term]++
+	}
+}
+
+// Len returns the number of indexed documents
+func (ix *Index) Len() int {
+	return len(ix.docs)
+}
+
+// Similar returns the indexed documents at least threshold similar to doc,
+// most similar first. A document with the same non-zero ID as doc is
+// skipped, so a stored question is not reported as its own duplicate.
+func (ix *Index) Similar(doc Document, threshold float64) []Match {
+	query := ix.vector(termWeights(doc))
+	var matches []Match
+	for i, other := range ix.docs {
+		if doc.ID != 0 && other.ID == doc.ID {
+			continue
+		}
+		if score := cosine(query, ix.vector(ix.terms[i])); score >= threshold {
+			matches = append(matches, match(other, score))
+		}
+	}
+	sortMatches(matches)
+	return matches
+}
+
+// Clusters groups the indexed documents into connected components of pairs
+// at least threshold similar, largest similarity first. Documents without a
+// similar partner are left out.
+func (ix *Index) Clusters(threshold float64) []Cluster {
+	vectors := make([]map[string]float64, len(ix.docs))
+	for i := range ix.docs {
+		vectors[i] = ix.vector(ix.terms[i])
+	}
+
+	parent := make([]int, len(ix.docs))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	best := make([]float64, len(ix.docs))
+	for i := range vectors {
+		for j := i + 1; j < len(vectors); j++ {
+			score := cosine(vectors[i], vectors[j])
+			if score < threshold {
+				continue
+			}
+			parent[find(i)] = find(j)
+			best[i] = math.Max(best[i], score)
+			best[j] = math.Max(best[j], score)
+		}
+	}
+
+	groups := make(map[int]*Cluster)
+	var roots []int
+	for i, doc := range ix.docs {
+		if best[i] == 0 {
+			continue
+		}
+		root := find(i)
+		cluster, ok := groups[root]
+		if !ok {
+			cluster = &Cluster{}
+			groups[root] = cluster
+			roots = append(roots, root)
+		}
+		m := match(doc, best[i])
+		cluster.Questions = append(cluster.Questions, m)
+		cluster.Max = math.Max(cluster.Max, m.Similarity)
+	}
+
+	clusters := make([]Cluster, 0, len(roots))
+	for _, root := range roots {
+		sortMatches(groups[root].Questions)
+		clusters = append(clusters, *groups[root])
+	}
+	sort.SliceStable(clusters, func(i, j int) bool {
+		return clusters[i].Max > clusters[j].Max
+	})
+	return clusters
+}
+
+// vector turns term weights into TF-IDF weights. Terms the index has not seen
+// get the highest IDF.
+func (ix *Index) vector(terms map[string]float64) map[string]float64 {
+	n := float64(len(ix.docs) + 1)
+	vector := make(map[string]float64, len(terms))
+	for term, weight := range terms {
+		vector[term] = weight * math.Log(n/float64(ix.df[term]+1)+1)
+	}
+	return vector
+}
+
+// termWeights extracts weighted terms from a document. Question and answer
+// terms share a namespace, so a question that names the answer matches one
+// that asks for it; distractor terms are kept apart.
+func termWeights(doc Document) map[string]float64 {
+	terms := make(map[string]float64)
+	add := func(text, prefix string, weight float64) {
+		for _, word := range tokenize(text) {
+			terms[prefix+word] += weight
+		}
+	}
+	add(doc.Question, "", weightQuestion)
+	for _, answer := range doc.Answers {
+		add(answer, "", weightAnswer)
+	}
+	for _, option := range doc.Options {
+		add(option, "option:", weightOption)
+	}
+	return terms
+}
+
+// tokenize lower-cases text, splits it into words, drops stopwords and
+// strips common suffixes so that "replicas" and "replica" compare equal
+func tokenize(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	tokens := words[:0]
+	for _, word := range words {
+		if stopwords[word] {
+			continue
+		}
+		tokens = append(tokens, stem(word))
+	}
+	return tokens
+}
+
+// stem strips a few English inflections; it only needs to be consistent
+func stem(word string) string {
+	for _, suffix := range []string{"ing", "ed", "s"} {
+		if len(word) > len(suffix)+3 && strings.HasSuffix(word, suffix) && !strings.HasSuffix(word, "ss") {
+			return strings.TrimSuffix(word, suffix)
+		}
+	}
+	return word
+}
+
+func cosine(a, b map[string]float64) float64 {
+	if len(b) < len(a) {
+		a, b = b, a
+	}
+	var dot, normA, normB float64
+	for term, weight := range a {
+		dot += weight * b[term]
+		normA += weight * weight
+	}
+	for _, weight := range b {
+		normB += weight * weight
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(normA*normB)
+}
+
+func match(doc Document, score float64) Match {
+	return Match{ID: doc.ID, Line: doc.Line, Question: doc.Question, Similarity: math.Round(score*1000) / 1000}
+}
+
+func sortMatches(matches []Match) {
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Similarity > matches[j].Similarity
+	})
+}
+
+// FromRequest builds a document from a question in its authoring form
+func FromRequest(id uint, line int, req *models.QuestionRequest) Document {
+	doc := Document{ID: id, Line: line, Question: req.Question}
+
+	correct := map[int]bool{}
+	switch req.Type {
+	case "", models.QuestionTypeSingle:
+		correct[req.CorrectAnswer] = true
+	case models.QuestionTypeMultiple:
+		for _, index := range req.CorrectAnswers {
+			correct[index] = true
+		}
+	case models.QuestionTypeFillBlank:
+		var key models.FillBlankKey
+		if (&models.Question{AnswerKey: string(req.AnswerKey)}).DecodeAnswerKey(&key) == nil {
+			doc.Answers = append(doc.Answers, key.Accepted...)
+		}
+	case models.QuestionTypeOrdering, models.QuestionTypeMatching:
+		doc.Answers = append(doc.Answers, req.Options...)
+		return doc
+	}
+
+	for i, option := range req.Options {
+		if correct[i] {
+			doc.Answers = append(doc.Answers, option)
+		} else {
+			doc.Options = append(doc.Options, option)
+		}
+	}
+	return doc
+}
+
+// Bank indexes the questions selected by query
+func Bank(query *gorm.DB) (*Index, error) {
+	var questions []models.Question
+	if err := query.Model(&models.Question{}).Order("questions.id").Find(&questions).Error; err != nil {
+		return nil, fmt.Errorf("failed to load questions: %v", err)
+	}
+
+	index := NewIndex(nil)
+	for i := range questions {
+		req, err := questions[i].ToRequest()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse question %d: %v", questions[i].ID, err)
+		}
+		index.Add(FromRequest(questions[i].ID, 0, &req))
+	}
+	return index, nil
+}
@@ -0,0 +1,62 @@
+package sqlsandbox
+
+import "strings"
+
+// Diff describes how a learner's result set differs from the reference
+type Diff struct {
+	Error           string     `json:"error,omitempty"` // the learner's query failed
+	ExpectedColumns []string   `json:"expectedColumns,omitempty"`
+	ActualColumns   []string   `json:"actualColumns,omitempty"`
+	Missing         [][]string `json:"missing,omitempty"`         // expected rows that were not returned
+	Unexpected      [][]string `json:"unexpected,omitempty"`      // returned rows that were not expected
+	OrderMismatchAt *int       `json:"orderMismatchAt,omitempty"` // first row out of order, when the rows match as a set
+}
+
+// Compare returns nil when actual matches expected, and otherwise a diff.
+// Columns are compared by position, so aliases do not matter. With
+// ignoreOrder the rows are compared as a multiset.
+func Compare(expected, actual *Result, ignoreOrder bool) *Diff {
+	if len(expected.Columns) != len(actual.Columns) {
+		return &Diff{ExpectedColumns: expected.Columns, ActualColumns: actual.Columns}
+	}
+
+	diff := &Diff{}
+	remaining := make(map[string]int, len(expected.Rows))
+	for _, row := range expected.Rows {
+		remaining[rowKey(row)]++
+	}
+	for _, row := range actual.Rows {
+		key := rowKey(row)
+		if remaining[key] > 0 {
+			remaining[key]--
+		} else {
+			diff.Unexpected = append(diff.Unexpected, row)
+		}
+	}
+	for _, row := range expected.Rows {
+		key := rowKey(row)
+		if remaining[key] > 0 {
+			remaining[key]--
+			diff.Missing = append(diff.Missing, row)
+		}
+	}
+
+	if len(diff.Missing) == 0 && len(diff.Unexpected) == 0 {
+		if ignoreOrder {
+			return nil
+		}
+		for i := range expected.Rows {
+			if rowKey(expected.Rows[i]) != rowKey(actual.Rows[i]) {
+				diff.OrderMismatchAt = &i
+				return diff
+			}
+		}
+		return nil
+	}
+	return diff
+}
+
+// rowKey joins a row with a separator that does not occur in practice
+func rowKey(row []string) string {
+	return strings.Join(row, "\x00")
+}
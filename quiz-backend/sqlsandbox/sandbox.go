@@ -0,0 +1,283 @@
+// Package sqlsandbox runs learner SQL against a throwaway in-memory SQLite
+// database built from a question's fixture.
+package sqlsandbox
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Limits bounds the work a single run may do
+type Limits struct {
+	Timeout     time.Duration // covers loading the fixture and running the query
+	MaxRows     int           // larger result sets are an error
+	MaxFixture  int           // bytes of fixture SQL
+	MaxQuery    int           // bytes of query SQL
+	MaxValue    int           // bytes of any string or blob, such as zeroblob(n)
+	MaxDatabase int           // bytes the fixture's tables may take up
+	MaxOps      int           // virtual machine instructions per statement, where SQLite supports it
+}
+
+// DefaultLimits are the limits used for grading and validation
+var DefaultLimits = Limits{
+	Timeout:     2 * time.Second,
+	MaxRows:     1000,
+	MaxFixture:  256 * 1024,
+	MaxQuery:    16 * 1024,
+	MaxValue:    1 << 20,
+	MaxDatabase: 16 << 20,
+	MaxOps:      25000,
+}
+
+// pageSize is the page size of the sandbox database, which MaxDatabase is
+// counted in
+const pageSize = 4096
+
+// sqliteRecursive is SQLITE_RECURSIVE, which go-sqlite3 does not export
+const sqliteRecursive = 33
+
+// Result is a query's result set with every value rendered as text
+type Result struct {
+	Columns []string   `json:"columns"`
+	Rows    [][]string `json:"rows"`
+}
+
+// Run loads fixture into a fresh in-memory database and runs query against
+// it. The fixture may create and fill tables; the query may only read. Both
+// are denied access to anything outside the database, such as ATTACH,
+// PRAGMA and extension loading.
+func Run(fixture, query string, limits Limits) (*Result, error) {
+	if len(fixture) > limits.MaxFixture {
+		return nil, fmt.Errorf("fixture is larger than %d bytes", limits.MaxFixture)
+	}
+	if len(query) > limits.MaxQuery {
+		return nil, fmt.Errorf("query is larger than %d bytes", limits.MaxQuery)
+	}
+	statements := splitStatements(query)
+	if len(statements) != 1 {
+		return nil, fmt.Errorf("expected exactly one SQL statement, found %d", len(statements))
+	}
+	// The driver runs whatever follows the statement too, so a trailing
+	// comment must not reach it
+	query = statements[0]
+
+	ctx, cancel := context.WithTimeout(context.Background(), limits.Timeout)
+	defer cancel()
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := limit(ctx, conn, limits); err != nil {
+		return nil, err
+	}
+	if err := authorize(conn, fixturePolicy); err != nil {
+		return nil, err
+	}
+	if _, err := conn.ExecContext(ctx, fixture); err != nil {
+		return nil, fmt.Errorf("fixture failed: %v", describe(ctx, err, limits))
+	}
+	if err := authorize(conn, queryPolicy); err != nil {
+		return nil, err
+	}
+
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, describe(ctx, err, limits)
+	}
+	defer rows.Close()
+
+	result := &Result{Rows: [][]string{}}
+	if result.Columns, err = rows.Columns(); err != nil {
+		return nil, err
+	}
+	values := make([]interface{}, len(result.Columns))
+	pointers := make([]interface{}, len(values))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	for rows.Next() {
+		if len(result.Rows) == limits.MaxRows {
+			return nil, fmt.Errorf("query returned more than %d rows", limits.MaxRows)
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+		row := make([]string, len(values))
+		for i, value := range values {
+			row[i] = formatValue(value)
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, describe(ctx, err, limits)
+	}
+	return result, nil
+}
+
+// limit caps the memory a run may take: the size of values, statements and
+// the database, and the instructions a statement may run. Attaching databases
+// is forbidden as a second line of defence behind the authorizer.
+func limit(ctx context.Context, conn *sql.Conn, limits Limits) error {
+	pages := limits.MaxDatabase / pageSize
+	if _, err := conn.ExecContext(ctx, fmt.Sprintf("PRAGMA page_size = %d; PRAGMA max_page_count = %d", pageSize, pages)); err != nil {
+		return err
+	}
+	statement := limits.MaxFixture
+	if limits.MaxQuery > statement {
+		statement = limits.MaxQuery
+	}
+	return conn.Raw(func(driverConn interface{}) error {
+		c, ok := driverConn.(*sqlite3.SQLiteConn)
+		if !ok {
+			return fmt.Errorf("unexpected driver connection %T", driverConn)
+		}
+		c.SetLimit(sqlite3.SQLITE_LIMIT_ATTACHED, 0)
+		c.SetLimit(sqlite3.SQLITE_LIMIT_LENGTH, limits.MaxValue)
+		c.SetLimit(sqlite3.SQLITE_LIMIT_SQL_LENGTH, statement)
+		c.SetLimit(sqlite3.SQLITE_LIMIT_VDBE_OP, limits.MaxOps)
+		return nil
+	})
+}
+
+// authorize installs an authorizer policy on the connection
+func authorize(conn *sql.Conn, policy func(action int, arg1, arg2 string) bool) error {
+	return conn.Raw(func(driverConn interface{}) error {
+		c, ok := driverConn.(*sqlite3.SQLiteConn)
+		if !ok {
+			return fmt.Errorf("unexpected driver connection %T", driverConn)
+		}
+		c.RegisterAuthorizer(func(action int, arg1, arg2, arg3 string) int {
+			if policy(action, arg1, arg2) {
+				return sqlite3.SQLITE_OK
+			}
+			return sqlite3.SQLITE_DENY
+		})
+		return nil
+	})
+}
+
+// fixturePolicy allows building and filling tables, indexes, views and
+// triggers in the main database
+func fixturePolicy(action int, arg1, arg2 string) bool {
+	switch action {
+	case sqlite3.SQLITE_CREATE_TABLE, sqlite3.SQLITE_CREATE_INDEX, sqlite3.SQLITE_CREATE_VIEW, sqlite3.SQLITE_CREATE_TRIGGER,
+		sqlite3.SQLITE_DROP_TABLE, sqlite3.SQLITE_DROP_INDEX, sqlite3.SQLITE_DROP_VIEW, sqlite3.SQLITE_DROP_TRIGGER,
+		sqlite3.SQLITE_ALTER_TABLE, sqlite3.SQLITE_INSERT, sqlite3.SQLITE_UPDATE, sqlite3.SQLITE_DELETE,
+		sqlite3.SQLITE_TRANSACTION, sqlite3.SQLITE_SAVEPOINT:
+		return true
+	}
+	return queryPolicy(action, arg1, arg2)
+}
+
+// queryPolicy allows reading only
+func queryPolicy(action int, arg1, arg2 string) bool {
+	switch action {
+	case sqlite3.SQLITE_SELECT, sqlite3.SQLITE_READ, sqliteRecursive:
+		return true
+	case sqlite3.SQLITE_FUNCTION:
+		return !strings.EqualFold(arg2, "load_extension")
+	}
+	return false
+}
+
+// describe replaces the driver's interrupt and limit errors with messages
+// naming the limit
+func describe(ctx context.Context, err error, limits Limits) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("timed out after %s", limits.Timeout)
+	}
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.Code {
+		case sqlite3.ErrAuth:
+			return fmt.Errorf("statement not allowed: %v", err)
+		case sqlite3.ErrFull:
+			return fmt.Errorf("database is larger than %d bytes", limits.MaxDatabase)
+		case sqlite3.ErrTooBig:
+			return fmt.Errorf("value is larger than %d bytes", limits.MaxValue)
+		}
+	}
+	return err
+}
+
+// formatValue renders a column value so equal values compare equal whatever
+// their storage class: 2.0 and 2 both become "2"
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		return string(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// splitStatements returns the non-empty statements in sql without their
+// terminating semicolons, skipping semicolons inside string literals, quoted
+// identifiers and comments
+func splitStatements(sql string) []string {
+	var statements []string
+	start, pending := 0, false
+	for i := 0; i < len(sql); i++ {
+		switch c := sql[i]; {
+		case c == '\'' || c == '"' || c == '`' || c == '[':
+			closing := c
+			if c == '[' {
+				closing = ']'
+			}
+			end := strings.IndexByte(sql[i+1:], closing)
+			if end < 0 {
+				return append(statements, sql[start:])
+			}
+			i += end + 1
+			pending = true
+		case c == '-' && strings.HasPrefix(sql[i:], "--"):
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				i = len(sql)
+			} else {
+				i += end
+			}
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				i = len(sql)
+			} else {
+				i += end + 3
+			}
+		case c == ';':
+			if pending {
+				statements = append(statements, sql[start:i])
+			}
+			start, pending = i+1, false
+		case c != ' ' && c != '\t' && c != '\n' && c != '\r':
+			pending = true
+		}
+	}
+	if pending {
+		statements = append(statements, sql[start:])
+	}
+	return statements
+}
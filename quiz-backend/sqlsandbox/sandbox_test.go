@@ -0,0 +1,143 @@
+package sqlsandbox
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const fixture = "CREATE TABLE db (name TEXT, engine TEXT, storage REAL); " +
+	"INSERT INTO db VALUES ('a', 'mysql', 20), ('b', 'postgres', 100.0), ('c', 'mysql', NULL);"
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    [][]string
+		wantErr string
+	}{
+		{"select", "SELECT name FROM db WHERE engine = 'mysql' ORDER BY name", [][]string{{"a"}, {"c"}}, ""},
+		{"values as text", "SELECT storage FROM db ORDER BY name", [][]string{{"20"}, {"100"}, {"NULL"}}, ""},
+		{"trailing semicolon and comment", "SELECT count(*) FROM db; -- all of them", [][]string{{"3"}}, ""},
+		{"trailing block comment", "SELECT count(*) FROM db; /* all of them */", [][]string{{"3"}}, ""},
+		{"recursive cte", "WITH RECURSIVE n(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM n WHERE x < 3) SELECT x FROM n", [][]string{{"1"}, {"2"}, {"3"}}, ""},
+		{"two statements", "SELECT 1; SELECT 2", nil, "exactly one SQL statement, found 2"},
+		{"semicolon in string", "SELECT ';' AS s", [][]string{{";"}}, ""},
+		{"write", "DELETE FROM db", nil, "statement not allowed"},
+		{"pragma", "PRAGMA table_info(db)", nil, "statement not allowed"},
+		{"attach", "ATTACH DATABASE '/tmp/x.db' AS x", nil, "statement not allowed"},
+		{"load extension", "SELECT load_extension('x')", nil, "not authorized"},
+		{"unknown column", "SELECT nope FROM db", nil, "no such column"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Run(fixture, tt.query, DefaultLimits)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Run() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if !reflect.DeepEqual(result.Rows, tt.want) {
+				t.Errorf("Run() rows = %v, want %v", result.Rows, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunLimits(t *testing.T) {
+	limits := DefaultLimits
+	limits.Timeout = 500 * time.Millisecond
+	limits.MaxRows = 10
+
+	tests := []struct {
+		name    string
+		fixture string
+		query   string
+		wantErr string
+	}{
+		{"fixture size", strings.Repeat(" ", limits.MaxFixture+1), "SELECT 1", "fixture is larger than"},
+		{"query size", "", "SELECT 1" + strings.Repeat(" ", limits.MaxQuery), "query is larger than"},
+		{"rows", "", "WITH RECURSIVE n(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM n WHERE x < 11) SELECT x FROM n", "more than 10 rows"},
+		{"value size", "", "SELECT length(zeroblob(900000000))", "value is larger than"},
+		{"random blob size", "", "SELECT length(randomblob(900000000))", "value is larger than"},
+		{"database size", "CREATE TABLE t (b BLOB); WITH RECURSIVE n(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM n WHERE x < 100) " +
+			"INSERT INTO t SELECT zeroblob(500000) FROM n;", "SELECT 1", "database is larger than"},
+		{"timeout", "", "WITH RECURSIVE n(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM n) SELECT count(*) FROM n", "timed out after 500ms"},
+		{"fixture attach", "ATTACH DATABASE ':memory:' AS x;", "SELECT 1", "statement not allowed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Run(tt.fixture, tt.query, limits)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Run() error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want []string
+	}{
+		{"", nil},
+		{" ; ;", nil},
+		{"SELECT 1", []string{"SELECT 1"}},
+		{"SELECT 1;", []string{"SELECT 1"}},
+		{"SELECT 1; SELECT 2;", []string{"SELECT 1", " SELECT 2"}},
+		{"SELECT 'a;b'", []string{"SELECT 'a;b'"}},
+		{`SELECT "a;b" FROM [x;y]`, []string{`SELECT "a;b" FROM [x;y]`}},
+		{"SELECT 1 -- ; SELECT 2", []string{"SELECT 1 -- ; SELECT 2"}},
+		{"SELECT 1 /* ; */", []string{"SELECT 1 /* ; */"}},
+		{"SELECT 1; /* trailing */", []string{"SELECT 1"}},
+		{"SELECT 1; -- trailing", []string{"SELECT 1"}},
+		{"SELECT 'unterminated; 2", []string{"SELECT 'unterminated; 2"}},
+	}
+
+	for _, tt := range tests {
+		if got := splitStatements(tt.sql); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitStatements(%q) = %q, want %q", tt.sql, got, tt.want)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	result := func(rows ...string) *Result {
+		r := &Result{Columns: []string{"n"}}
+		for _, row := range rows {
+			r.Rows = append(r.Rows, []string{row})
+		}
+		return r
+	}
+	at := func(i int) *int { return &i }
+
+	tests := []struct {
+		name        string
+		expected    *Result
+		actual      *Result
+		ignoreOrder bool
+		want        *Diff
+	}{
+		{"equal", result("a", "b"), result("a", "b"), false, nil},
+		{"reordered", result("a", "b"), result("b", "a"), false, &Diff{OrderMismatchAt: at(0)}},
+		{"reordered ignored", result("a", "b"), result("b", "a"), true, nil},
+		{"missing and unexpected", result("a", "b"), result("a", "c"), false, &Diff{Missing: [][]string{{"b"}}, Unexpected: [][]string{{"c"}}}},
+		{"duplicate row", result("a"), result("a", "a"), true, &Diff{Unexpected: [][]string{{"a"}}}},
+		{"columns", result("a"), &Result{Columns: []string{"n", "m"}}, false, &Diff{ExpectedColumns: []string{"n"}, ActualColumns: []string{"n", "m"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Compare(tt.expected, tt.actual, tt.ignoreOrder); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Compare() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -23,6 +23,15 @@ func SuccessResponse(c *gin.Context, data interface{}, message string) {
 	})
 }
 
+// CreatedResponse returns a 201 Created response
+func CreatedResponse(c *gin.Context, data interface{}, message string) {
+	c.JSON(http.StatusCreated, Response{
+		Success: true,
+		Message: message,
+		Data:    data,
+	})
+}
+
 // ErrorResponse returns an error response
 func ErrorResponse(c *gin.Context, statusCode int, error string) {
 	c.JSON(statusCode, Response{
@@ -31,6 +40,16 @@ func ErrorResponse(c *gin.Context, statusCode int, error string) {
 	})
 }
 
+// ErrorResponseWithData returns an error response that also carries a payload,
+// for failures the caller needs details about (e.g. a per-row import report)
+func ErrorResponseWithData(c *gin.Context, statusCode int, data interface{}, error string) {
+	c.JSON(statusCode, Response{
+		Success: false,
+		Data:    data,
+		Error:   error,
+	})
+}
+
 // BadRequestResponse returns a 400 Bad Request response
 func BadRequestResponse(c *gin.Context, error string) {
 	ErrorResponse(c, http.StatusBadRequest, error)